@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/telumdb/telumdb/internal/config"
+)
+
+// runHealth probes the HTTP health endpoints of a running server and returns
+// the process exit code: 0 when healthy, 1 otherwise
+func runHealth(args []string) int {
+	fs := flag.NewFlagSet("health", flag.ContinueOnError)
+	var (
+		configFile = fs.String("config", os.Getenv("TELUMDB_CONFIG_FILE"), "Path to configuration file")
+		url        = fs.String("url", "", "Base URL of the HTTP API (default: derived from the configuration)")
+		live       = fs.Bool("live", false, "Check liveness instead of readiness")
+		timeout    = fs.Duration("timeout", 5*time.Second, "Timeout for the check")
+	)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	endpoint := "/readyz"
+	if *live {
+		endpoint = "/livez"
+	}
+
+	var tlsConfig *tls.Config
+	baseURL := *url
+	if baseURL == "" {
+		cfg, err := config.Load(*configFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "health: %v\n", err)
+			return 1
+		}
+		baseURL = healthURL(cfg.Server)
+		if cfg.Server.EnableTLS {
+			// The probe targets the local process; the certificate is
+			// usually issued for a public name, not the loopback address
+			tlsConfig = &tls.Config{InsecureSkipVerify: true}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if err := probeHealth(ctx, baseURL+endpoint, tlsConfig); err != nil {
+		fmt.Fprintf(os.Stderr, "health: %v\n", err)
+		return 1
+	}
+
+	fmt.Println("ok")
+	return 0
+}
+
+// healthURL returns the base URL of the HTTP API for a server configuration.
+// Wildcard listen addresses are probed on the loopback interface.
+func healthURL(cfg config.ServerConfig) string {
+	host := cfg.Host
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+
+	scheme := "http"
+	if cfg.EnableTLS {
+		scheme = "https"
+	}
+
+	return fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, strconv.Itoa(cfg.HTTPPort)))
+}
+
+// probeHealth issues a GET to url and fails unless it answers 200 OK
+func probeHealth(ctx context.Context, url string, tlsConfig *tls.Config) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("invalid health URL: %w", err)
+	}
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/telumdb/telumdb/internal/config"
+)
+
+func TestHealthURL(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.ServerConfig
+		want string
+	}{
+		{"wildcard IPv4", config.ServerConfig{Host: "0.0.0.0", HTTPPort: 8080}, "http://127.0.0.1:8080"},
+		{"wildcard IPv6", config.ServerConfig{Host: "::", HTTPPort: 8080}, "http://127.0.0.1:8080"},
+		{"empty host", config.ServerConfig{HTTPPort: 9090}, "http://127.0.0.1:9090"},
+		{"named host", config.ServerConfig{Host: "db.internal", HTTPPort: 8080}, "http://db.internal:8080"},
+		{"IPv6 host", config.ServerConfig{Host: "::1", HTTPPort: 8080}, "http://[::1]:8080"},
+		{"TLS", config.ServerConfig{Host: "0.0.0.0", HTTPPort: 8443, EnableTLS: true}, "https://127.0.0.1:8443"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := healthURL(tt.cfg); got != tt.want {
+				t.Errorf("healthURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbeHealth(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/readyz" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	if err := probeHealth(ctx, ts.URL+"/readyz", nil); err != nil {
+		t.Errorf("probeHealth(/readyz) error = %v", err)
+	}
+	if err := probeHealth(ctx, ts.URL+"/livez", nil); err == nil {
+		t.Error("probeHealth() should fail on 503")
+	}
+
+	ts.Close()
+	if err := probeHealth(ctx, ts.URL+"/readyz", nil); err == nil {
+		t.Error("probeHealth() should fail when the server is down")
+	}
+}
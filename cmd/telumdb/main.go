@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/signal"
 	"runtime"
+	"strings"
 	"syscall"
 
 	"github.com/telumdb/telumdb/internal/config"
@@ -23,12 +24,39 @@ var (
 )
 
 func main() {
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		switch args[0] {
+		case "serve":
+			args = args[1:]
+		case "health":
+			os.Exit(runHealth(args[1:]))
+		case "version":
+			printVersion()
+			return
+		case "help":
+			printHelp()
+			return
+		default:
+			fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+			printHelp()
+			os.Exit(2)
+		}
+	}
+
+	runServe(args)
+}
+
+// runServe starts the server and blocks until it is interrupted
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
 	var (
-		configFile  = flag.String("config", "", "Path to configuration file")
-		showHelp    = flag.Bool("help", false, "Show help message")
-		showVersion = flag.Bool("version", false, "Show version information")
+		configFile  = fs.String("config", os.Getenv("TELUMDB_CONFIG_FILE"), "Path to configuration file")
+		showHelp    = fs.Bool("help", false, "Show help message")
+		showVersion = fs.Bool("version", false, "Show version information")
 	)
-	flag.Parse()
+	fs.Usage = printHelp
+	fs.Parse(args)
 
 	if *showHelp {
 		printHelp()
@@ -60,7 +88,7 @@ func main() {
 	}
 
 	// Initialize storage engine
-	storageEngine, err := storage.CreateEngine(cfg.Storage)
+	storageEngine, err := storage.CreateEngine(cfg.Storage, storage.WithLogger(logger.Named("storage")))
 	if err != nil {
 		logger.Fatal("Failed to initialize storage engine", zap.Error(err))
 	}
@@ -103,22 +131,37 @@ func printHelp() {
 	fmt.Printf(`TelumDB - The World's First Hybrid General-Purpose + AI Tensor Database
 
 Usage:
-  telumdb [options]
+  telumdb [command] [options]
+
+Commands:
+  serve              Start the server (default)
+  health             Check the health of a running server
+  version            Show version information
+  help               Show this help message
 
 Options:
   -config string     Path to configuration file (default: config.yaml)
   -help              Show this help message
   -version           Show version information
 
+Health Options:
+  -config string     Configuration used to locate the HTTP port
+  -url string        Base URL of the HTTP API (overrides -config)
+  -live              Check liveness (/livez) instead of readiness (/readyz)
+  -timeout duration  Timeout for the check (default: 5s)
+
 Environment Variables:
   TELUMDB_CONFIG_FILE    Path to configuration file
   TELUMDB_DATA_DIR       Data directory path
   TELUMDB_LOG_LEVEL      Log level (debug, info, warn, error)
+  TELUMDB_ADMIN_PASSWORD Password for the initial admin user
 
 Examples:
   telumdb                                    # Start with default config
   telumdb -config /etc/telumdb/config.yaml   # Start with custom config
   telumdb -version                           # Show version
+  telumdb health                             # Exit 0 once the server is ready
+  telumdb health -url http://db:8080 -live   # Check liveness of a remote server
 
 For more information, visit: https://github.com/telumdb/telumdb
 `)
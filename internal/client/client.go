@@ -1,14 +1,21 @@
 package client
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"net"
+	"sync"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/telumdb/telumdb/internal/protocol"
 )
 
+// clientVersion is reported to the server during the handshake
+const clientVersion = "0.1.0"
+
 // Config represents client configuration
 type Config struct {
 	ServerURL string
@@ -16,6 +23,10 @@ type Config struct {
 	Username  string
 	Password  string
 	Timeout   time.Duration
+
+	// TLSConfig provides base TLS settings such as trusted CAs or client
+	// certificates. TLS itself is enabled by the sslmode URL parameter.
+	TLSConfig *tls.Config
 }
 
 // Client represents a database client
@@ -24,6 +35,7 @@ type Client struct {
 	conn      net.Conn
 	sessionID string
 	connected bool
+	mu        sync.Mutex
 }
 
 // New creates a new database client
@@ -33,8 +45,7 @@ func New(cfg *Config) (*Client, error) {
 	}
 
 	client := &Client{
-		config:    cfg,
-		sessionID: uuid.New().String(),
+		config: cfg,
 	}
 
 	return client, nil
@@ -42,55 +53,224 @@ func New(cfg *Config) (*Client, error) {
 
 // Connect connects to the database server
 func (c *Client) Connect(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.connect(ctx)
+}
+
+// connect dials the server and performs the handshake; c.mu must be held
+func (c *Client) connect(ctx context.Context) error {
 	// Parse the server URL to extract connection parameters
 	params, err := ParseURL(c.config.ServerURL)
 	if err != nil {
 		return fmt.Errorf("failed to parse server URL: %w", err)
 	}
 
-	conn, err := net.DialTimeout("tcp", params.Address(), c.config.Timeout)
+	tlsConfig, err := params.tlsConfig(c.config.TLSConfig)
+	if err != nil {
+		return err
+	}
+
+	dialer := &net.Dialer{Timeout: c.config.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", params.Address())
 	if err != nil {
 		return fmt.Errorf("failed to connect to server at %s: %w", params.Address(), err)
 	}
 
+	if tlsConfig != nil {
+		tlsConn := tls.Client(conn, tlsConfig)
+		handshakeCtx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+		err := tlsConn.HandshakeContext(handshakeCtx)
+		cancel()
+		if err != nil {
+			conn.Close()
+			return fmt.Errorf("TLS handshake with %s failed: %w", params.Address(), err)
+		}
+		conn = tlsConn
+	}
+
 	c.conn = conn
+
+	// Explicit configuration takes precedence over credentials in the URL
+	username, password := c.config.Username, c.config.Password
+	if username == "" {
+		username, password = params.Username, params.Password
+	}
+
+	database := c.config.Database
+	if database == "" {
+		database = params.Database
+	}
+
+	resp, err := c.roundTrip(ctx, &protocol.Request{
+		Type:          protocol.MessageTypeAuth,
+		Username:      username,
+		Password:      password,
+		Database:      database,
+		ClientVersion: clientVersion,
+	})
+	if err != nil {
+		c.disconnect()
+		return fmt.Errorf("authentication handshake failed: %w", err)
+	}
+	if err := responseError(resp); err != nil {
+		c.disconnect()
+		return err
+	}
+	if resp.SessionID == "" {
+		c.disconnect()
+		return fmt.Errorf("no session ID received from server")
+	}
+
+	c.sessionID = resp.SessionID
 	c.connected = true
 
-	// TODO: Implement authentication and session setup
 	return nil
 }
 
 // Close closes the client connection
 func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.disconnect()
+}
+
+// disconnect closes the connection and resets the session; c.mu must be held
+func (c *Client) disconnect() error {
+	c.connected = false
+	c.sessionID = ""
+
 	if c.conn != nil {
-		c.connected = false
-		return c.conn.Close()
+		err := c.conn.Close()
+		c.conn = nil
+		return err
 	}
 	return nil
 }
 
 // Execute executes a command and returns the result
 func (c *Client) Execute(ctx context.Context, query string) (*Result, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if !c.connected {
-		if err := c.Connect(ctx); err != nil {
+		if err := c.connect(ctx); err != nil {
 			return nil, err
 		}
 	}
 
-	// TODO: Implement command execution
-	// For now, return a mock result
+	resp, err := c.roundTrip(ctx, &protocol.Request{
+		Type:      protocol.MessageTypeQuery,
+		Query:     query,
+		SessionID: c.sessionID,
+	})
+	if err != nil {
+		// The stream is unusable after a transport error; reconnect next time
+		c.disconnect()
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	if err := responseError(resp); err != nil {
+		return nil, err
+	}
+
 	return &Result{
-		Columns:  []string{"result"},
-		Rows:     [][]interface{}{{"mock result for: " + query}},
-		Affected: 0,
+		Columns:  resp.Columns,
+		Rows:     resp.Rows,
+		Affected: resp.Affected,
 	}, nil
 }
 
+// SessionID returns the session ID issued by the server
+func (c *Client) SessionID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.sessionID
+}
+
 // Config returns the client configuration
 func (c *Client) Config() *Config {
 	return c.config
 }
 
+// roundTrip sends a request frame and waits for the response; c.mu must be held
+func (c *Client) roundTrip(ctx context.Context, req *protocol.Request) (*protocol.Response, error) {
+	deadline := time.Now().Add(c.config.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	// Unblock pending I/O if the context is cancelled mid-request
+	stop := make(chan struct{})
+	defer close(stop)
+	go func(conn net.Conn) {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now())
+		case <-stop:
+		}
+	}(c.conn)
+
+	if err := protocol.WriteMessage(c.conn, req); err != nil {
+		return nil, err
+	}
+
+	payload, err := protocol.ReadFrame(c.conn)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	return decodeResponse(payload)
+}
+
+// decodeResponse decodes a response frame, keeping integers in rows exact
+// rather than rounding them through float64
+func decodeResponse(payload []byte) (*protocol.Response, error) {
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+
+	var resp protocol.Response
+	if err := dec.Decode(&resp); err != nil {
+		return nil, fmt.Errorf("invalid message payload: %w", err)
+	}
+	for _, row := range resp.Rows {
+		for i, value := range row {
+			row[i] = resultValue(value)
+		}
+	}
+	return &resp, nil
+}
+
+// resultValue converts the numbers in a decoded value to int64 when they are
+// integral and to float64 otherwise
+func resultValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = resultValue(elem)
+		}
+	case map[string]interface{}:
+		for key, elem := range v {
+			v[key] = resultValue(elem)
+		}
+	}
+	return value
+}
+
 // Result represents a query result
 type Result struct {
 	Columns  []string
@@ -0,0 +1,118 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/telumdb/telumdb/internal/protocol"
+)
+
+// fakeServer accepts a single connection and answers requests with handler
+func fakeServer(t *testing.T, handler func(req *protocol.Request) *protocol.Response) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			var req protocol.Request
+			if err := protocol.ReadMessage(conn, &req); err != nil {
+				return
+			}
+			if err := protocol.WriteMessage(conn, handler(&req)); err != nil {
+				return
+			}
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestClientExecute(t *testing.T) {
+	addr := fakeServer(t, func(req *protocol.Request) *protocol.Response {
+		switch req.Type {
+		case protocol.MessageTypeAuth:
+			return &protocol.Response{Success: true, SessionID: "session-1"}
+		case protocol.MessageTypeQuery:
+			if req.SessionID != "session-1" {
+				return &protocol.Response{Code: protocol.ErrorCodeSession, Error: "invalid session ID"}
+			}
+			if req.Query == "BAD" {
+				return &protocol.Response{Code: protocol.ErrorCodeQuery, Error: "syntax error"}
+			}
+			return &protocol.Response{
+				Success:  true,
+				Columns:  []string{"id", "score", "tags"},
+				Rows:     [][]interface{}{{int64(1<<53 + 1), 2.5, []interface{}{int64(3), "x"}}},
+				Affected: 2,
+			}
+		}
+		return &protocol.Response{Code: protocol.ErrorCodeProtocol, Error: "unknown"}
+	})
+
+	cli, err := New(&Config{ServerURL: "telumdb://" + addr, Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer cli.Close()
+
+	ctx := context.Background()
+	result, err := cli.Execute(ctx, "SELECT 1")
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if cli.SessionID() != "session-1" {
+		t.Errorf("SessionID() = %q, want session-1", cli.SessionID())
+	}
+	if want := []string{"id", "score", "tags"}; !reflect.DeepEqual(result.Columns, want) {
+		t.Errorf("Columns = %v, want %v", result.Columns, want)
+	}
+	// Integers above 2^53 survive, and integral numbers come back as int64
+	wantRows := [][]interface{}{{int64(1<<53 + 1), 2.5, []interface{}{int64(3), "x"}}}
+	if !reflect.DeepEqual(result.Rows, wantRows) {
+		t.Errorf("Rows = %#v, want %#v", result.Rows, wantRows)
+	}
+	if result.Affected != 2 {
+		t.Errorf("Affected = %d, want 2", result.Affected)
+	}
+
+	_, err = cli.Execute(ctx, "BAD")
+	if !errors.Is(err, ErrQuery) {
+		t.Fatalf("Execute() error = %v, want ErrQuery", err)
+	}
+	var serverErr *ServerError
+	if !errors.As(err, &serverErr) || serverErr.Message != "syntax error" {
+		t.Errorf("Execute() error = %#v, want ServerError with message", err)
+	}
+}
+
+func TestClientAuthenticationFailure(t *testing.T) {
+	addr := fakeServer(t, func(req *protocol.Request) *protocol.Response {
+		return &protocol.Response{Code: protocol.ErrorCodeAuth, Error: "invalid credentials"}
+	})
+
+	cli, err := New(&Config{ServerURL: addr, Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer cli.Close()
+
+	_, err = cli.Execute(context.Background(), "SELECT 1")
+	if !errors.Is(err, ErrAuthentication) {
+		t.Errorf("Execute() error = %v, want ErrAuthentication", err)
+	}
+}
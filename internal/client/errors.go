@@ -0,0 +1,65 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/telumdb/telumdb/internal/protocol"
+)
+
+// Sentinel errors matched by ServerError codes via errors.Is
+var (
+	ErrProtocol       = errors.New("protocol error")
+	ErrAuthentication = errors.New("authentication failed")
+	ErrSession        = errors.New("invalid session")
+	ErrQuery          = errors.New("query failed")
+
+	ErrTooManyConnections = errors.New("too many connections")
+	ErrShuttingDown       = errors.New("server is shutting down")
+)
+
+// ServerError is an error reported by the server in a response frame
+type ServerError struct {
+	Code    string
+	Message string
+}
+
+// Error implements the error interface
+func (e *ServerError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("[%s] %s", e.Code, e.Message)
+	}
+	return e.Message
+}
+
+// Is maps the server error code onto the package sentinel errors
+func (e *ServerError) Is(target error) bool {
+	switch target {
+	case ErrProtocol:
+		return e.Code == protocol.ErrorCodeProtocol
+	case ErrAuthentication:
+		return e.Code == protocol.ErrorCodeAuth
+	case ErrSession:
+		return e.Code == protocol.ErrorCodeSession
+	case ErrQuery:
+		return e.Code == protocol.ErrorCodeQuery
+	case ErrTooManyConnections:
+		return e.Code == protocol.ErrorCodeTooManyConnections
+	case ErrShuttingDown:
+		return e.Code == protocol.ErrorCodeShuttingDown
+	}
+	return false
+}
+
+// responseError converts a failed response into a ServerError
+func responseError(resp *protocol.Response) error {
+	if resp.Success && resp.Error == "" {
+		return nil
+	}
+
+	msg := resp.Error
+	if msg == "" {
+		msg = "request failed"
+	}
+	return &ServerError{Code: resp.Code, Message: msg}
+}
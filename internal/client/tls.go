@@ -0,0 +1,83 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// tlsConfig builds the TLS configuration for the connection parameters,
+// starting from base if given. It returns nil when TLS is disabled.
+func (p *ConnectionParams) tlsConfig(base *tls.Config) (*tls.Config, error) {
+	if p.SSLMode == "" || p.SSLMode == SSLModeDisable {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{}
+	if base != nil {
+		cfg = base.Clone()
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = p.Host
+	}
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+
+	if p.SSLRootCert != "" {
+		pem, err := os.ReadFile(p.SSLRootCert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sslrootcert: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in sslrootcert %s", p.SSLRootCert)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if p.SSLCert != "" {
+		cert, err := tls.LoadX509KeyPair(p.SSLCert, p.SSLKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	switch p.SSLMode {
+	case SSLModeRequire:
+		// Encrypt only; the server's identity is not checked
+		cfg.InsecureSkipVerify = true
+	case SSLModeVerifyCA:
+		// Check the certificate chain ourselves but skip host name verification
+		roots := cfg.RootCAs
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			return verifyChain(cs, roots)
+		}
+	case SSLModeVerifyFull:
+		cfg.InsecureSkipVerify = false
+	}
+
+	return cfg, nil
+}
+
+// verifyChain verifies the peer certificate chain against roots without
+// checking the host name
+func verifyChain(cs tls.ConnectionState, roots *x509.CertPool) error {
+	if len(cs.PeerCertificates) == 0 {
+		return fmt.Errorf("server presented no certificate")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	return err
+}
@@ -8,10 +8,21 @@ import (
 	"strings"
 )
 
+// SSL modes accepted in the sslmode URL parameter
+const (
+	SSLModeDisable    = "disable"     // plain TCP
+	SSLModeRequire    = "require"     // TLS without certificate verification
+	SSLModeVerifyCA   = "verify-ca"   // TLS, server certificate must chain to a trusted CA
+	SSLModeVerifyFull = "verify-full" // verify-ca plus host name verification
+)
+
 // ParseURL parses a TelumDB connection URL and extracts connection parameters
 // Supported formats:
-// - telumdb://[user[:password]@]host[:port][/database]
+// - telumdb://[user[:password]@]host[:port][/database][?sslmode=...]
 // - host:port (legacy format)
+//
+// TLS is configured with the query parameters sslmode, sslrootcert (CA bundle),
+// sslcert and sslkey (client certificate).
 func ParseURL(serverURL string) (*ConnectionParams, error) {
 	// Handle legacy host:port format
 	if !strings.Contains(serverURL, "://") {
@@ -20,8 +31,9 @@ func ParseURL(serverURL string) (*ConnectionParams, error) {
 			return nil, fmt.Errorf("invalid host:port format: %w", err)
 		}
 		return &ConnectionParams{
-			Host: host,
-			Port: port,
+			Host:    host,
+			Port:    port,
+			SSLMode: SSLModeDisable,
 		}, nil
 	}
 
@@ -64,6 +76,24 @@ func ParseURL(serverURL string) (*ConnectionParams, error) {
 		return nil, fmt.Errorf("host is required in connection URL")
 	}
 
+	// Extract TLS settings
+	query := u.Query()
+	params.SSLMode = query.Get("sslmode")
+	params.SSLRootCert = query.Get("sslrootcert")
+	params.SSLCert = query.Get("sslcert")
+	params.SSLKey = query.Get("sslkey")
+
+	switch params.SSLMode {
+	case "":
+		params.SSLMode = SSLModeDisable
+	case SSLModeDisable, SSLModeRequire, SSLModeVerifyCA, SSLModeVerifyFull:
+	default:
+		return nil, fmt.Errorf("unsupported sslmode: %s", params.SSLMode)
+	}
+	if (params.SSLCert == "") != (params.SSLKey == "") {
+		return nil, fmt.Errorf("sslcert and sslkey must be given together")
+	}
+
 	return params, nil
 }
 
@@ -74,6 +104,11 @@ type ConnectionParams struct {
 	Database string
 	Username string
 	Password string
+
+	SSLMode     string
+	SSLRootCert string
+	SSLCert     string
+	SSLKey      string
 }
 
 // Address returns the network address (host:port) for dialing
@@ -103,6 +138,10 @@ func (p *ConnectionParams) String() string {
 		parts = append(parts, fmt.Sprintf("/%s", p.Database))
 	}
 
+	if p.SSLMode != "" && p.SSLMode != SSLModeDisable {
+		parts = append(parts, fmt.Sprintf("?sslmode=%s", p.SSLMode))
+	}
+
 	return "telumdb://" + strings.Join(parts, "")
 }
 
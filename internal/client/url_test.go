@@ -0,0 +1,30 @@
+package client
+
+import "testing"
+
+func TestParseURLSSLMode(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{"localhost:5432", SSLModeDisable, false},
+		{"telumdb://localhost/main", SSLModeDisable, false},
+		{"telumdb://localhost/main?sslmode=require", SSLModeRequire, false},
+		{"telumdb://localhost/main?sslmode=verify-ca&sslrootcert=ca.pem", SSLModeVerifyCA, false},
+		{"telumdb://localhost/main?sslmode=verify-full", SSLModeVerifyFull, false},
+		{"telumdb://localhost/main?sslmode=sometimes", "", true},
+		{"telumdb://localhost/main?sslmode=require&sslcert=client.pem", "", true},
+	}
+
+	for _, tt := range tests {
+		params, err := ParseURL(tt.url)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseURL(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+			continue
+		}
+		if err == nil && params.SSLMode != tt.want {
+			t.Errorf("ParseURL(%q) SSLMode = %q, want %q", tt.url, params.SSLMode, tt.want)
+		}
+	}
+}
@@ -18,17 +18,22 @@ type Config struct {
 
 // ServerConfig contains server-related configuration
 type ServerConfig struct {
-	Host            string        `yaml:"host"`
-	Port            int           `yaml:"port"`
-	HTTPPort        int           `yaml:"http_port"`
-	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
-	ReadTimeout     time.Duration `yaml:"read_timeout"`
-	WriteTimeout    time.Duration `yaml:"write_timeout"`
-	IdleTimeout     time.Duration `yaml:"idle_timeout"`
-	MaxConnections  int           `yaml:"max_connections"`
-	EnableTLS       bool          `yaml:"enable_tls"`
-	CertFile        string        `yaml:"cert_file"`
-	KeyFile         string        `yaml:"key_file"`
+	Host              string        `yaml:"host"`
+	Port              int           `yaml:"port"`
+	HTTPPort          int           `yaml:"http_port"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	MaxConnections    int           `yaml:"max_connections"`
+	EnableTLS         bool          `yaml:"enable_tls"`
+	CertFile          string        `yaml:"cert_file"`
+	KeyFile           string        `yaml:"key_file"`
+	ClientCAFile      string        `yaml:"client_ca_file"`
+	RequireClientCert bool          `yaml:"require_client_cert"`
+	AuthEnabled       bool          `yaml:"auth_enabled"`
+	AdminUser         string        `yaml:"admin_user"`
+	AdminPassword     string        `yaml:"admin_password"`
 }
 
 // StorageConfig contains storage-related configuration
@@ -128,6 +133,8 @@ func setDefaults(cfg *Config) {
 		IdleTimeout:     60 * time.Second,
 		MaxConnections:  1000,
 		EnableTLS:       false,
+		AuthEnabled:     true,
+		AdminUser:       "admin",
 	}
 
 	cfg.Storage = StorageConfig{
@@ -194,6 +201,9 @@ func loadFromEnv(cfg *Config) {
 	if logLevel := os.Getenv("TELUMDB_LOG_LEVEL"); logLevel != "" {
 		cfg.Logging.Level = logLevel
 	}
+	if adminPassword := os.Getenv("TELUMDB_ADMIN_PASSWORD"); adminPassword != "" {
+		cfg.Server.AdminPassword = adminPassword
+	}
 }
 
 // validate validates the configuration
@@ -204,12 +214,29 @@ func validate(cfg *Config) error {
 	if cfg.Server.HTTPPort <= 0 || cfg.Server.HTTPPort > 65535 {
 		return fmt.Errorf("invalid HTTP port: %d", cfg.Server.HTTPPort)
 	}
+	if cfg.Server.EnableTLS && (cfg.Server.CertFile == "" || cfg.Server.KeyFile == "") {
+		return fmt.Errorf("cert file and key file are required when TLS is enabled")
+	}
+	if cfg.Server.RequireClientCert && cfg.Server.ClientCAFile == "" {
+		return fmt.Errorf("client CA file is required to verify client certificates")
+	}
+	if cfg.Server.AuthEnabled && cfg.Server.AdminUser == "" {
+		return fmt.Errorf("admin user cannot be empty when authentication is enabled")
+	}
 	if cfg.Storage.DataDir == "" {
 		return fmt.Errorf("storage data directory cannot be empty")
 	}
 	if cfg.Storage.CacheSize <= 0 {
 		return fmt.Errorf("storage cache size must be positive")
 	}
+	switch cfg.Storage.SyncMode {
+	case "off", "normal", "full":
+	default:
+		return fmt.Errorf("invalid storage sync mode %q: must be off, normal or full", cfg.Storage.SyncMode)
+	}
+	if cfg.Storage.CheckpointInterval < 0 {
+		return fmt.Errorf("storage checkpoint interval cannot be negative")
+	}
 	return nil
 }
 
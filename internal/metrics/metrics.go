@@ -0,0 +1,395 @@
+// Package metrics implements counters, gauges and histograms exposed in the
+// Prometheus text format. Metrics are registered without a namespace; the
+// namespace from the configuration is prepended when they are written out.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBuckets are histogram buckets suited to query latencies in seconds
+var DefaultBuckets = []float64{0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10}
+
+// Default is the registry used by the server and the storage engine
+var Default = NewRegistry()
+
+// collector is a metric family that can write its samples
+type collector interface {
+	name() string
+	write(w *bufio.Writer, namespace string)
+}
+
+// Registry holds a set of metric families
+type Registry struct {
+	mu         sync.Mutex
+	collectors map[string]collector
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]collector)}
+}
+
+// register adds c to the registry, returning the existing family when one
+// with the same name was registered before
+func (r *Registry) register(c collector) collector {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.collectors[c.name()]; ok {
+		return existing
+	}
+	r.collectors[c.name()] = c
+	return c
+}
+
+// WriteText writes all metrics in the Prometheus text exposition format
+func (r *Registry) WriteText(w io.Writer, namespace string) error {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	collectors := make([]collector, 0, len(names))
+	sort.Strings(names)
+	for _, name := range names {
+		collectors = append(collectors, r.collectors[name])
+	}
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(bw, namespace)
+	}
+	return bw.Flush()
+}
+
+// Handler returns an HTTP handler serving the registry under namespace
+func (r *Registry) Handler(namespace string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteText(w, namespace)
+	})
+}
+
+// family holds what all metric kinds share: name, help and label names
+type family struct {
+	metricName string
+	help       string
+	labels     []string
+}
+
+func (f *family) name() string {
+	return f.metricName
+}
+
+// fullName prefixes the metric name with the namespace
+func (f *family) fullName(namespace string) string {
+	if namespace == "" {
+		return f.metricName
+	}
+	return namespace + "_" + f.metricName
+}
+
+// writeHeader writes the HELP and TYPE lines of the family
+func (f *family) writeHeader(w *bufio.Writer, namespace, kind string) {
+	name := f.fullName(namespace)
+	fmt.Fprintf(w, "# HELP %s %s\n", name, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+// labelKey joins label values into a map key
+func (f *family) labelKey(values []string) string {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.metricName, len(f.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// formatLabels renders {name="value",...} for the given values plus extra pairs
+func (f *family) formatLabels(values []string, extra ...string) string {
+	if len(values) == 0 && len(extra) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, value := range values {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, f.labels[i], escapeLabel(value))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, extra[i], escapeLabel(extra[i+1]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// series is one labelled time series of a family
+type series[T any] struct {
+	values []string
+	metric *T
+}
+
+// seriesSet stores the series of a family keyed by label values
+type seriesSet[T any] struct {
+	mu     sync.Mutex
+	series map[string]*series[T]
+}
+
+// get returns the series for values, creating it with create if needed
+func (s *seriesSet[T]) get(key string, values []string, create func() *T) *T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.series == nil {
+		s.series = make(map[string]*series[T])
+	}
+	if existing, ok := s.series[key]; ok {
+		return existing.metric
+	}
+
+	m := create()
+	s.series[key] = &series[T]{values: append([]string(nil), values...), metric: m}
+	return m
+}
+
+// sorted returns the series ordered by label values
+func (s *seriesSet[T]) sorted() []*series[T] {
+	s.mu.Lock()
+	list := make([]*series[T], 0, len(s.series))
+	for _, entry := range s.series {
+		list = append(list, entry)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return strings.Join(list[i].values, "\xff") < strings.Join(list[j].values, "\xff")
+	})
+	return list
+}
+
+// value is a float64 that can be updated atomically
+type value struct {
+	bits uint64
+}
+
+func (v *value) add(delta float64) {
+	for {
+		old := atomic.LoadUint64(&v.bits)
+		next := math.Float64bits(math.Float64frombits(old) + delta)
+		if atomic.CompareAndSwapUint64(&v.bits, old, next) {
+			return
+		}
+	}
+}
+
+func (v *value) set(x float64) {
+	atomic.StoreUint64(&v.bits, math.Float64bits(x))
+}
+
+func (v *value) get() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&v.bits))
+}
+
+// Counter is a monotonically increasing value
+type Counter struct {
+	v value
+}
+
+// Inc increments the counter by one
+func (c *Counter) Inc() {
+	c.v.add(1)
+}
+
+// Add increments the counter by delta, which must not be negative
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	c.v.add(delta)
+}
+
+// Value returns the current count
+func (c *Counter) Value() float64 {
+	return c.v.get()
+}
+
+// CounterVec is a counter family partitioned by labels
+type CounterVec struct {
+	family
+	set seriesSet[Counter]
+}
+
+// NewCounter registers a counter family on r
+func (r *Registry) NewCounter(name, help string, labels ...string) *CounterVec {
+	return r.register(&CounterVec{family: family{name, help, labels}}).(*CounterVec)
+}
+
+// With returns the counter for the given label values
+func (c *CounterVec) With(values ...string) *Counter {
+	return c.set.get(c.labelKey(values), values, func() *Counter { return &Counter{} })
+}
+
+func (c *CounterVec) write(w *bufio.Writer, namespace string) {
+	c.writeHeader(w, namespace, "counter")
+	for _, s := range c.set.sorted() {
+		fmt.Fprintf(w, "%s%s %s\n", c.fullName(namespace), c.formatLabels(s.values), formatFloat(s.metric.Value()))
+	}
+}
+
+// Gauge is a value that can go up and down
+type Gauge struct {
+	v value
+}
+
+// Set sets the gauge to x
+func (g *Gauge) Set(x float64) {
+	g.v.set(x)
+}
+
+// Add adds delta to the gauge
+func (g *Gauge) Add(delta float64) {
+	g.v.add(delta)
+}
+
+// Inc increments the gauge by one
+func (g *Gauge) Inc() {
+	g.v.add(1)
+}
+
+// Dec decrements the gauge by one
+func (g *Gauge) Dec() {
+	g.v.add(-1)
+}
+
+// Value returns the current value
+func (g *Gauge) Value() float64 {
+	return g.v.get()
+}
+
+// GaugeVec is a gauge family partitioned by labels
+type GaugeVec struct {
+	family
+	set seriesSet[Gauge]
+}
+
+// NewGauge registers a gauge family on r
+func (r *Registry) NewGauge(name, help string, labels ...string) *GaugeVec {
+	return r.register(&GaugeVec{family: family{name, help, labels}}).(*GaugeVec)
+}
+
+// With returns the gauge for the given label values
+func (g *GaugeVec) With(values ...string) *Gauge {
+	return g.set.get(g.labelKey(values), values, func() *Gauge { return &Gauge{} })
+}
+
+func (g *GaugeVec) write(w *bufio.Writer, namespace string) {
+	g.writeHeader(w, namespace, "gauge")
+	for _, s := range g.set.sorted() {
+		fmt.Fprintf(w, "%s%s %s\n", g.fullName(namespace), g.formatLabels(s.values), formatFloat(s.metric.Value()))
+	}
+}
+
+// Histogram counts observations into cumulative buckets
+type Histogram struct {
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     value
+}
+
+// Observe records a single observation
+func (h *Histogram) Observe(x float64) {
+	for i, upper := range h.buckets {
+		if x <= upper {
+			atomic.AddUint64(&h.counts[i], 1)
+		}
+	}
+	atomic.AddUint64(&h.count, 1)
+	h.sum.add(x)
+}
+
+// Count returns the number of observations
+func (h *Histogram) Count() uint64 {
+	return atomic.LoadUint64(&h.count)
+}
+
+// HistogramVec is a histogram family partitioned by labels
+type HistogramVec struct {
+	family
+	buckets []float64
+	set     seriesSet[Histogram]
+}
+
+// NewHistogram registers a histogram family on r. Nil buckets selects DefaultBuckets.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	return r.register(&HistogramVec{family: family{name, help, labels}, buckets: buckets}).(*HistogramVec)
+}
+
+// With returns the histogram for the given label values
+func (h *HistogramVec) With(values ...string) *Histogram {
+	return h.set.get(h.labelKey(values), values, func() *Histogram {
+		return &Histogram{buckets: h.buckets, counts: make([]uint64, len(h.buckets))}
+	})
+}
+
+func (h *HistogramVec) write(w *bufio.Writer, namespace string) {
+	h.writeHeader(w, namespace, "histogram")
+	name := h.fullName(namespace)
+	for _, s := range h.set.sorted() {
+		for i, upper := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", name, h.formatLabels(s.values, "le", formatFloat(upper)), atomic.LoadUint64(&s.metric.counts[i]))
+		}
+		count := s.metric.Count()
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, h.formatLabels(s.values, "le", "+Inf"), count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", name, h.formatLabels(s.values), formatFloat(s.metric.sum.get()))
+		fmt.Fprintf(w, "%s_count%s %d\n", name, h.formatLabels(s.values), count)
+	}
+}
+
+// formatFloat renders a sample value the way Prometheus expects
+func formatFloat(x float64) string {
+	switch {
+	case math.IsInf(x, 1):
+		return "+Inf"
+	case math.IsInf(x, -1):
+		return "-Inf"
+	case math.IsNaN(x):
+		return "NaN"
+	}
+	return strconv.FormatFloat(x, 'g', -1, 64)
+}
+
+// escapeHelp escapes backslashes and newlines in help text
+func escapeHelp(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
+
+// escapeLabel escapes backslashes, quotes and newlines in label values
+func escapeLabel(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
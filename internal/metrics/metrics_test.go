@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+
+	requests := r.NewCounter("requests_total", "Requests handled.", "method")
+	requests.With("get").Inc()
+	requests.With("get").Add(2)
+	requests.With("post").Inc()
+
+	// Registering the same name again returns the existing family
+	if again := r.NewCounter("requests_total", "Requests handled.", "method"); again != requests {
+		t.Error("NewCounter() should return the registered family")
+	}
+
+	inFlight := r.NewGauge("in_flight", "Requests in flight.").With()
+	inFlight.Inc()
+	inFlight.Inc()
+	inFlight.Dec()
+
+	latency := r.NewHistogram("latency_seconds", "Request latency.", []float64{0.1, 1}).With()
+	latency.Observe(0.05)
+	latency.Observe(0.5)
+	latency.Observe(5)
+
+	var b strings.Builder
+	if err := r.WriteText(&b, "app"); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+
+	want := `# HELP app_in_flight Requests in flight.
+# TYPE app_in_flight gauge
+app_in_flight 1
+# HELP app_latency_seconds Request latency.
+# TYPE app_latency_seconds histogram
+app_latency_seconds_bucket{le="0.1"} 1
+app_latency_seconds_bucket{le="1"} 2
+app_latency_seconds_bucket{le="+Inf"} 3
+app_latency_seconds_sum 5.55
+app_latency_seconds_count 3
+# HELP app_requests_total Requests handled.
+# TYPE app_requests_total counter
+app_requests_total{method="get"} 3
+app_requests_total{method="post"} 1
+`
+	if b.String() != want {
+		t.Errorf("WriteText() =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestLabelEscaping(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("errors_total", "Errors.", "message").With("say \"hi\"\n").Inc()
+
+	var b strings.Builder
+	r.WriteText(&b, "")
+	if !strings.Contains(b.String(), `errors_total{message="say \"hi\"\n"} 1`) {
+		t.Errorf("label not escaped:\n%s", b.String())
+	}
+}
@@ -0,0 +1,126 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MaxMessageSize is the largest frame payload accepted in either direction
+const MaxMessageSize = 64 << 20 // 64MB
+
+// Message types sent by clients
+const (
+	MessageTypeAuth  = "auth"
+	MessageTypeQuery = "query"
+)
+
+// Error codes returned in Response.Code
+const (
+	ErrorCodeProtocol = "PROTOCOL_ERROR"
+	ErrorCodeAuth     = "AUTH_ERROR"
+	ErrorCodeSession  = "SESSION_ERROR"
+	ErrorCodeQuery    = "QUERY_ERROR"
+
+	// ErrorCodeTooManyConnections is sent before closing a connection that
+	// would exceed the server's connection limit
+	ErrorCodeTooManyConnections = "TOO_MANY_CONNECTIONS"
+
+	// ErrorCodeShuttingDown is sent for requests that arrive while the
+	// server is draining connections
+	ErrorCodeShuttingDown = "SHUTTING_DOWN"
+)
+
+// ErrMessageTooLarge is returned when a frame exceeds MaxMessageSize
+var ErrMessageTooLarge = errors.New("message exceeds maximum size")
+
+// Request represents a client request frame
+type Request struct {
+	Type          string                 `json:"type"`
+	Query         string                 `json:"query,omitempty"`
+	Params        map[string]interface{} `json:"params,omitempty"`
+	SessionID     string                 `json:"session_id,omitempty"`
+	Username      string                 `json:"username,omitempty"`
+	Password      string                 `json:"password,omitempty"`
+	Database      string                 `json:"database,omitempty"`
+	ClientVersion string                 `json:"client_version,omitempty"`
+}
+
+// Response represents a server response frame
+type Response struct {
+	Success   bool            `json:"success"`
+	SessionID string          `json:"session_id,omitempty"`
+	Columns   []string        `json:"columns,omitempty"`
+	Rows      [][]interface{} `json:"rows,omitempty"`
+	Affected  int64           `json:"affected"`
+	Error     string          `json:"error,omitempty"`
+	Code      string          `json:"code,omitempty"`
+}
+
+// ErrorResponse builds a failed response with the given code and message
+func ErrorResponse(code string, err error) *Response {
+	return &Response{
+		Success: false,
+		Error:   err.Error(),
+		Code:    code,
+	}
+}
+
+// ReadMessage reads a single length-prefixed JSON frame and decodes it into v.
+// Frames are a 4-byte big-endian payload length followed by the JSON payload.
+func ReadMessage(r io.Reader, v interface{}) error {
+	payload, err := ReadFrame(r)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(payload, v); err != nil {
+		return fmt.Errorf("invalid message payload: %w", err)
+	}
+
+	return nil
+}
+
+// ReadFrame reads a single length-prefixed frame and returns its JSON payload
+// undecoded, for readers that decode it themselves
+func ReadFrame(r io.Reader) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[:])
+	if length > MaxMessageSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrMessageTooLarge, length)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return payload, nil
+}
+
+// WriteMessage encodes v as JSON and writes it as a single length-prefixed frame
+func WriteMessage(w io.Writer, v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	if len(payload) > MaxMessageSize {
+		return fmt.Errorf("%w: %d bytes", ErrMessageTooLarge, len(payload))
+	}
+
+	frame := make([]byte, 4+len(payload))
+	binary.BigEndian.PutUint32(frame[:4], uint32(len(payload)))
+	copy(frame[4:], payload)
+
+	_, err = w.Write(frame)
+	return err
+}
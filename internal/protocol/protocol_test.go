@@ -0,0 +1,65 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestMessageRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+
+	req := Request{
+		Type:      MessageTypeQuery,
+		Query:     "SELECT 1",
+		SessionID: "abc",
+	}
+	if err := WriteMessage(&buf, req); err != nil {
+		t.Fatalf("WriteMessage() error = %v", err)
+	}
+
+	length := binary.BigEndian.Uint32(buf.Bytes()[:4])
+	if int(length) != buf.Len()-4 {
+		t.Errorf("length prefix = %d, want %d", length, buf.Len()-4)
+	}
+
+	var got Request
+	if err := ReadMessage(&buf, &got); err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	if got.Type != req.Type || got.Query != req.Query || got.SessionID != req.SessionID {
+		t.Errorf("ReadMessage() = %+v, want %+v", got, req)
+	}
+}
+
+func TestReadMessageErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  error
+	}{
+		{
+			name:  "oversized frame",
+			input: []byte{0xff, 0xff, 0xff, 0xff},
+			want:  ErrMessageTooLarge,
+		},
+		{
+			name:  "invalid JSON",
+			input: append([]byte{0, 0, 0, 3}, []byte("{{{")...),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req Request
+			err := ReadMessage(bytes.NewReader(tt.input), &req)
+			if err == nil {
+				t.Fatal("ReadMessage() should fail")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("ReadMessage() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,549 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/telumdb/telumdb/internal/protocol"
+	"github.com/telumdb/telumdb/pkg/storage"
+	"go.uber.org/zap"
+)
+
+// apiPrefix is the path under which the REST API is served
+const apiPrefix = "/api/v1/"
+
+// apiColumn is the JSON form of storage.ColumnDefinition
+type apiColumn struct {
+	Name       string      `json:"name"`
+	Type       string      `json:"type"`
+	Nullable   bool        `json:"nullable"`
+	Default    interface{} `json:"default,omitempty"`
+	PrimaryKey bool        `json:"primary_key,omitempty"`
+}
+
+// apiIndex is the JSON form of storage.IndexDefinition
+type apiIndex struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Type    string   `json:"type,omitempty"`
+	Unique  bool     `json:"unique"`
+}
+
+// apiTable describes a table in requests and responses
+type apiTable struct {
+	Name    string      `json:"name"`
+	Columns []apiColumn `json:"columns"`
+	Indexes []apiIndex  `json:"indexes,omitempty"`
+}
+
+// apiTensor describes a tensor in requests and responses
+type apiTensor struct {
+	Name        string                 `json:"name"`
+	Shape       []int                  `json:"shape"`
+	DType       string                 `json:"dtype"`
+	ChunkSize   []int                  `json:"chunk_size,omitempty"`
+	Compression string                 `json:"compression,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// apiQuery is the body of POST /api/v1/query
+type apiQuery struct {
+	Query string `json:"query"`
+}
+
+// apiResult is the JSON form of storage.Result
+type apiResult struct {
+	Columns  []string        `json:"columns"`
+	Rows     [][]interface{} `json:"rows"`
+	Affected int64           `json:"affected"`
+}
+
+// apiRequest carries the per-request state of an API call
+type apiRequest struct {
+	w      http.ResponseWriter
+	r      *http.Request
+	user   *storage.User
+	engine storage.Engine
+}
+
+// handleAPI routes REST API requests. Every request is authenticated and
+// runs against a view of the engine restricted to the caller's privileges.
+//
+//	GET, POST        /api/v1/tables
+//	GET, DELETE      /api/v1/tables/{name}
+//	GET, POST        /api/v1/tensors
+//	GET, DELETE      /api/v1/tensors/{name}
+//	GET, PATCH       /api/v1/tensors/{name}/metadata
+//	GET, PUT         /api/v1/tensors/{name}/chunks/{i,j,k}
+//	POST             /api/v1/query
+func (s *Server) handleAPI(w http.ResponseWriter, r *http.Request) {
+	// Shutdown waits for the request before stopping the storage engine
+	ctx, done, err := s.requests.begin(r.Context())
+	if err != nil {
+		writeAPIError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	defer done()
+	r = r.WithContext(ctx)
+
+	user, ok := s.authenticateHTTP(w, r)
+	if !ok {
+		return
+	}
+
+	engine, err := storage.Authorize(s.storage, user)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	req := &apiRequest{w: w, r: r, user: user, engine: engine}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, apiPrefix), "/"), "/")
+
+	switch {
+	case len(parts) == 1 && parts[0] == "query":
+		s.routeMethods(req, map[string]func(*apiRequest){http.MethodPost: s.apiQuery})
+	case len(parts) == 1 && parts[0] == "tables":
+		s.routeMethods(req, map[string]func(*apiRequest){
+			http.MethodGet:  s.apiListTables,
+			http.MethodPost: s.apiCreateTable,
+		})
+	case len(parts) == 2 && parts[0] == "tables":
+		s.routeMethods(req, map[string]func(*apiRequest){
+			http.MethodGet:    func(req *apiRequest) { s.apiGetTable(req, parts[1]) },
+			http.MethodDelete: func(req *apiRequest) { s.apiDropTable(req, parts[1]) },
+		})
+	case len(parts) == 1 && parts[0] == "tensors":
+		s.routeMethods(req, map[string]func(*apiRequest){
+			http.MethodGet:  s.apiListTensors,
+			http.MethodPost: s.apiCreateTensor,
+		})
+	case len(parts) == 2 && parts[0] == "tensors":
+		s.routeMethods(req, map[string]func(*apiRequest){
+			http.MethodGet:    func(req *apiRequest) { s.apiGetTensor(req, parts[1]) },
+			http.MethodDelete: func(req *apiRequest) { s.apiDropTensor(req, parts[1]) },
+		})
+	case len(parts) == 3 && parts[0] == "tensors" && parts[2] == "metadata":
+		s.routeMethods(req, map[string]func(*apiRequest){
+			http.MethodGet:   func(req *apiRequest) { s.apiGetMetadata(req, parts[1]) },
+			http.MethodPatch: func(req *apiRequest) { s.apiPatchMetadata(req, parts[1]) },
+		})
+	case len(parts) == 4 && parts[0] == "tensors" && parts[2] == "chunks":
+		s.routeMethods(req, map[string]func(*apiRequest){
+			http.MethodGet: func(req *apiRequest) { s.apiGetChunk(req, parts[1], parts[3]) },
+			http.MethodPut: func(req *apiRequest) { s.apiPutChunk(req, parts[1], parts[3]) },
+		})
+	default:
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("unknown API endpoint: %s", r.URL.Path))
+	}
+}
+
+// routeMethods dispatches req to the handler registered for its HTTP method
+func (s *Server) routeMethods(req *apiRequest, handlers map[string]func(*apiRequest)) {
+	if handler, ok := handlers[req.r.Method]; ok {
+		handler(req)
+		return
+	}
+
+	allowed := make([]string, 0, len(handlers))
+	for method := range handlers {
+		allowed = append(allowed, method)
+	}
+	sort.Strings(allowed)
+	req.w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeAPIError(req.w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", req.r.Method))
+}
+
+// authenticateHTTP checks HTTP basic credentials when authentication is enabled
+func (s *Server) authenticateHTTP(w http.ResponseWriter, r *http.Request) (*storage.User, bool) {
+	if !s.config.Server.AuthEnabled {
+		// Without authentication every request has full access
+		return &storage.User{Superuser: true}, true
+	}
+
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="telumdb"`)
+		writeAPIError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return nil, false
+	}
+
+	// Clients send credentials with every request; hashing the password each
+	// time would make every request cost a full key derivation
+	if user, ok := s.credentials.get(username, password); ok {
+		return user, true
+	}
+
+	user, err := s.users.Authenticate(username, password)
+	if err != nil {
+		s.logger.Warn("HTTP authentication failed",
+			zap.String("remote_addr", r.RemoteAddr),
+			zap.String("username", username),
+			zap.Error(err),
+		)
+		w.Header().Set("WWW-Authenticate", `Basic realm="telumdb"`)
+		writeAPIError(w, http.StatusUnauthorized, storage.ErrInvalidCredentials)
+		return nil, false
+	}
+	s.credentials.put(username, password, user)
+
+	return user, true
+}
+
+// apiQuery handles POST /api/v1/query
+func (s *Server) apiQuery(req *apiRequest) {
+	var body apiQuery
+	if !decodeJSON(req, &body) {
+		return
+	}
+	if strings.TrimSpace(body.Query) == "" {
+		writeAPIError(req.w, http.StatusBadRequest, fmt.Errorf("query is required"))
+		return
+	}
+
+	// HTTP requests get a transient session so server commands see the caller
+	sess := &session{
+		id:         uuid.New().String(),
+		remoteAddr: req.r.RemoteAddr,
+		startedAt:  time.Now(),
+	}
+	sess.login(req.user.Name, "", req.user.Superuser, req.engine)
+
+	result, err := s.runQuery(req.r.Context(), sess, body.Query)
+	if err != nil {
+		writeStorageError(req.w, err)
+		return
+	}
+
+	writeJSON(req.w, http.StatusOK, apiResult{
+		Columns:  result.Columns,
+		Rows:     result.Rows,
+		Affected: result.Affected,
+	})
+}
+
+// apiListTables handles GET /api/v1/tables
+func (s *Server) apiListTables(req *apiRequest) {
+	names, err := req.engine.ListTables()
+	if err != nil {
+		writeStorageError(req.w, err)
+		return
+	}
+	writeJSON(req.w, http.StatusOK, nonNil(names))
+}
+
+// apiCreateTable handles POST /api/v1/tables
+func (s *Server) apiCreateTable(req *apiRequest) {
+	var body apiTable
+	if !decodeJSON(req, &body) {
+		return
+	}
+
+	schema := storage.TableSchema{}
+	for _, col := range body.Columns {
+		schema.Columns = append(schema.Columns, storage.ColumnDefinition{
+			Name:       col.Name,
+			Type:       col.Type,
+			Nullable:   col.Nullable,
+			Default:    col.Default,
+			PrimaryKey: col.PrimaryKey,
+		})
+	}
+	for _, idx := range body.Indexes {
+		schema.Indexes = append(schema.Indexes, storage.IndexDefinition{
+			Name:    idx.Name,
+			Columns: idx.Columns,
+			Type:    idx.Type,
+			Unique:  idx.Unique,
+		})
+	}
+
+	if err := req.engine.CreateTable(body.Name, schema); err != nil {
+		writeStorageError(req.w, err)
+		return
+	}
+
+	writeJSON(req.w, http.StatusCreated, tableToAPI(body.Name, schema))
+}
+
+// apiGetTable handles GET /api/v1/tables/{name}
+func (s *Server) apiGetTable(req *apiRequest, name string) {
+	table, err := req.engine.GetTable(name)
+	if err != nil {
+		writeStorageError(req.w, err)
+		return
+	}
+	writeJSON(req.w, http.StatusOK, tableToAPI(table.Name(), table.Schema()))
+}
+
+// apiDropTable handles DELETE /api/v1/tables/{name}
+func (s *Server) apiDropTable(req *apiRequest, name string) {
+	if err := req.engine.DropTable(name); err != nil {
+		writeStorageError(req.w, err)
+		return
+	}
+	req.w.WriteHeader(http.StatusNoContent)
+}
+
+// apiListTensors handles GET /api/v1/tensors
+func (s *Server) apiListTensors(req *apiRequest) {
+	names, err := req.engine.ListTensors()
+	if err != nil {
+		writeStorageError(req.w, err)
+		return
+	}
+	writeJSON(req.w, http.StatusOK, nonNil(names))
+}
+
+// apiCreateTensor handles POST /api/v1/tensors
+func (s *Server) apiCreateTensor(req *apiRequest) {
+	var body apiTensor
+	if !decodeJSON(req, &body) {
+		return
+	}
+
+	schema := storage.TensorSchema{
+		Shape:       body.Shape,
+		DType:       body.DType,
+		ChunkSize:   body.ChunkSize,
+		Compression: body.Compression,
+		Metadata:    body.Metadata,
+	}
+	if schema.DType == "" {
+		schema.DType = s.config.Storage.TensorConfig.DefaultDType
+	}
+
+	if err := req.engine.CreateTensor(body.Name, schema); err != nil {
+		writeStorageError(req.w, err)
+		return
+	}
+
+	tensor, err := req.engine.GetTensor(body.Name)
+	if err != nil {
+		writeStorageError(req.w, err)
+		return
+	}
+	writeJSON(req.w, http.StatusCreated, tensorToAPI(tensor))
+}
+
+// apiGetTensor handles GET /api/v1/tensors/{name}
+func (s *Server) apiGetTensor(req *apiRequest, name string) {
+	tensor, err := req.engine.GetTensor(name)
+	if err != nil {
+		writeStorageError(req.w, err)
+		return
+	}
+	writeJSON(req.w, http.StatusOK, tensorToAPI(tensor))
+}
+
+// apiDropTensor handles DELETE /api/v1/tensors/{name}
+func (s *Server) apiDropTensor(req *apiRequest, name string) {
+	if err := req.engine.DropTensor(name); err != nil {
+		writeStorageError(req.w, err)
+		return
+	}
+	req.w.WriteHeader(http.StatusNoContent)
+}
+
+// apiGetMetadata handles GET /api/v1/tensors/{name}/metadata
+func (s *Server) apiGetMetadata(req *apiRequest, name string) {
+	tensor, err := req.engine.GetTensor(name)
+	if err != nil {
+		writeStorageError(req.w, err)
+		return
+	}
+
+	metadata := tensor.Metadata()
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	writeJSON(req.w, http.StatusOK, metadata)
+}
+
+// apiPatchMetadata handles PATCH /api/v1/tensors/{name}/metadata by setting
+// every key in the request body in one catalog update
+func (s *Server) apiPatchMetadata(req *apiRequest, name string) {
+	var patch map[string]interface{}
+	if !decodeJSON(req, &patch) {
+		return
+	}
+
+	tensor, err := req.engine.GetTensor(name)
+	if err != nil {
+		writeStorageError(req.w, err)
+		return
+	}
+
+	if err := tensor.UpdateMetadata(patch); err != nil {
+		writeStorageError(req.w, err)
+		return
+	}
+
+	writeJSON(req.w, http.StatusOK, tensor.Metadata())
+}
+
+// apiGetChunk handles GET /api/v1/tensors/{name}/chunks/{i,j,k}
+func (s *Server) apiGetChunk(req *apiRequest, name, indexList string) {
+	indices, err := parseChunkIndices(indexList)
+	if err != nil {
+		writeAPIError(req.w, http.StatusBadRequest, err)
+		return
+	}
+
+	tensor, err := req.engine.GetTensor(name)
+	if err != nil {
+		writeStorageError(req.w, err)
+		return
+	}
+
+	data, err := tensor.GetChunk(req.r.Context(), indices)
+	if err != nil {
+		writeStorageError(req.w, err)
+		return
+	}
+
+	req.w.Header().Set("Content-Type", "application/octet-stream")
+	req.w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	req.w.WriteHeader(http.StatusOK)
+	req.w.Write(data)
+}
+
+// apiPutChunk handles PUT /api/v1/tensors/{name}/chunks/{i,j,k}
+func (s *Server) apiPutChunk(req *apiRequest, name, indexList string) {
+	indices, err := parseChunkIndices(indexList)
+	if err != nil {
+		writeAPIError(req.w, http.StatusBadRequest, err)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(req.w, req.r.Body, protocol.MaxMessageSize))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeAPIError(req.w, http.StatusRequestEntityTooLarge, err)
+		} else {
+			writeAPIError(req.w, http.StatusBadRequest, fmt.Errorf("failed to read body: %w", err))
+		}
+		return
+	}
+
+	tensor, err := req.engine.GetTensor(name)
+	if err != nil {
+		writeStorageError(req.w, err)
+		return
+	}
+
+	if err := tensor.StoreChunk(req.r.Context(), indices, data); err != nil {
+		writeStorageError(req.w, err)
+		return
+	}
+
+	req.w.WriteHeader(http.StatusNoContent)
+}
+
+// parseChunkIndices parses a comma separated chunk index such as "0,1,2"
+func parseChunkIndices(list string) ([]int, error) {
+	fields := strings.Split(list, ",")
+	indices := make([]int, len(fields))
+	for i, field := range fields {
+		idx, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, fmt.Errorf("invalid chunk index %q", field)
+		}
+		indices[i] = idx
+	}
+	return indices, nil
+}
+
+// tableToAPI converts a table schema to its JSON form
+func tableToAPI(name string, schema storage.TableSchema) apiTable {
+	table := apiTable{Name: name, Columns: []apiColumn{}}
+	for _, col := range schema.Columns {
+		table.Columns = append(table.Columns, apiColumn{
+			Name:       col.Name,
+			Type:       col.Type,
+			Nullable:   col.Nullable,
+			Default:    col.Default,
+			PrimaryKey: col.PrimaryKey,
+		})
+	}
+	for _, idx := range schema.Indexes {
+		table.Indexes = append(table.Indexes, apiIndex{
+			Name:    idx.Name,
+			Columns: idx.Columns,
+			Type:    idx.Type,
+			Unique:  idx.Unique,
+		})
+	}
+	return table
+}
+
+// tensorToAPI converts a tensor to its JSON form
+func tensorToAPI(tensor storage.Tensor) apiTensor {
+	schema := tensor.Schema()
+	return apiTensor{
+		Name:        tensor.Name(),
+		Shape:       tensor.Shape(),
+		DType:       tensor.DType(),
+		ChunkSize:   schema.ChunkSize,
+		Compression: schema.Compression,
+		Metadata:    tensor.Metadata(),
+	}
+}
+
+// nonNil makes empty name lists encode as [] rather than null
+func nonNil(names []string) []string {
+	if names == nil {
+		return []string{}
+	}
+	return names
+}
+
+// decodeJSON decodes the request body into v, answering 400 on failure
+func decodeJSON(req *apiRequest, v interface{}) bool {
+	body := http.MaxBytesReader(req.w, req.r.Body, protocol.MaxMessageSize)
+	if err := json.NewDecoder(body).Decode(v); err != nil {
+		writeAPIError(req.w, http.StatusBadRequest, fmt.Errorf("invalid JSON body: %w", err))
+		return false
+	}
+	return true
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeAPIError writes an error response of the form {"error": "..."}
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// writeStorageError maps a storage error onto an HTTP status. Errors without
+// a more specific meaning are reported as bad requests, since the engine
+// mostly rejects invalid input.
+func writeStorageError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, storage.ErrPermissionDenied):
+		status = http.StatusForbidden
+	case errors.Is(err, storage.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, storage.ErrAlreadyExists):
+		status = http.StatusConflict
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		status = http.StatusServiceUnavailable
+	}
+	writeAPIError(w, status, err)
+}
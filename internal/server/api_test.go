@@ -0,0 +1,189 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/iotest"
+
+	"github.com/telumdb/telumdb/internal/protocol"
+)
+
+// apiCall sends a request to the server's HTTP handler as the given user
+func apiCall(t *testing.T, srv *Server, method, path, username, password string, body []byte) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAPI(t *testing.T) {
+	srv := newAuthServer(t)
+	admin := func(method, path, body string) *httptest.ResponseRecorder {
+		return apiCall(t, srv, method, path, "admin", "admin-pw", []byte(body))
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"create table", "POST", "/api/v1/tables", `{"name":"events","columns":[{"name":"kind","type":"TEXT"}]}`, http.StatusCreated, ""},
+		{"duplicate table", "POST", "/api/v1/tables", `{"name":"events","columns":[]}`, http.StatusConflict, ""},
+		{"invalid table name", "POST", "/api/v1/tables", `{"name":"../etc","columns":[]}`, http.StatusBadRequest, ""},
+		{"list tables", "GET", "/api/v1/tables", "", http.StatusOK, `["events"]`},
+		{"get table", "GET", "/api/v1/tables/events", "", http.StatusOK, `{"name":"events","columns":[{"name":"kind","type":"TEXT","nullable":false}]}`},
+		{"missing table", "GET", "/api/v1/tables/nope", "", http.StatusNotFound, ""},
+		{"create tensor", "POST", "/api/v1/tensors", `{"name":"weights","shape":[4],"dtype":"float32","chunk_size":[2]}`, http.StatusCreated, ""},
+		{"list tensors", "GET", "/api/v1/tensors", "", http.StatusOK, `["weights"]`},
+		{"patch metadata", "PATCH", "/api/v1/tensors/weights/metadata", `{"owner":"ml"}`, http.StatusOK, `{"owner":"ml"}`},
+		{"get metadata", "GET", "/api/v1/tensors/weights/metadata", "", http.StatusOK, `{"owner":"ml"}`},
+		{"bad chunk index", "GET", "/api/v1/tensors/weights/chunks/x", "", http.StatusBadRequest, ""},
+		{"query", "POST", "/api/v1/query", `{"query":"SHOW GRANTS"}`, http.StatusOK, `{"columns":["grantee","object_type","object_name","privilege"],"rows":null,"affected":0}`},
+		{"wrong method", "PUT", "/api/v1/tables", "", http.StatusMethodNotAllowed, ""},
+		{"unknown endpoint", "GET", "/api/v1/nothing", "", http.StatusNotFound, ""},
+		{"drop table", "DELETE", "/api/v1/tables/events", "", http.StatusNoContent, ""},
+		{"drop missing table", "DELETE", "/api/v1/tables/events", "", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		rec := admin(tt.method, tt.path, tt.body)
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d (body %s)", tt.name, rec.Code, tt.wantStatus, rec.Body)
+			continue
+		}
+		if tt.wantBody != "" && !jsonEqual(t, rec.Body.Bytes(), []byte(tt.wantBody)) {
+			t.Errorf("%s: body = %s, want %s", tt.name, rec.Body, tt.wantBody)
+		}
+	}
+
+	// Chunks round-trip as raw little-endian float32 bytes
+	chunk := []byte{0, 0, 128, 63, 0, 0, 0, 64} // 1.0, 2.0
+	if rec := admin("PUT", "/api/v1/tensors/weights/chunks/1", string(chunk)); rec.Code != http.StatusNoContent {
+		t.Fatalf("PUT chunk: status = %d, body %s", rec.Code, rec.Body)
+	}
+	rec := admin("GET", "/api/v1/tensors/weights/chunks/1", "")
+	if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), chunk) {
+		t.Errorf("GET chunk: status = %d, body %v, want %v", rec.Code, rec.Body.Bytes(), chunk)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/octet-stream" {
+		t.Errorf("GET chunk: Content-Type = %q", ct)
+	}
+}
+
+func TestAPIPutChunkBody(t *testing.T) {
+	srv := newAuthServer(t)
+	apiCall(t, srv, "POST", "/api/v1/tensors", "admin", "admin-pw", []byte(`{"name":"weights","shape":[2],"chunk_size":[2]}`))
+
+	tests := []struct {
+		name       string
+		body       io.Reader
+		wantStatus int
+	}{
+		{"too large", bytes.NewReader(make([]byte, protocol.MaxMessageSize+1)), http.StatusRequestEntityTooLarge},
+		{"read error", iotest.ErrReader(io.ErrUnexpectedEOF), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("PUT", "/api/v1/tensors/weights/chunks/0", tt.body)
+		req.SetBasicAuth("admin", "admin-pw")
+		rec := httptest.NewRecorder()
+		srv.httpServer.Handler.ServeHTTP(rec, req)
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d (body %s)", tt.name, rec.Code, tt.wantStatus, rec.Body)
+		}
+	}
+}
+
+func TestAPIAccessControl(t *testing.T) {
+	srv := newAuthServer(t)
+
+	if rec := apiCall(t, srv, "GET", "/api/v1/tables", "", "", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous request: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if rec := apiCall(t, srv, "GET", "/api/v1/tables", "admin", "wrong", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong password: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	for _, q := range []string{
+		`{"query":"CREATE USER dave PASSWORD 'pw'"}`,
+		`{"query":"GRANT READ ON TENSOR weights TO dave"}`,
+	} {
+		if rec := apiCall(t, srv, "POST", "/api/v1/query", "admin", "admin-pw", []byte(q)); rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, body %s", q, rec.Code, rec.Body)
+		}
+	}
+	apiCall(t, srv, "POST", "/api/v1/tensors", "admin", "admin-pw", []byte(`{"name":"weights","shape":[2],"chunk_size":[2]}`))
+	apiCall(t, srv, "POST", "/api/v1/tensors", "admin", "admin-pw", []byte(`{"name":"secret","shape":[2],"chunk_size":[2]}`))
+
+	tests := []struct {
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{"GET", "/api/v1/tensors", "", http.StatusOK},
+		{"GET", "/api/v1/tensors/weights/chunks/0", "", http.StatusOK},
+		{"PUT", "/api/v1/tensors/weights/chunks/0", "\x00\x00\x80\x3f\x00\x00\x00\x40", http.StatusForbidden},
+		{"GET", "/api/v1/tensors/secret", "", http.StatusForbidden},
+		{"DELETE", "/api/v1/tensors/weights", "", http.StatusForbidden},
+		{"POST", "/api/v1/query", `{"query":"SELECT * FROM users"}`, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		rec := apiCall(t, srv, tt.method, tt.path, "dave", "pw", []byte(tt.body))
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s %s as dave: status = %d, want %d (body %s)", tt.method, tt.path, rec.Code, tt.wantStatus, rec.Body)
+		}
+	}
+
+	rec := apiCall(t, srv, "GET", "/api/v1/tensors", "dave", "pw", nil)
+	if !jsonEqual(t, rec.Body.Bytes(), []byte(`["weights"]`)) {
+		t.Errorf("GET /api/v1/tensors as dave = %s, want only granted tensors", rec.Body)
+	}
+
+	// Verified credentials are cached until the password changes
+	if _, ok := srv.credentials.get("dave", "pw"); !ok {
+		t.Error("credentials of dave not cached after a successful request")
+	}
+	if _, ok := srv.credentials.get("dave", "wrong"); ok {
+		t.Error("cached credentials matched a wrong password")
+	}
+	q := []byte(`{"query":"ALTER USER dave PASSWORD 'pw2'"}`)
+	if rec := apiCall(t, srv, "POST", "/api/v1/query", "admin", "admin-pw", q); rec.Code != http.StatusOK {
+		t.Fatalf("ALTER USER: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if rec := apiCall(t, srv, "GET", "/api/v1/tensors", "dave", "pw", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("old password after ALTER USER: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if rec := apiCall(t, srv, "GET", "/api/v1/tensors", "dave", "pw2", nil); rec.Code != http.StatusOK {
+		t.Errorf("new password after ALTER USER: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+// jsonEqual compares two JSON documents semantically
+func jsonEqual(t *testing.T, a, b []byte) bool {
+	t.Helper()
+
+	var va, vb interface{}
+	if err := json.Unmarshal(a, &va); err != nil {
+		t.Fatalf("invalid JSON %s: %v", a, err)
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		t.Fatalf("invalid JSON %s: %v", b, err)
+	}
+	ja, _ := json.Marshal(va)
+	jb, _ := json.Marshal(vb)
+	return bytes.Equal(ja, jb)
+}
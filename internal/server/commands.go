@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/telumdb/telumdb/pkg/storage"
+)
+
+// serverCommand is a statement handled by the server itself rather than
+// being passed through to the storage engine
+type serverCommand struct {
+	pattern *regexp.Regexp
+	handler func(s *Server, ctx context.Context, sess *session, args []string) (storage.Result, error)
+}
+
+// serverCommands lists the statements intercepted by executeCommand
+var serverCommands = []serverCommand{
+	{
+		pattern: regexp.MustCompile(`(?is)^CREATE\s+USER\s+(\w+)\s+(?:WITH\s+)?PASSWORD\s+'((?:[^']|'')*)'(\s+SUPERUSER)?$`),
+		handler: (*Server).createUser,
+	},
+	{
+		pattern: regexp.MustCompile(`(?is)^ALTER\s+USER\s+(\w+)\s+(?:WITH\s+)?PASSWORD\s+'((?:[^']|'')*)'$`),
+		handler: (*Server).alterUser,
+	},
+	{
+		pattern: regexp.MustCompile(`(?is)^DROP\s+USER\s+(\w+)$`),
+		handler: (*Server).dropUser,
+	},
+	{
+		pattern: regexp.MustCompile(`(?is)^SHOW\s+USERS$`),
+		handler: (*Server).showUsers,
+	},
+	{
+		pattern: regexp.MustCompile(`(?is)^GRANT\s+(.+?)\s+ON\s+(TABLE|TENSOR)\s+(\w+)\s+TO\s+(\w+)$`),
+		handler: (*Server).grant,
+	},
+	{
+		pattern: regexp.MustCompile(`(?is)^REVOKE\s+(.+?)\s+ON\s+(TABLE|TENSOR)\s+(\w+)\s+FROM\s+(\w+)$`),
+		handler: (*Server).revoke,
+	},
+	{
+		pattern: regexp.MustCompile(`(?is)^SHOW\s+GRANTS(?:\s+FOR\s+(\w+))?$`),
+		handler: (*Server).showGrants,
+	},
+	{
+		pattern: regexp.MustCompile(`(?is)^CREATE\s+(UNIQUE\s+)?INDEX\s+(\w+)\s+ON\s+(\w+)\s*\(([^)]*)\)$`),
+		handler: (*Server).createIndex,
+	},
+	{
+		pattern: regexp.MustCompile(`(?is)^DROP\s+INDEX\s+(\w+)\s+ON\s+(\w+)$`),
+		handler: (*Server).dropIndex,
+	},
+	{
+		pattern: regexp.MustCompile(`(?is)^SHOW\s+SESSIONS$`),
+		handler: (*Server).showSessions,
+	},
+	{
+		pattern: regexp.MustCompile(`(?is)^KILL\s+SESSION\s+'?([0-9a-f-]+)'?$`),
+		handler: (*Server).killSession,
+	},
+}
+
+// executeCommand runs query if it is a server command. The boolean result
+// reports whether the statement was handled.
+func (s *Server) executeCommand(ctx context.Context, sess *session, query string) (storage.Result, bool, error) {
+	stmt := normalizeStatement(query)
+
+	for _, cmd := range serverCommands {
+		if match := cmd.pattern.FindStringSubmatch(stmt); match != nil {
+			result, err := cmd.handler(s, ctx, sess, match[1:])
+			return result, true, err
+		}
+	}
+
+	return storage.Result{}, false, nil
+}
+
+// normalizeStatement trims whitespace and the trailing semicolon from a statement
+func normalizeStatement(query string) string {
+	stmt := strings.TrimSpace(query)
+	stmt = strings.TrimSuffix(stmt, ";")
+	return strings.TrimSpace(stmt)
+}
+
+// unquoteString undoes SQL quote escaping, where a doubled quote stands for one
+func unquoteString(s string) string {
+	return strings.ReplaceAll(s, "''", "'")
+}
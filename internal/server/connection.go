@@ -0,0 +1,235 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/telumdb/telumdb/internal/protocol"
+	"github.com/telumdb/telumdb/pkg/storage"
+	"go.uber.org/zap"
+)
+
+// handleConnection handles a single database connection
+func (s *Server) handleConnection(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sess := newSession(conn)
+	sess.cancel = cancel
+
+	if err := s.sessions.add(sess); err != nil {
+		s.logger.Warn("Rejecting connection",
+			zap.String("remote_addr", sess.remoteAddr),
+			zap.Error(err),
+		)
+		code := protocol.ErrorCodeTooManyConnections
+		if errors.Is(err, errShuttingDown) {
+			code = protocol.ErrorCodeShuttingDown
+		}
+		s.writeResponse(conn, sess, protocol.ErrorResponse(code, err))
+		return
+	}
+	defer s.sessions.remove(sess.id)
+
+	activeConnections.Inc()
+	defer activeConnections.Dec()
+
+	// Complete the TLS handshake up front so failures are reported as such
+	// instead of surfacing as a malformed first frame
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		if err := s.handshake(ctx, tlsConn); err != nil {
+			s.logger.Warn("TLS handshake failed",
+				zap.String("remote_addr", sess.remoteAddr),
+				zap.Error(err),
+			)
+			return
+		}
+	}
+
+	s.logger.Info("New connection established",
+		zap.String("remote_addr", sess.remoteAddr),
+		zap.String("session_id", sess.id),
+	)
+
+	for {
+		if s.config.Server.IdleTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(s.config.Server.IdleTimeout))
+		}
+
+		var req protocol.Request
+		if err := protocol.ReadMessage(conn, &req); err != nil {
+			if isConnectionClosed(err) {
+				s.logger.Debug("Connection closed", zap.String("session_id", sess.id), zap.Error(err))
+				return
+			}
+
+			// Malformed frames leave the stream in an unknown state, so report
+			// the problem to the client and drop the connection
+			s.logger.Debug("Failed to read request", zap.String("session_id", sess.id), zap.Error(err))
+			s.writeResponse(conn, sess, protocol.ErrorResponse(protocol.ErrorCodeProtocol, err))
+			return
+		}
+
+		// Once draining starts, requests that were not yet running are refused
+		if !sess.begin() {
+			s.writeResponse(conn, sess, protocol.ErrorResponse(protocol.ErrorCodeShuttingDown, errShuttingDown))
+			return
+		}
+
+		resp := s.handleRequest(ctx, sess, &req)
+		err := s.writeResponse(conn, sess, resp)
+		if sess.end() || err != nil {
+			return
+		}
+	}
+}
+
+// handshake performs the server side of a TLS handshake within the read timeout
+func (s *Server) handshake(ctx context.Context, conn *tls.Conn) error {
+	if s.config.Server.ReadTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.Server.ReadTimeout)
+		defer cancel()
+	}
+	return conn.HandshakeContext(ctx)
+}
+
+// writeResponse writes a response frame to the connection
+func (s *Server) writeResponse(conn net.Conn, sess *session, resp *protocol.Response) error {
+	if s.config.Server.WriteTimeout > 0 {
+		conn.SetWriteDeadline(time.Now().Add(s.config.Server.WriteTimeout))
+	}
+
+	if err := protocol.WriteMessage(conn, resp); err != nil {
+		s.logger.Error("Error writing to connection", zap.String("session_id", sess.id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// handleRequest dispatches a decoded request based on its type
+func (s *Server) handleRequest(ctx context.Context, sess *session, req *protocol.Request) *protocol.Response {
+	switch req.Type {
+	case protocol.MessageTypeAuth:
+		return s.handleAuth(sess, req)
+	case protocol.MessageTypeQuery:
+		return s.handleQuery(ctx, sess, req)
+	default:
+		return protocol.ErrorResponse(protocol.ErrorCodeProtocol, fmt.Errorf("unknown message type: %q", req.Type))
+	}
+}
+
+// handleAuth performs the authentication handshake and issues the session ID
+func (s *Server) handleAuth(sess *session, req *protocol.Request) *protocol.Response {
+	if sess.authenticated {
+		return protocol.ErrorResponse(protocol.ErrorCodeAuth, fmt.Errorf("session already authenticated"))
+	}
+
+	// Without authentication every session has full access
+	user := &storage.User{Name: req.Username, Superuser: true}
+	if s.config.Server.AuthEnabled {
+		var err error
+		user, err = s.users.Authenticate(req.Username, req.Password)
+		if err != nil {
+			s.logger.Warn("Authentication failed",
+				zap.String("session_id", sess.id),
+				zap.String("remote_addr", sess.remoteAddr),
+				zap.String("username", req.Username),
+				zap.Error(err),
+			)
+			if errors.Is(err, storage.ErrInvalidCredentials) {
+				return protocol.ErrorResponse(protocol.ErrorCodeAuth, err)
+			}
+			return protocol.ErrorResponse(protocol.ErrorCodeAuth, fmt.Errorf("authentication failed"))
+		}
+	}
+
+	// Statements of the session run against a view of the engine that
+	// enforces the user's privileges
+	engine, err := storage.Authorize(s.storage, user)
+	if err != nil {
+		return protocol.ErrorResponse(protocol.ErrorCodeAuth, err)
+	}
+	sess.login(req.Username, req.Database, user.Superuser, engine)
+
+	s.logger.Info("Session authenticated",
+		zap.String("session_id", sess.id),
+		zap.String("username", sess.username),
+		zap.String("client_version", req.ClientVersion),
+	)
+
+	return &protocol.Response{
+		Success:   true,
+		SessionID: sess.id,
+	}
+}
+
+// handleQuery executes a query on behalf of an authenticated session
+func (s *Server) handleQuery(ctx context.Context, sess *session, req *protocol.Request) *protocol.Response {
+	if !sess.authenticated {
+		return protocol.ErrorResponse(protocol.ErrorCodeAuth, fmt.Errorf("authentication required"))
+	}
+	if req.SessionID != sess.id {
+		return protocol.ErrorResponse(protocol.ErrorCodeSession, fmt.Errorf("invalid session ID"))
+	}
+	if len(req.Params) > 0 {
+		return protocol.ErrorResponse(protocol.ErrorCodeQuery, fmt.Errorf("query parameters are not supported"))
+	}
+
+	sess.setQuery(req.Query)
+	defer sess.clearQuery()
+
+	result, err := s.runQuery(ctx, sess, req.Query)
+	if err != nil {
+		return protocol.ErrorResponse(protocol.ErrorCodeQuery, err)
+	}
+
+	return &protocol.Response{
+		Success:   true,
+		SessionID: sess.id,
+		Columns:   result.Columns,
+		Rows:      result.Rows,
+		Affected:  result.Affected,
+	}
+}
+
+// runQuery executes a statement for sess, trying server commands before
+// passing it to the storage engine
+func (s *Server) runQuery(ctx context.Context, sess *session, query string) (result storage.Result, err error) {
+	started := time.Now()
+	defer func() { observeQuery(query, started, err) }()
+
+	result, handled, err := s.executeCommand(ctx, sess, query)
+	if handled {
+		return result, err
+	}
+	result, handled, err = s.executeTableStatement(ctx, sess, query)
+	if handled {
+		return result, err
+	}
+
+	// Raw SQL runs directly against the catalog, including the users table
+	if !sess.superuser {
+		return storage.Result{}, fmt.Errorf("%w: only superusers can run raw SQL", storage.ErrPermissionDenied)
+	}
+	return s.storage.ExecuteQuery(ctx, query)
+}
+
+// isConnectionClosed reports whether a read error means the peer went away
+// or the connection idled out, rather than a malformed frame
+func isConnectionClosed(err error) bool {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, net.ErrClosed) || errors.Is(err, io.ErrClosedPipe) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
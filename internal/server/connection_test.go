@@ -0,0 +1,416 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/telumdb/telumdb/internal/config"
+	"github.com/telumdb/telumdb/internal/protocol"
+	"github.com/telumdb/telumdb/pkg/storage"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// stubEngine answers queries with a fixed result and leaves the rest of the
+// Engine interface unimplemented
+type stubEngine struct {
+	storage.Engine
+}
+
+func (e *stubEngine) ExecuteQuery(ctx context.Context, query string) (storage.Result, error) {
+	if query == "FAIL" {
+		return storage.Result{}, fmt.Errorf("query failed")
+	}
+	return storage.Result{
+		Columns: []string{"query"},
+		Rows:    [][]interface{}{{query}},
+	}, nil
+}
+
+// newTestConnection starts handleConnection on one end of a pipe and returns the other end
+func newTestConnection(t *testing.T) net.Conn {
+	t.Helper()
+
+	cfg := &config.Config{}
+	srv := &Server{
+		config:   cfg,
+		storage:  &stubEngine{},
+		logger:   zap.NewNop(),
+		sessions: newSessionRegistry(0),
+	}
+
+	return connectPipe(t, srv)
+}
+
+// newAuthServer creates a server with authentication enabled on a real engine
+func newAuthServer(t *testing.T) *Server {
+	t.Helper()
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			AuthEnabled:   true,
+			AdminUser:     "admin",
+			AdminPassword: "admin-pw",
+		},
+		Storage: config.StorageConfig{DataDir: t.TempDir()},
+	}
+
+	engine, err := storage.NewEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+	ctx := context.Background()
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { engine.Shutdown(ctx) })
+
+	srv, err := New(cfg, engine, zap.NewNop())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := srv.bootstrapUsers(); err != nil {
+		t.Fatalf("bootstrapUsers() error = %v", err)
+	}
+
+	return srv
+}
+
+// connectPipe serves a pipe connection with srv and returns the client end
+func connectPipe(t *testing.T, srv *Server) net.Conn {
+	t.Helper()
+
+	client, conn := net.Pipe()
+	go srv.handleConnection(context.Background(), conn)
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func roundTrip(t *testing.T, conn net.Conn, req protocol.Request) protocol.Response {
+	t.Helper()
+
+	if err := protocol.WriteMessage(conn, req); err != nil {
+		t.Fatalf("WriteMessage() error = %v", err)
+	}
+
+	var resp protocol.Response
+	if err := protocol.ReadMessage(conn, &resp); err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	return resp
+}
+
+func TestHandleConnectionQuery(t *testing.T) {
+	conn := newTestConnection(t)
+
+	// Queries before the handshake are rejected
+	resp := roundTrip(t, conn, protocol.Request{Type: protocol.MessageTypeQuery, Query: "SELECT 1"})
+	if resp.Success || resp.Code != protocol.ErrorCodeAuth {
+		t.Errorf("unauthenticated query: got %+v, want %s", resp, protocol.ErrorCodeAuth)
+	}
+
+	auth := roundTrip(t, conn, protocol.Request{Type: protocol.MessageTypeAuth, Username: "alice"})
+	if !auth.Success || auth.SessionID == "" {
+		t.Fatalf("auth: got %+v, want session ID", auth)
+	}
+
+	resp = roundTrip(t, conn, protocol.Request{Type: protocol.MessageTypeQuery, Query: "SELECT 1", SessionID: auth.SessionID})
+	if !resp.Success {
+		t.Fatalf("query: got error %q", resp.Error)
+	}
+	if len(resp.Columns) != 1 || resp.Columns[0] != "query" {
+		t.Errorf("query: columns = %v, want [query]", resp.Columns)
+	}
+	if len(resp.Rows) != 1 || resp.Rows[0][0] != "SELECT 1" {
+		t.Errorf("query: rows = %v, want [[SELECT 1]]", resp.Rows)
+	}
+
+	resp = roundTrip(t, conn, protocol.Request{Type: protocol.MessageTypeQuery, Query: "FAIL", SessionID: auth.SessionID})
+	if resp.Success || resp.Code != protocol.ErrorCodeQuery || resp.Error != "query failed" {
+		t.Errorf("failing query: got %+v, want %s", resp, protocol.ErrorCodeQuery)
+	}
+
+	resp = roundTrip(t, conn, protocol.Request{Type: protocol.MessageTypeQuery, Query: "SELECT 1", SessionID: "other"})
+	if resp.Success || resp.Code != protocol.ErrorCodeSession {
+		t.Errorf("wrong session: got %+v, want %s", resp, protocol.ErrorCodeSession)
+	}
+}
+
+func TestHandleConnectionUnknownType(t *testing.T) {
+	conn := newTestConnection(t)
+
+	resp := roundTrip(t, conn, protocol.Request{Type: "bogus"})
+	if resp.Success || resp.Code != protocol.ErrorCodeProtocol {
+		t.Errorf("got %+v, want %s", resp, protocol.ErrorCodeProtocol)
+	}
+}
+
+// login authenticates conn and returns the issued session ID
+func login(t *testing.T, conn net.Conn, username, password string) string {
+	t.Helper()
+
+	resp := roundTrip(t, conn, protocol.Request{Type: protocol.MessageTypeAuth, Username: username, Password: password})
+	if !resp.Success {
+		t.Fatalf("login as %s failed: %s", username, resp.Error)
+	}
+	return resp.SessionID
+}
+
+func TestHandleConnectionAuthentication(t *testing.T) {
+	srv := newAuthServer(t)
+
+	conn := connectPipe(t, srv)
+	resp := roundTrip(t, conn, protocol.Request{Type: protocol.MessageTypeAuth, Username: "admin", Password: "wrong"})
+	if resp.Success || resp.Code != protocol.ErrorCodeAuth || resp.SessionID != "" {
+		t.Errorf("wrong password: got %+v, want %s", resp, protocol.ErrorCodeAuth)
+	}
+
+	sessionID := login(t, conn, "admin", "admin-pw")
+	query := func(conn net.Conn, sessionID, q string) protocol.Response {
+		return roundTrip(t, conn, protocol.Request{Type: protocol.MessageTypeQuery, Query: q, SessionID: sessionID})
+	}
+
+	if resp := query(conn, sessionID, "CREATE USER alice WITH PASSWORD 'it''s-me';"); !resp.Success {
+		t.Fatalf("CREATE USER failed: %s", resp.Error)
+	}
+
+	resp = query(conn, sessionID, "SHOW USERS")
+	if !resp.Success || len(resp.Rows) != 2 {
+		t.Errorf("SHOW USERS: got %+v, want 2 users", resp)
+	}
+
+	// The new user can log in but cannot manage users or run raw SQL
+	aliceConn := connectPipe(t, srv)
+	aliceSession := login(t, aliceConn, "alice", "it's-me")
+
+	if resp := query(aliceConn, aliceSession, "CREATE USER mallory PASSWORD 'x'"); resp.Success {
+		t.Error("non-superuser should not be able to create users")
+	}
+	if resp := query(aliceConn, aliceSession, "SELECT * FROM users"); resp.Success {
+		t.Error("non-superuser should not be able to run raw SQL")
+	}
+	if resp := query(aliceConn, aliceSession, "ALTER USER alice PASSWORD 'new-pw'"); !resp.Success {
+		t.Errorf("ALTER USER on self failed: %s", resp.Error)
+	}
+
+	if resp := query(conn, sessionID, "DROP USER alice"); !resp.Success {
+		t.Fatalf("DROP USER failed: %s", resp.Error)
+	}
+
+	resp = roundTrip(t, connectPipe(t, srv), protocol.Request{Type: protocol.MessageTypeAuth, Username: "alice", Password: "new-pw"})
+	if resp.Success {
+		t.Error("dropped user should not be able to log in")
+	}
+}
+
+func TestBootstrapGeneratedPassword(t *testing.T) {
+	ctx := context.Background()
+	dataDir := t.TempDir()
+	cfg := &config.Config{
+		Server:  config.ServerConfig{AuthEnabled: true, AdminUser: "admin"},
+		Storage: config.StorageConfig{DataDir: dataDir},
+	}
+
+	engine, err := storage.NewEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer engine.Shutdown(ctx)
+
+	core, logs := observer.New(zap.DebugLevel)
+	srv, err := New(cfg, engine, zap.New(core))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := srv.bootstrapUsers(); err != nil {
+		t.Fatalf("bootstrapUsers() error = %v", err)
+	}
+
+	path := filepath.Join(dataDir, adminPasswordFile)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("password file mode = %v, want 0600", perm)
+	}
+	data, _ := os.ReadFile(path)
+	password := strings.TrimSpace(string(data))
+	if _, err := engine.(storage.UserStore).Authenticate("admin", password); err != nil {
+		t.Errorf("Authenticate() with the generated password error = %v", err)
+	}
+
+	// The password never reaches the structured log
+	for _, entry := range logs.All() {
+		for _, field := range entry.Context {
+			if field.String == password {
+				t.Errorf("password logged in %q as field %s", entry.Message, field.Key)
+			}
+		}
+	}
+}
+
+func TestHandleConnectionGrants(t *testing.T) {
+	srv := newAuthServer(t)
+
+	conn := connectPipe(t, srv)
+	sessionID := login(t, conn, "admin", "admin-pw")
+	query := func(conn net.Conn, sessionID, q string) protocol.Response {
+		return roundTrip(t, conn, protocol.Request{Type: protocol.MessageTypeQuery, Query: q, SessionID: sessionID})
+	}
+
+	if resp := query(conn, sessionID, "CREATE USER bob PASSWORD 'pw'"); !resp.Success {
+		t.Fatalf("CREATE USER failed: %s", resp.Error)
+	}
+	if resp := query(conn, sessionID, "GRANT SELECT, INSERT ON TABLE events TO bob"); !resp.Success {
+		t.Fatalf("GRANT failed: %s", resp.Error)
+	}
+	if resp := query(conn, sessionID, "GRANT ALL PRIVILEGES ON TENSOR weights TO bob"); !resp.Success {
+		t.Fatalf("GRANT ALL failed: %s", resp.Error)
+	}
+	if resp := query(conn, sessionID, "GRANT READ ON TABLE events TO bob"); resp.Success {
+		t.Error("GRANT of a tensor privilege on a table should fail")
+	}
+	if resp := query(conn, sessionID, "REVOKE INSERT ON TABLE events FROM bob"); !resp.Success {
+		t.Fatalf("REVOKE failed: %s", resp.Error)
+	}
+
+	resp := query(conn, sessionID, "SHOW GRANTS FOR bob")
+	if !resp.Success || len(resp.Rows) != 4 {
+		t.Errorf("SHOW GRANTS FOR bob: got %+v, want 4 grants", resp)
+	}
+
+	bobConn := connectPipe(t, srv)
+	bobSession := login(t, bobConn, "bob", "pw")
+
+	if resp := query(bobConn, bobSession, "GRANT ALL ON TABLE events TO bob"); resp.Success {
+		t.Error("non-superuser should not be able to grant privileges")
+	}
+	if resp := query(bobConn, bobSession, "SHOW GRANTS"); !resp.Success || len(resp.Rows) != 4 {
+		t.Errorf("SHOW GRANTS as bob: got %+v, want own 4 grants", resp)
+	}
+	if resp := query(bobConn, bobSession, "SHOW GRANTS FOR admin"); resp.Success {
+		t.Error("non-superuser should not be able to list grants of other users")
+	}
+}
+
+func TestHandleConnectionIndexes(t *testing.T) {
+	srv := newAuthServer(t)
+	if err := srv.storage.CreateTable("events", storage.TableSchema{}); err != nil {
+		t.Fatalf("CreateTable() error = %v", err)
+	}
+
+	conn := connectPipe(t, srv)
+	sessionID := login(t, conn, "admin", "admin-pw")
+	query := func(conn net.Conn, sessionID, q string) protocol.Response {
+		return roundTrip(t, conn, protocol.Request{Type: protocol.MessageTypeQuery, Query: q, SessionID: sessionID})
+	}
+
+	if resp := query(conn, sessionID, "CREATE UNIQUE INDEX events_key ON events (source, seq)"); !resp.Success {
+		t.Fatalf("CREATE UNIQUE INDEX failed: %s", resp.Error)
+	}
+	table, err := srv.storage.GetTable("events")
+	if err != nil {
+		t.Fatalf("GetTable() error = %v", err)
+	}
+	indexes := table.Schema().Indexes
+	if len(indexes) != 1 || !indexes[0].Unique || len(indexes[0].Columns) != 2 || indexes[0].Columns[1] != "seq" {
+		t.Errorf("indexes = %+v, want unique events_key on (source, seq)", indexes)
+	}
+
+	if resp := query(conn, sessionID, "CREATE USER bob PASSWORD 'pw'"); !resp.Success {
+		t.Fatalf("CREATE USER failed: %s", resp.Error)
+	}
+	if resp := query(conn, sessionID, "GRANT SELECT ON TABLE events TO bob"); !resp.Success {
+		t.Fatalf("GRANT failed: %s", resp.Error)
+	}
+	bobConn := connectPipe(t, srv)
+	bobSession := login(t, bobConn, "bob", "pw")
+	if resp := query(bobConn, bobSession, "CREATE INDEX events_seq ON events (seq)"); resp.Success {
+		t.Error("CREATE INDEX without DDL on the table should fail")
+	}
+	if resp := query(conn, sessionID, "GRANT DDL ON TABLE events TO bob"); !resp.Success {
+		t.Fatalf("GRANT DDL failed: %s", resp.Error)
+	}
+	if resp := query(bobConn, bobSession, "CREATE INDEX events_seq ON events (seq)"); !resp.Success {
+		t.Errorf("CREATE INDEX with DDL failed: %s", resp.Error)
+	}
+
+	if resp := query(conn, sessionID, "DROP INDEX events_key ON events"); !resp.Success {
+		t.Errorf("DROP INDEX failed: %s", resp.Error)
+	}
+	if resp := query(conn, sessionID, "DROP INDEX events_key ON events"); resp.Success {
+		t.Error("DROP INDEX of a missing index should fail")
+	}
+}
+
+func TestHandleConnectionTableAccess(t *testing.T) {
+	srv := newAuthServer(t)
+	schema := storage.TableSchema{Columns: []storage.ColumnDefinition{
+		{Name: "id", Type: "INTEGER", PrimaryKey: true},
+		{Name: "kind", Type: "TEXT"},
+	}}
+	if err := srv.storage.CreateTable("events", schema); err != nil {
+		t.Fatalf("CreateTable() error = %v", err)
+	}
+
+	conn := connectPipe(t, srv)
+	sessionID := login(t, conn, "admin", "admin-pw")
+	query := func(conn net.Conn, sessionID, q string) protocol.Response {
+		return roundTrip(t, conn, protocol.Request{Type: protocol.MessageTypeQuery, Query: q, SessionID: sessionID})
+	}
+
+	for _, q := range []string{
+		"INSERT INTO events (id, kind) VALUES (1, 'click')",
+		"INSERT INTO events (id, kind) VALUES (2, 'view')",
+		"CREATE USER bob PASSWORD 'pw'",
+		"CREATE USER carol PASSWORD 'pw'",
+		"GRANT SELECT ON TABLE events TO bob",
+	} {
+		if resp := query(conn, sessionID, q); !resp.Success {
+			t.Fatalf("%s failed: %s", q, resp.Error)
+		}
+	}
+
+	bobConn := connectPipe(t, srv)
+	bobSession := login(t, bobConn, "bob", "pw")
+	resp := query(bobConn, bobSession, "SELECT id, kind FROM events WHERE kind = 'view'")
+	if !resp.Success || len(resp.Rows) != 1 || fmt.Sprint(resp.Rows[0]) != "[2 view]" {
+		t.Errorf("SELECT as bob: got %+v, want the view row", resp)
+	}
+	if resp := query(bobConn, bobSession, "SELECT * FROM events ORDER BY id DESC LIMIT 1"); !resp.Success ||
+		len(resp.Columns) != 2 || len(resp.Rows) != 1 || fmt.Sprint(resp.Rows[0]) != "[2 view]" {
+		t.Errorf("SELECT * as bob: got %+v, want the last row", resp)
+	}
+	if resp := query(bobConn, bobSession, "INSERT INTO events (id, kind) VALUES (3, 'click')"); resp.Success ||
+		!strings.Contains(resp.Error, storage.ErrPermissionDenied.Error()) {
+		t.Errorf("INSERT without INSERT privilege: got %+v, want permission denied", resp)
+	}
+
+	carolConn := connectPipe(t, srv)
+	carolSession := login(t, carolConn, "carol", "pw")
+	if resp := query(carolConn, carolSession, "SELECT * FROM events"); resp.Success ||
+		!strings.Contains(resp.Error, storage.ErrPermissionDenied.Error()) {
+		t.Errorf("SELECT as carol: got %+v, want permission denied", resp)
+	}
+
+	carol, err := storage.Authorize(srv.storage, &storage.User{Name: "carol"})
+	if err != nil {
+		t.Fatalf("Authorize() error = %v", err)
+	}
+	sess := &session{}
+	sess.login("carol", "", false, carol)
+	if _, err := srv.runQuery(context.Background(), sess, "SELECT * FROM events"); !errors.Is(err, storage.ErrPermissionDenied) {
+		t.Errorf("runQuery() as carol error = %v, want %v", err, storage.ErrPermissionDenied)
+	}
+}
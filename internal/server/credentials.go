@@ -0,0 +1,101 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/telumdb/telumdb/pkg/storage"
+)
+
+// credentialTTL is how long verified HTTP credentials are trusted before the
+// password hash is checked again
+const credentialTTL = time.Minute
+
+// credentialCache remembers recently verified HTTP basic credentials, so
+// clients sending them on every request don't pay for a password hash each
+// time. Passwords are kept only as an HMAC under a key that never leaves
+// the process.
+type credentialCache struct {
+	key []byte
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]credentialEntry
+}
+
+// credentialEntry is a verified password of a user
+type credentialEntry struct {
+	mac     []byte
+	user    storage.User
+	expires time.Time
+}
+
+// newCredentialCache creates a cache trusting credentials for ttl
+func newCredentialCache(ttl time.Duration) (*credentialCache, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate credential cache key: %w", err)
+	}
+	return &credentialCache{
+		key:     key,
+		ttl:     ttl,
+		entries: make(map[string]credentialEntry),
+	}, nil
+}
+
+// mac returns the HMAC of a user's password
+func (c *credentialCache) mac(username, password string) []byte {
+	h := hmac.New(sha256.New, c.key)
+	h.Write([]byte(username))
+	h.Write([]byte{0})
+	h.Write([]byte(password))
+	return h.Sum(nil)
+}
+
+// get returns the user if the password was verified within the TTL
+func (c *credentialCache) get(username, password string) (*storage.User, bool) {
+	mac := c.mac(username, password)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[username]
+	if !ok || !hmac.Equal(entry.mac, mac) {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, username)
+		return nil, false
+	}
+	user := entry.user
+	return &user, true
+}
+
+// put records a verified password of a user
+func (c *credentialCache) put(username, password string, user *storage.User) {
+	mac := c.mac(username, password)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Drop expired entries so users that stopped calling don't linger
+	now := time.Now()
+	for name, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, name)
+		}
+	}
+	c.entries[username] = credentialEntry{mac: mac, user: *user, expires: now.Add(c.ttl)}
+}
+
+// forget drops the cached credentials of a user whose password changed or
+// who was dropped
+func (c *credentialCache) forget(username string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, username)
+}
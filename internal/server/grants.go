@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/telumdb/telumdb/pkg/storage"
+	"go.uber.org/zap"
+)
+
+// privilegeStore returns the engine's privilege store or an error if grants are unsupported
+func (s *Server) privilegeStore() (storage.PrivilegeStore, error) {
+	if s.privileges == nil {
+		return nil, fmt.Errorf("the storage engine does not support access control")
+	}
+	return s.privileges, nil
+}
+
+// parsePrivilegeList splits a comma separated privilege list such as "SELECT, INSERT"
+func parsePrivilegeList(list string) []string {
+	var privileges []string
+	for _, p := range strings.Split(list, ",") {
+		p = strings.Join(strings.Fields(p), " ")
+		// Accept the SQL spelling ALL PRIVILEGES
+		if strings.EqualFold(p, "ALL PRIVILEGES") {
+			p = storage.PrivilegeAll
+		}
+		privileges = append(privileges, p)
+	}
+	return privileges
+}
+
+// grant handles GRANT privileges ON TABLE|TENSOR name TO user
+func (s *Server) grant(ctx context.Context, sess *session, args []string) (storage.Result, error) {
+	if !sess.superuser {
+		return storage.Result{}, fmt.Errorf("%w: only superusers can grant privileges", storage.ErrPermissionDenied)
+	}
+
+	store, err := s.privilegeStore()
+	if err != nil {
+		return storage.Result{}, err
+	}
+
+	objectType := strings.ToLower(args[1])
+	if err := store.Grant(args[3], objectType, args[2], parsePrivilegeList(args[0])); err != nil {
+		return storage.Result{}, err
+	}
+
+	s.logger.Info("Privileges granted",
+		zap.String("privileges", args[0]),
+		zap.String("object_type", objectType),
+		zap.String("object_name", args[2]),
+		zap.String("grantee", args[3]),
+		zap.String("granted_by", sess.username),
+	)
+
+	return storage.Result{Affected: 1}, nil
+}
+
+// revoke handles REVOKE privileges ON TABLE|TENSOR name FROM user
+func (s *Server) revoke(ctx context.Context, sess *session, args []string) (storage.Result, error) {
+	if !sess.superuser {
+		return storage.Result{}, fmt.Errorf("%w: only superusers can revoke privileges", storage.ErrPermissionDenied)
+	}
+
+	store, err := s.privilegeStore()
+	if err != nil {
+		return storage.Result{}, err
+	}
+
+	objectType := strings.ToLower(args[1])
+	if err := store.Revoke(args[3], objectType, args[2], parsePrivilegeList(args[0])); err != nil {
+		return storage.Result{}, err
+	}
+
+	s.logger.Info("Privileges revoked",
+		zap.String("privileges", args[0]),
+		zap.String("object_type", objectType),
+		zap.String("object_name", args[2]),
+		zap.String("grantee", args[3]),
+		zap.String("revoked_by", sess.username),
+	)
+
+	return storage.Result{Affected: 1}, nil
+}
+
+// showGrants handles SHOW GRANTS [FOR user]. Users may always list their own grants.
+func (s *Server) showGrants(ctx context.Context, sess *session, args []string) (storage.Result, error) {
+	grantee := args[0]
+	if grantee == "" && !sess.superuser {
+		grantee = sess.username
+	}
+	if !sess.superuser && grantee != sess.username {
+		return storage.Result{}, fmt.Errorf("%w: only superusers can list grants of other users", storage.ErrPermissionDenied)
+	}
+
+	store, err := s.privilegeStore()
+	if err != nil {
+		return storage.Result{}, err
+	}
+
+	grants, err := store.ListGrants(grantee)
+	if err != nil {
+		return storage.Result{}, err
+	}
+
+	result := storage.Result{Columns: []string{"grantee", "object_type", "object_name", "privilege"}}
+	for _, g := range grants {
+		result.Rows = append(result.Rows, []interface{}{g.Grantee, g.ObjectType, g.ObjectName, g.Privilege})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/telumdb/telumdb/pkg/storage"
+	"go.uber.org/zap"
+)
+
+// indexManager returns the engine's index support as seen by the session's user
+func (s *Server) indexManager(sess *session) (storage.IndexManager, error) {
+	indexes, ok := sess.engine.(storage.IndexManager)
+	if !ok {
+		return nil, fmt.Errorf("the storage engine does not support indexes")
+	}
+	return indexes, nil
+}
+
+// createIndex handles CREATE [UNIQUE] INDEX name ON table (column, ...)
+func (s *Server) createIndex(ctx context.Context, sess *session, args []string) (storage.Result, error) {
+	indexes, err := s.indexManager(sess)
+	if err != nil {
+		return storage.Result{}, err
+	}
+
+	index := storage.IndexDefinition{
+		Name:   args[1],
+		Unique: strings.TrimSpace(args[0]) != "",
+	}
+	for _, col := range strings.Split(args[3], ",") {
+		index.Columns = append(index.Columns, strings.TrimSpace(col))
+	}
+
+	if err := indexes.CreateIndex(args[2], index); err != nil {
+		return storage.Result{}, err
+	}
+
+	s.logger.Info("Index created",
+		zap.String("index", index.Name),
+		zap.String("table", args[2]),
+		zap.Strings("columns", index.Columns),
+		zap.Bool("unique", index.Unique),
+		zap.String("created_by", sess.username),
+	)
+
+	return storage.Result{Affected: 1}, nil
+}
+
+// dropIndex handles DROP INDEX name ON table
+func (s *Server) dropIndex(ctx context.Context, sess *session, args []string) (storage.Result, error) {
+	indexes, err := s.indexManager(sess)
+	if err != nil {
+		return storage.Result{}, err
+	}
+
+	if err := indexes.DropIndex(args[1], args[0]); err != nil {
+		return storage.Result{}, err
+	}
+
+	s.logger.Info("Index dropped",
+		zap.String("index", args[0]),
+		zap.String("table", args[1]),
+		zap.String("dropped_by", sess.username),
+	)
+
+	return storage.Result{Affected: 1}, nil
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/telumdb/telumdb/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// Server metrics, exposed with the storage metrics on the metrics endpoint
+var (
+	queriesTotal = metrics.Default.NewCounter("queries_total",
+		"Number of queries executed, by statement type and status.", "type", "status")
+	queryDuration = metrics.Default.NewHistogram("query_duration_seconds",
+		"Query latency in seconds, by statement type.", nil, "type")
+	activeConnections = metrics.Default.NewGauge("active_connections",
+		"Number of open client connections.").With()
+)
+
+// statementTypes are the statement keywords reported as query types; anything
+// else is counted as "other" to keep the number of series bounded
+var statementTypes = map[string]bool{
+	"SELECT": true, "INSERT": true, "UPDATE": true, "DELETE": true,
+	"CREATE": true, "DROP": true, "ALTER": true, "SHOW": true,
+	"GRANT": true, "REVOKE": true, "KILL": true, "WITH": true,
+	"BEGIN": true, "COMMIT": true, "ROLLBACK": true, "EXPLAIN": true, "PRAGMA": true,
+}
+
+// statementType returns the metrics label for a statement
+func statementType(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "other"
+	}
+
+	keyword := strings.ToUpper(strings.TrimSuffix(fields[0], ";"))
+	if !statementTypes[keyword] {
+		return "other"
+	}
+	return strings.ToLower(keyword)
+}
+
+// observeQuery records the outcome and latency of a statement
+func observeQuery(query string, started time.Time, err error) {
+	kind := statementType(query)
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+
+	queriesTotal.With(kind, status).Inc()
+	queryDuration.With(kind).Observe(time.Since(started).Seconds())
+}
+
+// setupMetrics prepares the metrics endpoint. It shares the HTTP API server
+// when both are configured on the same port.
+func (s *Server) setupMetrics() {
+	cfg := s.config.Metrics
+	if !cfg.Enabled {
+		return
+	}
+
+	path := cfg.Path
+	if path == "" {
+		path = "/metrics"
+	}
+	handler := metrics.Default.Handler(cfg.Namespace)
+
+	if cfg.Port == s.config.Server.HTTPPort {
+		s.httpServer.Handler.(*http.ServeMux).Handle(path, s.requireClientCert(handler))
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(path, handler)
+	s.metricsServer = &http.Server{
+		Addr:         fmt.Sprintf("%s:%d", s.config.Server.Host, cfg.Port),
+		Handler:      mux,
+		ReadTimeout:  s.config.Server.ReadTimeout,
+		WriteTimeout: s.config.Server.WriteTimeout,
+	}
+}
+
+// startMetrics starts the dedicated metrics server, if there is one
+func (s *Server) startMetrics() error {
+	if s.metricsServer == nil {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", s.metricsServer.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on metrics port %d: %w", s.config.Metrics.Port, err)
+	}
+	s.metricsListener = listener
+
+	go func() {
+		s.logger.Info("Starting metrics server",
+			zap.String("address", listener.Addr().String()),
+			zap.String("path", s.config.Metrics.Path),
+		)
+		if err := s.metricsServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.logger.Error("Metrics server error", zap.Error(err))
+		}
+	}()
+
+	return nil
+}
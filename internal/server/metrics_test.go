@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/telumdb/telumdb/internal/config"
+	"github.com/telumdb/telumdb/internal/protocol"
+	"github.com/telumdb/telumdb/pkg/storage"
+	"go.uber.org/zap"
+)
+
+func TestStatementType(t *testing.T) {
+	tests := map[string]string{
+		"SELECT 1":                "select",
+		"  show users;":           "show",
+		"GRANT READ ON TENSOR t":  "grant",
+		"VACUUM":                  "other",
+		"":                        "other",
+		"create table t (id int)": "create",
+	}
+	for query, want := range tests {
+		if got := statementType(query); got != want {
+			t.Errorf("statementType(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	cfg := &config.Config{
+		Storage: config.StorageConfig{DataDir: t.TempDir()},
+		Metrics: config.MetricsConfig{Enabled: true, Path: "/metrics", Namespace: "telumdb"},
+	}
+
+	engine, err := storage.NewEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+	ctx := context.Background()
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { engine.Shutdown(ctx) })
+
+	srv, err := New(cfg, engine, zap.NewNop())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	conn := connectPipe(t, srv)
+	sessionID := login(t, conn, "admin", "")
+	for _, q := range []string{"SELECT 1", "SELEC broken"} {
+		roundTrip(t, conn, protocol.Request{Type: protocol.MessageTypeQuery, Query: q, SessionID: sessionID})
+	}
+
+	tensor := `{"name":"m","shape":[2],"chunk_size":[2]}`
+	apiCall(t, srv, "POST", "/api/v1/tensors", "", "", []byte(tensor))
+	apiCall(t, srv, "PUT", "/api/v1/tensors/m/chunks/0", "", "", []byte{0, 0, 128, 63, 0, 0, 0, 64})
+
+	rec := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /metrics status = %d", rec.Code)
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		`telumdb_queries_total{type="select",status="ok"}`,
+		`telumdb_queries_total{type="other",status="error"}`,
+		`telumdb_query_duration_seconds_bucket{type="select",le="+Inf"}`,
+		"telumdb_active_connections ",
+		`telumdb_sqlite_errors_total{operation="query"}`,
+		"telumdb_chunk_writes_total",
+		"telumdb_tensor_memory_bytes",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"sync"
+)
+
+// requestTracker tracks in-flight HTTP API requests, which unlike protocol
+// sessions are not in the session registry, so that shutdown can wait for
+// them and cancel them at the deadline before stopping the storage engine.
+// The zero value is ready to use.
+type requestTracker struct {
+	mu       sync.Mutex
+	cancels  map[uint64]context.CancelFunc
+	next     uint64
+	draining bool
+	drained  chan struct{}
+}
+
+// begin registers a request and returns the context it must run with and
+// the function to call when it ends. It fails once draining has started.
+func (t *requestTracker) begin(ctx context.Context) (context.Context, func(), error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.draining {
+		return nil, nil, errShuttingDown
+	}
+	if t.cancels == nil {
+		t.cancels = make(map[uint64]context.CancelFunc)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	t.next++
+	id := t.next
+	t.cancels[id] = cancel
+
+	return ctx, func() {
+		cancel()
+		t.end(id)
+	}, nil
+}
+
+// end unregisters a request
+func (t *requestTracker) end(id uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.cancels, id)
+	if t.draining && len(t.cancels) == 0 {
+		close(t.drained)
+	}
+}
+
+// drain rejects new requests. The returned channel is closed once every
+// in-flight request has ended.
+func (t *requestTracker) drain() <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.draining {
+		return t.drained
+	}
+
+	t.draining = true
+	t.drained = make(chan struct{})
+	if len(t.cancels) == 0 {
+		close(t.drained)
+	}
+	return t.drained
+}
+
+// cancelAll cancels the context of every in-flight request
+func (t *requestTracker) cancelAll() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, cancel := range t.cancels {
+		cancel()
+	}
+}
+
+// count returns the number of in-flight requests
+func (t *requestTracker) count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.cancels)
+}
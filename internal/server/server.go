@@ -2,9 +2,12 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/telumdb/telumdb/internal/config"
@@ -14,55 +17,119 @@ import (
 
 // Server represents the TelumDB server
 type Server struct {
-	config     *config.Config
-	storage    storage.Engine
-	logger     *zap.Logger
-	httpServer *http.Server
-	listener   net.Listener
+	config       *config.Config
+	storage      storage.Engine
+	logger       *zap.Logger
+	httpServer   *http.Server
+	listener     net.Listener
+	httpListener net.Listener
+	users        storage.UserStore
+	credentials  *credentialCache
+	privileges   storage.PrivilegeStore
+	tlsConfig    *tls.Config
+	sessions     *sessionRegistry
+	requests     requestTracker
+	ready        atomic.Bool
+
+	metricsServer   *http.Server
+	metricsListener net.Listener
 }
 
 // New creates a new server instance
 func New(cfg *config.Config, storageEngine storage.Engine, logger *zap.Logger) (*Server, error) {
 	srv := &Server{
-		config:  cfg,
-		storage: storageEngine,
-		logger:  logger,
+		config:   cfg,
+		storage:  storageEngine,
+		logger:   logger,
+		sessions: newSessionRegistry(cfg.Server.MaxConnections),
 	}
 
+	// User accounts live in the storage engine's catalog
+	if users, ok := storageEngine.(storage.UserStore); ok {
+		srv.users = users
+		credentials, err := newCredentialCache(credentialTTL)
+		if err != nil {
+			return nil, err
+		}
+		srv.credentials = credentials
+	} else if cfg.Server.AuthEnabled {
+		return nil, fmt.Errorf("authentication is enabled but the storage engine does not support user accounts")
+	}
+	if privileges, ok := storageEngine.(storage.PrivilegeStore); ok {
+		srv.privileges = privileges
+	}
+
+	// Load certificates up front so misconfiguration fails fast
+	tlsConfig, err := loadTLSConfig(cfg.Server)
+	if err != nil {
+		return nil, err
+	}
+	srv.tlsConfig = tlsConfig
+
 	// Initialize HTTP server for API endpoints
 	srv.httpServer = &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.HTTPPort),
 		ReadTimeout:  cfg.Server.ReadTimeout,
 		WriteTimeout: cfg.Server.WriteTimeout,
 		IdleTimeout:  cfg.Server.IdleTimeout,
+		TLSConfig:    httpTLSConfig(tlsConfig),
 	}
 
 	// Setup routes
 	srv.setupRoutes()
+	srv.setupMetrics()
 
 	return srv, nil
 }
 
-// Start starts the server
+// Start starts the server. The HTTP listener comes up first so liveness can
+// be probed while the storage engine loads tensors; readiness is reported
+// once the engine has started and the database port is accepting connections.
 func (s *Server) Start(ctx context.Context) error {
-	// Start storage engine
-	if err := s.storage.Start(ctx); err != nil {
-		return fmt.Errorf("failed to start storage engine: %w", err)
-	}
-
 	// Start HTTP server
+	httpListener, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on HTTP port %d: %w", s.config.Server.HTTPPort, err)
+	}
+	s.httpListener = httpListener
 	go func() {
 		s.logger.Info("Starting HTTP server",
-			zap.String("address", s.httpServer.Addr),
+			zap.String("address", httpListener.Addr().String()),
+			zap.Bool("tls", s.tlsConfig != nil),
 		)
-		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if s.tlsConfig != nil {
+			err = s.httpServer.ServeTLS(httpListener, "", "")
+		} else {
+			err = s.httpServer.Serve(httpListener)
+		}
+		if err != nil && err != http.ErrServerClosed {
 			s.logger.Error("HTTP server error", zap.Error(err))
 		}
 	}()
 
+	// Start metrics server
+	if err := s.startMetrics(); err != nil {
+		s.httpServer.Close()
+		return err
+	}
+
+	// Start storage engine
+	if err := s.storage.Start(ctx); err != nil {
+		s.closeHTTP()
+		return fmt.Errorf("failed to start storage engine: %w", err)
+	}
+
+	// Create the initial admin account on a fresh data directory
+	if err := s.bootstrapUsers(); err != nil {
+		s.closeHTTP()
+		return fmt.Errorf("failed to bootstrap users: %w", err)
+	}
+
 	// Start database protocol server
-	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", s.config.Server.Host, s.config.Server.Port))
+	listener, err := s.listen(fmt.Sprintf("%s:%d", s.config.Server.Host, s.config.Server.Port))
 	if err != nil {
+		s.closeHTTP()
 		return fmt.Errorf("failed to listen on port %d: %w", s.config.Server.Port, err)
 	}
 	s.listener = listener
@@ -71,26 +138,74 @@ func (s *Server) Start(ctx context.Context) error {
 		zap.String("host", s.config.Server.Host),
 		zap.Int("port", s.config.Server.Port),
 		zap.Int("http_port", s.config.Server.HTTPPort),
+		zap.Bool("tls", s.tlsConfig != nil),
 	)
 
 	// Accept connections
 	go s.acceptConnections(ctx)
+	s.ready.Store(true)
 
 	return nil
 }
 
-// Shutdown gracefully shuts down the server
+// closeHTTP closes the HTTP and metrics servers after a failed start
+func (s *Server) closeHTTP() {
+	s.httpServer.Close()
+	if s.metricsServer != nil {
+		s.metricsServer.Close()
+	}
+}
+
+// Shutdown gracefully shuts down the server. New connections and queries are
+// refused right away; in-flight requests get until the context deadline to
+// finish before they are cancelled. The storage engine is only shut down once
+// every connection and HTTP API request has exited.
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.logger.Info("Shutting down server...")
 
+	// Report not ready so load balancers stop routing new work here
+	s.ready.Store(false)
+
+	// Stop accepting new connections
+	if s.listener != nil {
+		s.listener.Close()
+	}
+
+	// Idle connections close now, busy ones after their current request
+	drained := s.sessions.drain()
+	requestsDone := s.requests.drain()
+
 	// Shutdown HTTP server
 	if err := s.httpServer.Shutdown(ctx); err != nil {
 		s.logger.Error("Error shutting down HTTP server", zap.Error(err))
 	}
+	if s.metricsServer != nil {
+		if err := s.metricsServer.Shutdown(ctx); err != nil {
+			s.logger.Error("Error shutting down metrics server", zap.Error(err))
+		}
+	}
 
-	// Close listener
-	if s.listener != nil {
-		s.listener.Close()
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		s.logger.Warn("Shutdown deadline reached, cancelling in-flight requests",
+			zap.Int("sessions", s.sessions.count()),
+		)
+		s.sessions.killAll()
+		<-drained
+	}
+
+	// API requests still running past the deadline are cancelled, and their
+	// connections closed so handlers blocked reading a body return too
+	select {
+	case <-requestsDone:
+	case <-ctx.Done():
+		s.logger.Warn("Shutdown deadline reached, cancelling API requests",
+			zap.Int("requests", s.requests.count()),
+		)
+		s.requests.cancelAll()
+		s.httpServer.Close()
+		<-requestsDone
 	}
 
 	// Shutdown storage engine
@@ -106,87 +221,55 @@ func (s *Server) Shutdown(ctx context.Context) error {
 func (s *Server) setupRoutes() {
 	mux := http.NewServeMux()
 
-	// Health check endpoint
-	mux.HandleFunc("/health", s.handleHealth)
+	// Health check endpoints
+	mux.HandleFunc("/livez", s.handleLivez)
+	mux.HandleFunc("/readyz", s.handleReadyz)
 
-	// Metrics endpoint
-	mux.HandleFunc("/metrics", s.handleMetrics)
+	// Earlier versions served a single /health endpoint; probes still using
+	// it get the readiness check
+	mux.HandleFunc("/health", s.handleReadyz)
 
 	// API endpoints
-	mux.HandleFunc("/api/v1/", s.handleAPI)
+	mux.Handle("/api/v1/", s.requireClientCert(http.HandlerFunc(s.handleAPI)))
 
 	s.httpServer.Handler = mux
 }
 
-// handleHealth handles health check requests
-func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprintf(w, `{"status":"healthy","timestamp":"%s"}`, time.Now().UTC().Format(time.RFC3339))
+// handleLivez reports that the process is up and serving HTTP
+func (s *Server) handleLivez(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, "alive")
 }
 
-// handleMetrics handles metrics requests
-func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement metrics collection
-	w.Header().Set("Content-Type", "text/plain")
-	w.WriteHeader(http.StatusOK)
-	fmt.Fprint(w, "# TelumDB metrics\n# TODO: Implement metrics\n")
+// handleReadyz reports whether the server has started and can take queries
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if !s.ready.Load() {
+		writeHealth(w, http.StatusServiceUnavailable, "not ready")
+		return
+	}
+	writeHealth(w, http.StatusOK, "ready")
 }
 
-// handleAPI handles API requests
-func (s *Server) handleAPI(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement API endpoints
+// writeHealth writes a health check response
+func writeHealth(w http.ResponseWriter, code int, status string) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusNotImplemented)
-	fmt.Fprint(w, `{"error":"API not yet implemented"}`)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	fmt.Fprintf(w, `{"status":"%s","timestamp":"%s"}`, status, time.Now().UTC().Format(time.RFC3339))
 }
 
-// acceptConnections accepts database connections
+// acceptConnections accepts database connections until the listener is closed
 func (s *Server) acceptConnections(ctx context.Context) {
 	for {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			conn, err := s.listener.Accept()
-			if err != nil {
-				select {
-				case <-ctx.Done():
-					return
-				default:
-					s.logger.Error("Error accepting connection", zap.Error(err))
-					continue
-				}
-			}
-
-			// Handle connection in goroutine
-			go s.handleConnection(conn)
-		}
-	}
-}
-
-// handleConnection handles a single database connection
-func (s *Server) handleConnection(conn net.Conn) {
-	defer conn.Close()
-
-	s.logger.Info("New connection established",
-		zap.String("remote_addr", conn.RemoteAddr().String()),
-	)
-
-	// TODO: Implement database protocol handling
-	// For now, just echo back messages
-	buffer := make([]byte, 1024)
-	for {
-		n, err := conn.Read(buffer)
+		conn, err := s.listener.Accept()
 		if err != nil {
-			s.logger.Debug("Connection closed", zap.Error(err))
-			return
+			if errors.Is(err, net.ErrClosed) || ctx.Err() != nil {
+				return
+			}
+			s.logger.Error("Error accepting connection", zap.Error(err))
+			continue
 		}
 
-		// Echo back the message
-		if _, err := conn.Write(buffer[:n]); err != nil {
-			s.logger.Error("Error writing to connection", zap.Error(err))
-			return
-		}
+		// Handle connection in goroutine
+		go s.handleConnection(ctx, conn)
 	}
 }
@@ -0,0 +1,244 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/telumdb/telumdb/internal/config"
+	"github.com/telumdb/telumdb/internal/protocol"
+	"github.com/telumdb/telumdb/pkg/storage"
+	"go.uber.org/zap"
+)
+
+// slowEngine blocks queries until they are released or cancelled and records
+// the order in which queries and shutdown happen
+type slowEngine struct {
+	stubEngine
+	started chan struct{}
+	release chan struct{}
+
+	mu     sync.Mutex
+	events []string
+}
+
+func (e *slowEngine) record(event string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.events = append(e.events, event)
+}
+
+func (e *slowEngine) ExecuteQuery(ctx context.Context, query string) (storage.Result, error) {
+	close(e.started)
+	select {
+	case <-e.release:
+		e.record("query finished")
+		return storage.Result{Affected: 1}, nil
+	case <-ctx.Done():
+		e.record("query cancelled")
+		return storage.Result{}, ctx.Err()
+	}
+}
+
+func (e *slowEngine) Shutdown(ctx context.Context) error {
+	e.record("storage shutdown")
+	return nil
+}
+
+func newDrainServer(t *testing.T) (*Server, *slowEngine) {
+	t.Helper()
+
+	engine := &slowEngine{started: make(chan struct{}), release: make(chan struct{})}
+	return &Server{
+		config:     &config.Config{},
+		storage:    engine,
+		logger:     zap.NewNop(),
+		httpServer: &http.Server{},
+		sessions:   newSessionRegistry(0),
+	}, engine
+}
+
+func TestShutdownDrainsInFlightQueries(t *testing.T) {
+	srv, engine := newDrainServer(t)
+
+	busy := connectPipe(t, srv)
+	busySession := login(t, busy, "alice", "")
+	idle := connectPipe(t, srv)
+	login(t, idle, "bob", "")
+
+	if err := protocol.WriteMessage(busy, protocol.Request{Type: protocol.MessageTypeQuery, Query: "SLOW", SessionID: busySession}); err != nil {
+		t.Fatalf("WriteMessage() error = %v", err)
+	}
+	<-engine.started
+
+	done := make(chan struct{})
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+		close(done)
+	}()
+
+	// The idle connection is closed without waiting for the busy one
+	var resp protocol.Response
+	if err := protocol.ReadMessage(idle, &resp); err == nil {
+		t.Errorf("idle connection still open during shutdown, got %+v", resp)
+	}
+
+	select {
+	case <-done:
+		t.Fatal("Shutdown() returned while a query was in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(engine.release)
+	if err := protocol.ReadMessage(busy, &resp); err != nil || !resp.Success {
+		t.Errorf("in-flight query: got %+v, %v, want success", resp, err)
+	}
+	<-done
+
+	want := []string{"query finished", "storage shutdown"}
+	if len(engine.events) != len(want) || engine.events[0] != want[0] || engine.events[1] != want[1] {
+		t.Errorf("events = %v, want %v", engine.events, want)
+	}
+}
+
+func TestShutdownCancelsAtDeadline(t *testing.T) {
+	srv, engine := newDrainServer(t)
+
+	conn := connectPipe(t, srv)
+	sessionID := login(t, conn, "alice", "")
+	if err := protocol.WriteMessage(conn, protocol.Request{Type: protocol.MessageTypeQuery, Query: "SLOW", SessionID: sessionID}); err != nil {
+		t.Fatalf("WriteMessage() error = %v", err)
+	}
+	<-engine.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	srv.Shutdown(ctx)
+
+	want := []string{"query cancelled", "storage shutdown"}
+	if len(engine.events) != len(want) || engine.events[0] != want[0] || engine.events[1] != want[1] {
+		t.Errorf("events = %v, want %v", engine.events, want)
+	}
+
+	// New connections are refused once shutdown has started
+	var resp protocol.Response
+	if err := protocol.ReadMessage(connectPipe(t, srv), &resp); err != nil || resp.Code != protocol.ErrorCodeShuttingDown {
+		t.Errorf("connection after shutdown: got %+v, %v, want %s", resp, err, protocol.ErrorCodeShuttingDown)
+	}
+}
+
+func TestShutdownWaitsForAPIRequests(t *testing.T) {
+	for _, release := range []bool{true, false} {
+		srv, engine := newDrainServer(t)
+		srv.setupRoutes()
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Listen() error = %v", err)
+		}
+		go srv.httpServer.Serve(listener)
+
+		status := make(chan int, 1)
+		go func() {
+			resp, err := http.Post("http://"+listener.Addr().String()+"/api/v1/query", "application/json", strings.NewReader(`{"query": "SLOW"}`))
+			if err != nil {
+				status <- 0
+				return
+			}
+			resp.Body.Close()
+			status <- resp.StatusCode
+		}()
+		<-engine.started
+
+		timeout := 50 * time.Millisecond
+		if release {
+			timeout = 5 * time.Second
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				close(engine.release)
+			}()
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		srv.Shutdown(ctx)
+		cancel()
+
+		want := []string{"query cancelled", "storage shutdown"}
+		if release {
+			want[0] = "query finished"
+			if got := <-status; got != http.StatusOK {
+				t.Errorf("API request drained by shutdown: status %d, want %d", got, http.StatusOK)
+			}
+		}
+		if len(engine.events) != len(want) || engine.events[0] != want[0] || engine.events[1] != want[1] {
+			t.Errorf("release = %v: events = %v, want %v", release, engine.events, want)
+		}
+
+		// Requests arriving once shutdown has started are refused
+		rec := httptest.NewRecorder()
+		srv.handleAPI(rec, httptest.NewRequest(http.MethodGet, "/api/v1/tables", nil))
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("API request after shutdown: status %d, want %d", rec.Code, http.StatusServiceUnavailable)
+		}
+	}
+}
+
+// gatedEngine blocks Start until it is released, like an engine loading tensors
+type gatedEngine struct {
+	stubEngine
+	release chan struct{}
+}
+
+func (e *gatedEngine) Start(ctx context.Context) error {
+	<-e.release
+	return nil
+}
+
+func (e *gatedEngine) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	cfg := &config.Config{Server: config.ServerConfig{Host: "127.0.0.1"}}
+	engine := &gatedEngine{release: make(chan struct{})}
+	srv, err := New(cfg, engine, zap.NewNop())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	check := func(path string, want int) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		srv.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("GET %s status = %d, want %d", path, rec.Code, want)
+		}
+	}
+
+	check("/livez", http.StatusOK)
+	check("/readyz", http.StatusServiceUnavailable)
+
+	// The server is live but not ready while the engine is starting
+	started := make(chan error, 1)
+	go func() { started <- srv.Start(context.Background()) }()
+	check("/livez", http.StatusOK)
+	check("/readyz", http.StatusServiceUnavailable)
+
+	close(engine.release)
+	if err := <-started; err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	check("/readyz", http.StatusOK)
+	check("/health", http.StatusOK)
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	check("/readyz", http.StatusServiceUnavailable)
+	check("/health", http.StatusServiceUnavailable)
+}
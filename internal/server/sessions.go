@@ -0,0 +1,326 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/telumdb/telumdb/pkg/storage"
+	"go.uber.org/zap"
+)
+
+// session holds the protocol state of a single client connection. Fields
+// below mu may be read by other connections through the session registry.
+type session struct {
+	id         string
+	remoteAddr string
+	startedAt  time.Time
+	conn       net.Conn
+	cancel     context.CancelFunc
+
+	mu             sync.Mutex
+	username       string
+	database       string
+	superuser      bool
+	engine         storage.Engine
+	authenticated  bool
+	query          string
+	queryStartedAt time.Time
+	busy           bool
+	draining       bool
+}
+
+// newSession creates the session state for a freshly accepted connection
+func newSession(conn net.Conn) *session {
+	return &session{
+		id:         uuid.New().String(),
+		remoteAddr: conn.RemoteAddr().String(),
+		startedAt:  time.Now(),
+		conn:       conn,
+	}
+}
+
+// login records the authenticated user of the session and the view of the
+// storage engine its statements run against
+func (sess *session) login(username, database string, superuser bool, engine storage.Engine) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	sess.username = username
+	sess.database = database
+	sess.superuser = superuser
+	sess.engine = engine
+	sess.authenticated = true
+}
+
+// setQuery records the statement the session is currently executing
+func (sess *session) setQuery(query string) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	sess.query = query
+	sess.queryStartedAt = time.Now()
+}
+
+// clearQuery marks the session as idle
+func (sess *session) clearQuery() {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	sess.query = ""
+	sess.queryStartedAt = time.Time{}
+}
+
+// begin marks the start of a request. It returns false once the session is
+// draining, in which case the request must not be executed.
+func (sess *session) begin() bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	if sess.draining {
+		return false
+	}
+	sess.busy = true
+	return true
+}
+
+// end marks the end of a request and reports whether the connection should
+// now be closed because the server is draining
+func (sess *session) end() bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	sess.busy = false
+	return sess.draining
+}
+
+// drain stops the session from starting new requests. Idle connections are
+// closed right away; busy ones close after their current request.
+func (sess *session) drain() {
+	sess.mu.Lock()
+	sess.draining = true
+	idle := !sess.busy
+	sess.mu.Unlock()
+
+	if idle {
+		sess.conn.Close()
+	}
+}
+
+// sessionInfo is a point-in-time copy of a session's state
+type sessionInfo struct {
+	id             string
+	remoteAddr     string
+	username       string
+	database       string
+	startedAt      time.Time
+	query          string
+	queryStartedAt time.Time
+}
+
+// info returns a snapshot of the session that is safe to use from other goroutines
+func (sess *session) info() sessionInfo {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	return sessionInfo{
+		id:             sess.id,
+		remoteAddr:     sess.remoteAddr,
+		username:       sess.username,
+		database:       sess.database,
+		startedAt:      sess.startedAt,
+		query:          sess.query,
+		queryStartedAt: sess.queryStartedAt,
+	}
+}
+
+// kill cancels the session's in-flight work and closes its connection
+func (sess *session) kill() {
+	if sess.cancel != nil {
+		sess.cancel()
+	}
+	sess.conn.Close()
+}
+
+var (
+	// errTooManyConnections is returned when the connection limit is reached
+	errTooManyConnections = errors.New("too many connections")
+
+	// errShuttingDown is returned for work arriving while the server drains
+	errShuttingDown = errors.New("server is shutting down")
+)
+
+// sessionRegistry tracks the sessions of all open connections
+type sessionRegistry struct {
+	mu       sync.Mutex
+	sessions map[string]*session
+	limit    int
+	draining bool
+	drained  chan struct{}
+}
+
+// newSessionRegistry creates a registry admitting at most limit sessions; zero means unlimited
+func newSessionRegistry(limit int) *sessionRegistry {
+	return &sessionRegistry{
+		sessions: make(map[string]*session),
+		limit:    limit,
+	}
+}
+
+// add registers a session unless the connection limit has been reached
+func (r *sessionRegistry) add(sess *session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.draining {
+		return errShuttingDown
+	}
+	if r.limit > 0 && len(r.sessions) >= r.limit {
+		return fmt.Errorf("%w: limit of %d reached", errTooManyConnections, r.limit)
+	}
+
+	r.sessions[sess.id] = sess
+	return nil
+}
+
+// remove unregisters a session
+func (r *sessionRegistry) remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.sessions[id]; !ok {
+		return
+	}
+
+	delete(r.sessions, id)
+	if r.draining && len(r.sessions) == 0 {
+		close(r.drained)
+	}
+}
+
+// drain rejects new sessions and drains the existing ones. The returned
+// channel is closed once every session has been removed.
+func (r *sessionRegistry) drain() <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.draining {
+		return r.drained
+	}
+
+	r.draining = true
+	r.drained = make(chan struct{})
+	if len(r.sessions) == 0 {
+		close(r.drained)
+	}
+
+	for _, sess := range r.sessions {
+		sess.drain()
+	}
+
+	return r.drained
+}
+
+// killAll cancels the in-flight work of every session and closes its connection
+func (r *sessionRegistry) killAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, sess := range r.sessions {
+		sess.kill()
+	}
+}
+
+// get returns the session with the given ID
+func (r *sessionRegistry) get(id string) (*session, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sess, ok := r.sessions[id]
+	return sess, ok
+}
+
+// count returns the number of registered sessions
+func (r *sessionRegistry) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.sessions)
+}
+
+// list returns snapshots of all sessions ordered by start time
+func (r *sessionRegistry) list() []sessionInfo {
+	r.mu.Lock()
+	sessions := make([]*session, 0, len(r.sessions))
+	for _, sess := range r.sessions {
+		sessions = append(sessions, sess)
+	}
+	r.mu.Unlock()
+
+	infos := make([]sessionInfo, 0, len(sessions))
+	for _, sess := range sessions {
+		infos = append(infos, sess.info())
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].startedAt.Equal(infos[j].startedAt) {
+			return infos[i].id < infos[j].id
+		}
+		return infos[i].startedAt.Before(infos[j].startedAt)
+	})
+
+	return infos
+}
+
+// showSessions handles SHOW SESSIONS. Non-superusers only see their own sessions.
+func (s *Server) showSessions(ctx context.Context, sess *session, args []string) (storage.Result, error) {
+	result := storage.Result{Columns: []string{"id", "remote_addr", "username", "database", "started_at", "query", "query_started_at"}}
+
+	for _, info := range s.sessions.list() {
+		if !sess.superuser && info.username != sess.username {
+			continue
+		}
+
+		var queryStartedAt interface{}
+		if !info.queryStartedAt.IsZero() {
+			queryStartedAt = info.queryStartedAt
+		}
+
+		result.Rows = append(result.Rows, []interface{}{
+			info.id, info.remoteAddr, info.username, info.database, info.startedAt, info.query, queryStartedAt,
+		})
+	}
+
+	return result, nil
+}
+
+// killSession handles KILL SESSION id. Users may kill their own other sessions.
+func (s *Server) killSession(ctx context.Context, sess *session, args []string) (storage.Result, error) {
+	id := strings.ToLower(args[0])
+	if id == sess.id {
+		return storage.Result{}, fmt.Errorf("cannot kill the current session")
+	}
+
+	target, ok := s.sessions.get(id)
+	if !ok {
+		return storage.Result{}, fmt.Errorf("session not found: %s", id)
+	}
+	if !sess.superuser && target.info().username != sess.username {
+		return storage.Result{}, fmt.Errorf("%w: only superusers can kill sessions of other users", storage.ErrPermissionDenied)
+	}
+
+	target.kill()
+
+	s.logger.Info("Session killed",
+		zap.String("session_id", id),
+		zap.String("killed_by", sess.username),
+	)
+
+	return storage.Result{Affected: 1}, nil
+}
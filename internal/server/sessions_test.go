@@ -0,0 +1,102 @@
+package server
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/telumdb/telumdb/internal/config"
+	"github.com/telumdb/telumdb/internal/protocol"
+	"go.uber.org/zap"
+)
+
+// waitForSessions waits until handleConnection goroutines have unregistered down to n sessions
+func waitForSessions(t *testing.T, srv *Server, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for srv.sessions.count() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d sessions, want %d", srv.sessions.count(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConnectionLimit(t *testing.T) {
+	srv := &Server{
+		config:   &config.Config{},
+		storage:  &stubEngine{},
+		logger:   zap.NewNop(),
+		sessions: newSessionRegistry(1),
+	}
+
+	first := connectPipe(t, srv)
+	login(t, first, "alice", "")
+
+	second := connectPipe(t, srv)
+	var resp protocol.Response
+	if err := protocol.ReadMessage(second, &resp); err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	if resp.Success || resp.Code != protocol.ErrorCodeTooManyConnections {
+		t.Errorf("connection over the limit: got %+v, want %s", resp, protocol.ErrorCodeTooManyConnections)
+	}
+
+	// Closing a connection frees its slot
+	first.Close()
+	waitForSessions(t, srv, 0)
+	login(t, connectPipe(t, srv), "alice", "")
+}
+
+func TestSessionCommands(t *testing.T) {
+	srv := newAuthServer(t)
+	query := func(conn net.Conn, sessionID, q string) protocol.Response {
+		return roundTrip(t, conn, protocol.Request{Type: protocol.MessageTypeQuery, Query: q, SessionID: sessionID})
+	}
+
+	admin := connectPipe(t, srv)
+	adminSession := login(t, admin, "admin", "admin-pw")
+	if resp := query(admin, adminSession, "CREATE USER carol PASSWORD 'pw'"); !resp.Success {
+		t.Fatalf("CREATE USER failed: %s", resp.Error)
+	}
+
+	carol := connectPipe(t, srv)
+	carolSession := login(t, carol, "carol", "pw")
+
+	resp := query(admin, adminSession, "SHOW SESSIONS")
+	if !resp.Success || len(resp.Rows) != 2 {
+		t.Fatalf("SHOW SESSIONS: got %+v, want 2 sessions", resp)
+	}
+	for _, row := range resp.Rows {
+		if row[0] == adminSession && row[5] != "SHOW SESSIONS" {
+			t.Errorf("SHOW SESSIONS: current query = %v, want SHOW SESSIONS", row[5])
+		}
+	}
+
+	// Non-superusers only see and kill their own sessions
+	if resp := query(carol, carolSession, "SHOW SESSIONS"); !resp.Success || len(resp.Rows) != 1 {
+		t.Errorf("SHOW SESSIONS as carol: got %+v, want 1 session", resp)
+	}
+	if resp := query(carol, carolSession, "KILL SESSION "+adminSession); resp.Success {
+		t.Error("non-superuser should not be able to kill other users' sessions")
+	}
+	if resp := query(admin, adminSession, "KILL SESSION "+adminSession); resp.Success {
+		t.Error("KILL SESSION should refuse to kill the current session")
+	}
+
+	if resp := query(admin, adminSession, "KILL SESSION "+carolSession); !resp.Success {
+		t.Fatalf("KILL SESSION failed: %s", resp.Error)
+	}
+	if err := protocol.WriteMessage(carol, protocol.Request{Type: protocol.MessageTypeQuery, Query: "SHOW SESSIONS", SessionID: carolSession}); err == nil {
+		var resp protocol.Response
+		if err := protocol.ReadMessage(carol, &resp); err == nil {
+			t.Errorf("killed session still answered: %+v", resp)
+		}
+	}
+
+	waitForSessions(t, srv, 1)
+	if resp := query(admin, adminSession, "KILL SESSION "+carolSession); resp.Success {
+		t.Error("KILL SESSION should fail for sessions that are gone")
+	}
+}
@@ -0,0 +1,578 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/telumdb/telumdb/pkg/storage"
+)
+
+// Table statements
+//
+// SELECT, INSERT, UPDATE and DELETE naming a table of the storage engine run
+// through the table API of the session's engine, which enforces the
+// privileges granted to its user. Only a simple form is understood:
+//
+//	SELECT * | column, ... FROM table [WHERE condition]
+//	    [ORDER BY column [ASC | DESC], ...] [LIMIT n [OFFSET n]]
+//	INSERT INTO table (column, ...) VALUES (value, ...)
+//	UPDATE table SET column = value, ... [WHERE condition]
+//	DELETE FROM table [WHERE condition]
+//
+// A condition is one or more comparisons of a column with a literal, or
+// column IS [NOT] NULL, joined by AND. Literals are quoted strings, numbers,
+// TRUE, FALSE and NULL. Any other statement is raw SQL against the catalog,
+// which only superusers may run.
+
+// tableStatement is a parsed table statement
+type tableStatement struct {
+	verb      string
+	table     string
+	row       storage.Row
+	condition storage.Condition
+	opts      storage.SelectOptions
+}
+
+// executeTableStatement runs query through the table API if it is a table
+// statement. The boolean result reports whether the statement was handled.
+// Superusers' statements that don't parse or don't name a table of the
+// engine are left to run as raw SQL.
+func (s *Server) executeTableStatement(ctx context.Context, sess *session, query string) (storage.Result, bool, error) {
+	stmt, err := parseTableStatement(normalizeStatement(query))
+	if stmt == nil && err == nil {
+		return storage.Result{}, false, nil
+	}
+	if sess.superuser {
+		if err != nil {
+			return storage.Result{}, false, nil
+		}
+		if _, err := s.storage.GetTable(stmt.table); errors.Is(err, storage.ErrNotFound) {
+			return storage.Result{}, false, nil
+		}
+	}
+	if err != nil {
+		return storage.Result{}, true, err
+	}
+
+	table, err := sess.engine.GetTable(stmt.table)
+	if err != nil {
+		return storage.Result{}, true, err
+	}
+
+	var result storage.Result
+	switch stmt.verb {
+	case "SELECT":
+		result, err = selectRows(ctx, table, stmt)
+	case "INSERT":
+		_, err = table.Insert(ctx, stmt.row)
+		result.Affected = 1
+	case "UPDATE":
+		result.Affected, err = table.Update(ctx, stmt.row, stmt.condition)
+	case "DELETE":
+		result.Affected, err = table.Delete(ctx, stmt.condition)
+	}
+	if err != nil {
+		return storage.Result{}, true, err
+	}
+	return result, true, nil
+}
+
+// selectRows reads the rows matched by a SELECT statement
+func selectRows(ctx context.Context, table storage.Table, stmt *tableStatement) (storage.Result, error) {
+	opts := stmt.opts
+	if len(opts.Columns) == 0 {
+		for _, col := range table.Schema().Columns {
+			opts.Columns = append(opts.Columns, col.Name)
+		}
+	}
+
+	it, err := table.SelectWithOptions(ctx, stmt.condition, opts)
+	if err != nil {
+		return storage.Result{}, err
+	}
+	defer it.Close()
+
+	var rows []storage.Row
+	for it.Next() {
+		var row storage.Row
+		if err := it.Scan(&row); err != nil {
+			return storage.Result{}, err
+		}
+		rows = append(rows, row)
+	}
+	if err := it.Err(); err != nil {
+		return storage.Result{}, err
+	}
+
+	// A table without a schema returns whichever columns its rows have
+	columns := opts.Columns
+	if len(columns) == 0 {
+		seen := make(map[string]bool)
+		for _, row := range rows {
+			for col := range row {
+				if !seen[col] {
+					seen[col] = true
+					columns = append(columns, col)
+				}
+			}
+		}
+		sort.Strings(columns)
+	}
+
+	result := storage.Result{Columns: columns, Rows: make([][]interface{}, len(rows))}
+	for i, row := range rows {
+		values := make([]interface{}, len(columns))
+		for j, col := range columns {
+			values[j] = row[col]
+		}
+		result.Rows[i] = values
+	}
+	return result, nil
+}
+
+// parseTableStatement parses a table statement. It returns nil without an
+// error if the statement doesn't start with SELECT, INSERT, UPDATE or DELETE.
+func parseTableStatement(query string) (*tableStatement, error) {
+	tokens, err := tokenizeStatement(query)
+	if err != nil || len(tokens) == 0 || tokens[0].kind != tokenIdent {
+		return nil, nil
+	}
+
+	p := &statementParser{tokens: tokens}
+	stmt := &tableStatement{verb: strings.ToUpper(p.next().text)}
+	switch stmt.verb {
+	case "SELECT":
+		err = p.parseSelect(stmt)
+	case "INSERT":
+		err = p.parseInsert(stmt)
+	case "UPDATE":
+		err = p.parseUpdate(stmt)
+	case "DELETE":
+		err = p.parseDelete(stmt)
+	default:
+		return nil, nil
+	}
+	if err == nil && !p.done() {
+		err = p.unexpected()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s statement: %w", stmt.verb, err)
+	}
+	return stmt, nil
+}
+
+// parseSelect parses the rest of a SELECT statement
+func (p *statementParser) parseSelect(stmt *tableStatement) error {
+	if !p.symbol("*") {
+		columns, err := p.identList()
+		if err != nil {
+			return err
+		}
+		stmt.opts.Columns = columns
+	}
+
+	if err := p.expectKeyword("FROM"); err != nil {
+		return err
+	}
+	table, err := p.ident()
+	if err != nil {
+		return err
+	}
+	stmt.table = table
+
+	if stmt.condition, err = p.optionalWhere(); err != nil {
+		return err
+	}
+
+	if p.keyword("ORDER") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return err
+		}
+		for {
+			col, err := p.ident()
+			if err != nil {
+				return err
+			}
+			order := storage.OrderBy{Column: col}
+			if p.keyword("DESC") {
+				order.Desc = true
+			} else {
+				p.keyword("ASC")
+			}
+			stmt.opts.OrderBy = append(stmt.opts.OrderBy, order)
+			if !p.symbol(",") {
+				break
+			}
+		}
+	}
+
+	if p.keyword("LIMIT") {
+		if stmt.opts.Limit, err = p.count(); err != nil {
+			return err
+		}
+		if p.keyword("OFFSET") {
+			if stmt.opts.Offset, err = p.count(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseInsert parses the rest of an INSERT statement
+func (p *statementParser) parseInsert(stmt *tableStatement) error {
+	if err := p.expectKeyword("INTO"); err != nil {
+		return err
+	}
+	table, err := p.ident()
+	if err != nil {
+		return err
+	}
+	stmt.table = table
+
+	if err := p.expectSymbol("("); err != nil {
+		return err
+	}
+	columns, err := p.identList()
+	if err != nil {
+		return err
+	}
+	if err := p.expectSymbol(")"); err != nil {
+		return err
+	}
+
+	if err := p.expectKeyword("VALUES"); err != nil {
+		return err
+	}
+	if err := p.expectSymbol("("); err != nil {
+		return err
+	}
+	stmt.row = make(storage.Row, len(columns))
+	for i, col := range columns {
+		if i > 0 {
+			if err := p.expectSymbol(","); err != nil {
+				return err
+			}
+		}
+		if stmt.row[col], err = p.value(); err != nil {
+			return err
+		}
+	}
+	return p.expectSymbol(")")
+}
+
+// parseUpdate parses the rest of an UPDATE statement
+func (p *statementParser) parseUpdate(stmt *tableStatement) error {
+	table, err := p.ident()
+	if err != nil {
+		return err
+	}
+	stmt.table = table
+
+	if err := p.expectKeyword("SET"); err != nil {
+		return err
+	}
+	stmt.row = make(storage.Row)
+	for {
+		col, err := p.ident()
+		if err != nil {
+			return err
+		}
+		if err := p.expectSymbol("="); err != nil {
+			return err
+		}
+		if stmt.row[col], err = p.value(); err != nil {
+			return err
+		}
+		if !p.symbol(",") {
+			break
+		}
+	}
+
+	stmt.condition, err = p.optionalWhere()
+	return err
+}
+
+// parseDelete parses the rest of a DELETE statement
+func (p *statementParser) parseDelete(stmt *tableStatement) error {
+	if err := p.expectKeyword("FROM"); err != nil {
+		return err
+	}
+	table, err := p.ident()
+	if err != nil {
+		return err
+	}
+	stmt.table = table
+
+	stmt.condition, err = p.optionalWhere()
+	return err
+}
+
+// optionalWhere parses a WHERE clause if one follows
+func (p *statementParser) optionalWhere() (storage.Condition, error) {
+	if !p.keyword("WHERE") {
+		return nil, nil
+	}
+
+	var conditions []storage.Condition
+	for {
+		condition, err := p.comparison()
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+		if !p.keyword("AND") {
+			break
+		}
+	}
+
+	if len(conditions) == 1 {
+		return conditions[0], nil
+	}
+	return storage.NewAndCondition(conditions...), nil
+}
+
+// comparison parses column op value or column IS [NOT] NULL
+func (p *statementParser) comparison() (storage.Condition, error) {
+	col, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.keyword("IS") {
+		not := p.keyword("NOT")
+		if err := p.expectKeyword("NULL"); err != nil {
+			return nil, err
+		}
+		if not {
+			return storage.NewIsNotNullCondition(col), nil
+		}
+		return storage.NewIsNullCondition(col), nil
+	}
+
+	tok := p.next()
+	switch tok.text {
+	case "=", "!=", "<>", "<", "<=", ">", ">=":
+	default:
+		p.pos--
+		return nil, p.unexpected()
+	}
+	value, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	return storage.NewSimpleCondition(col, tok.text, value), nil
+}
+
+// tokenKind classifies the tokens of a statement
+type tokenKind int
+
+const (
+	tokenIdent tokenKind = iota
+	tokenString
+	tokenNumber
+	tokenSymbol
+)
+
+// token is a word, literal or punctuation of a statement
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// tokenizeStatement splits a statement into tokens
+func tokenizeStatement(query string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(query); {
+		c := rune(query[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i < len(query) && (query[i] == '_' || unicode.IsLetter(rune(query[i])) || unicode.IsDigit(rune(query[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{tokenIdent, query[start:i]})
+		case c == '"':
+			end := strings.IndexByte(query[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated identifier")
+			}
+			tokens = append(tokens, token{tokenIdent, query[i+1 : i+1+end]})
+			i += end + 2
+		case c == '\'':
+			var text strings.Builder
+			for i++; ; i++ {
+				if i >= len(query) {
+					return nil, fmt.Errorf("unterminated string")
+				}
+				if query[i] == '\'' {
+					if i+1 < len(query) && query[i+1] == '\'' {
+						i++
+					} else {
+						i++
+						break
+					}
+				}
+				text.WriteByte(query[i])
+			}
+			tokens = append(tokens, token{tokenString, text.String()})
+		case unicode.IsDigit(c) || c == '.':
+			start := i
+			for i < len(query) && (unicode.IsDigit(rune(query[i])) || strings.IndexByte(".eE", query[i]) >= 0 ||
+				(strings.IndexByte("+-", query[i]) >= 0 && (query[i-1] == 'e' || query[i-1] == 'E'))) {
+				i++
+			}
+			tokens = append(tokens, token{tokenNumber, query[start:i]})
+		default:
+			text := query[i : i+1]
+			if i+1 < len(query) {
+				switch two := query[i : i+2]; two {
+				case "!=", "<>", "<=", ">=":
+					text = two
+				}
+			}
+			tokens = append(tokens, token{tokenSymbol, text})
+			i += len(text)
+		}
+	}
+	return tokens, nil
+}
+
+// statementParser reads the tokens of a statement in order
+type statementParser struct {
+	tokens []token
+	pos    int
+}
+
+// done reports whether every token was consumed
+func (p *statementParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+// next consumes a token. At the end it returns an empty symbol.
+func (p *statementParser) next() token {
+	if p.done() {
+		p.pos++
+		return token{kind: tokenSymbol}
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+	return tok
+}
+
+// unexpected reports the token at the current position
+func (p *statementParser) unexpected() error {
+	if p.done() {
+		return fmt.Errorf("unexpected end of statement")
+	}
+	return fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+}
+
+// keyword consumes the keyword if it comes next
+func (p *statementParser) keyword(word string) bool {
+	if !p.done() && p.tokens[p.pos].kind == tokenIdent && strings.EqualFold(p.tokens[p.pos].text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// expectKeyword consumes the keyword or fails
+func (p *statementParser) expectKeyword(word string) error {
+	if !p.keyword(word) {
+		return fmt.Errorf("expected %s: %w", word, p.unexpected())
+	}
+	return nil
+}
+
+// symbol consumes the punctuation if it comes next
+func (p *statementParser) symbol(text string) bool {
+	if !p.done() && p.tokens[p.pos].kind == tokenSymbol && p.tokens[p.pos].text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// expectSymbol consumes the punctuation or fails
+func (p *statementParser) expectSymbol(text string) error {
+	if !p.symbol(text) {
+		return fmt.Errorf("expected %q: %w", text, p.unexpected())
+	}
+	return nil
+}
+
+// ident consumes a column or table name
+func (p *statementParser) ident() (string, error) {
+	if p.done() || p.tokens[p.pos].kind != tokenIdent {
+		return "", fmt.Errorf("expected a name: %w", p.unexpected())
+	}
+	return p.next().text, nil
+}
+
+// identList consumes comma separated names
+func (p *statementParser) identList() ([]string, error) {
+	var names []string
+	for {
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+		if !p.symbol(",") {
+			return names, nil
+		}
+	}
+}
+
+// count consumes a non-negative integer
+func (p *statementParser) count() (int, error) {
+	tok := p.next()
+	n, err := strconv.Atoi(tok.text)
+	if tok.kind != tokenNumber || err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid count %q", tok.text)
+	}
+	return n, nil
+}
+
+// value consumes a literal
+func (p *statementParser) value() (interface{}, error) {
+	negative := p.symbol("-")
+	if p.done() {
+		return nil, p.unexpected()
+	}
+
+	tok := p.next()
+	switch {
+	case tok.kind == tokenNumber:
+		if negative {
+			tok.text = "-" + tok.text
+		}
+		if n, err := strconv.ParseInt(tok.text, 10, 64); err == nil {
+			return n, nil
+		}
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", tok.text)
+		}
+		return f, nil
+	case negative:
+	case tok.kind == tokenString:
+		return tok.text, nil
+	case tok.kind == tokenIdent && strings.EqualFold(tok.text, "NULL"):
+		return nil, nil
+	case tok.kind == tokenIdent && strings.EqualFold(tok.text, "TRUE"):
+		return true, nil
+	case tok.kind == tokenIdent && strings.EqualFold(tok.text, "FALSE"):
+		return false, nil
+	}
+	p.pos--
+	return nil, fmt.Errorf("expected a value: %w", p.unexpected())
+}
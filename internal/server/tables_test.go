@@ -0,0 +1,62 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/telumdb/telumdb/pkg/storage"
+)
+
+func TestParseTableStatement(t *testing.T) {
+	tests := []struct {
+		query     string
+		want      *tableStatement
+		condition string
+		wantErr   bool
+	}{
+		{
+			query: "SELECT * FROM events",
+			want:  &tableStatement{verb: "SELECT", table: "events"},
+		},
+		{
+			query:     "select id, kind from events where kind = 'it''s' and id >= -2 and note is not null order by id desc, kind limit 10 offset 5",
+			want:      &tableStatement{verb: "SELECT", table: "events", opts: storage.SelectOptions{Columns: []string{"id", "kind"}, OrderBy: []storage.OrderBy{{Column: "id", Desc: true}, {Column: "kind"}}, Limit: 10, Offset: 5}},
+			condition: "kind = 'it''s' AND id >= -2 AND NOT note IS NULL",
+		},
+		{
+			query: `INSERT INTO events ("id", kind, score, ok, note) VALUES (1, 'click', 2.5e1, TRUE, NULL)`,
+			want:  &tableStatement{verb: "INSERT", table: "events", row: storage.Row{"id": int64(1), "kind": "click", "score": 25.0, "ok": true, "note": nil}},
+		},
+		{
+			query:     "UPDATE events SET kind = 'view' WHERE id <> 3",
+			want:      &tableStatement{verb: "UPDATE", table: "events", row: storage.Row{"kind": "view"}},
+			condition: "id <> 3",
+		},
+		{
+			query: "DELETE FROM events",
+			want:  &tableStatement{verb: "DELETE", table: "events"},
+		},
+		{query: "SHOW TABLES"},
+		{query: "SELECT COUNT(*) FROM events", wantErr: true},
+		{query: "INSERT INTO events (id) VALUES (1, 2)", wantErr: true},
+		{query: "DELETE FROM events WHERE id = ", wantErr: true},
+		{query: "UPDATE events SET kind = 'a' WHERE kind LIKE 'b'", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		stmt, err := parseTableStatement(tt.query)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTableStatement(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if stmt != nil && stmt.condition != nil {
+			if got := stmt.condition.String(); got != tt.condition {
+				t.Errorf("parseTableStatement(%q) condition = %s, want %s", tt.query, got, tt.condition)
+			}
+			stmt.condition = nil
+		}
+		if !reflect.DeepEqual(stmt, tt.want) {
+			t.Errorf("parseTableStatement(%q) = %+v, want %+v", tt.query, stmt, tt.want)
+		}
+	}
+}
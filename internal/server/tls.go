@@ -0,0 +1,92 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/telumdb/telumdb/internal/config"
+)
+
+// loadTLSConfig builds the TLS configuration shared by the database and HTTP
+// listeners. It returns nil when TLS is disabled.
+func loadTLSConfig(cfg config.ServerConfig) (*tls.Config, error) {
+	if !cfg.EnableTLS {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA file %s", cfg.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+
+		// Certificates are checked whenever presented; requiring them is opt-in
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		if cfg.RequireClientCert {
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// httpTLSConfig derives the TLS configuration of the HTTP listener from the
+// database port's. Health probes and load balancers rarely hold a client
+// certificate, so the handshake only verifies certificates when presented and
+// requireClientCert enforces RequireClientCert for the other endpoints.
+func httpTLSConfig(tlsConfig *tls.Config) *tls.Config {
+	if tlsConfig == nil || tlsConfig.ClientAuth != tls.RequireAndVerifyClientCert {
+		return tlsConfig
+	}
+	httpConfig := tlsConfig.Clone()
+	httpConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	return httpConfig
+}
+
+// requireClientCert rejects requests without a verified client certificate
+// when the configuration requires one
+func (s *Server) requireClientCert(next http.Handler) http.Handler {
+	if s.tlsConfig == nil || s.tlsConfig.ClientAuth != tls.RequireAndVerifyClientCert {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			writeAPIError(w, http.StatusForbidden, fmt.Errorf("client certificate required"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// listen opens a TCP listener on addr, wrapped in TLS when it is enabled
+func (s *Server) listen(addr string) (net.Listener, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.tlsConfig != nil {
+		listener = tls.NewListener(listener, s.tlsConfig)
+	}
+
+	return listener, nil
+}
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/telumdb/telumdb/internal/client"
+	"github.com/telumdb/telumdb/internal/config"
+	"github.com/telumdb/telumdb/pkg/storage"
+	"go.uber.org/zap"
+)
+
+// testCert is a generated certificate and key
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+// newTestCert creates a certificate signed by parent, or a self-signed CA when parent is nil
+func newTestCert(t *testing.T, name string, parent *testCert, usage x509.ExtKeyUsage) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+
+	signerCert, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		template.ExtKeyUsage = nil
+	} else {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+// writeFiles writes the certificate and key as PEM files and returns their paths
+func (c *testCert) writeFiles(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0600); err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+
+	return certFile, keyFile
+}
+
+func TestServerTLS(t *testing.T) {
+	dir := t.TempDir()
+
+	ca := newTestCert(t, "telumdb-test-ca", nil, 0)
+	caFile, _ := ca.writeFiles(t, dir, "ca")
+	serverCert, serverKey := newTestCert(t, "localhost", ca, x509.ExtKeyUsageServerAuth).writeFiles(t, dir, "server")
+	clientCert, clientKey := newTestCert(t, "admin", ca, x509.ExtKeyUsageClientAuth).writeFiles(t, dir, "client")
+
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			Host:              "127.0.0.1",
+			EnableTLS:         true,
+			CertFile:          serverCert,
+			KeyFile:           serverKey,
+			ClientCAFile:      caFile,
+			RequireClientCert: true,
+			AuthEnabled:       true,
+			AdminUser:         "admin",
+			AdminPassword:     "admin-pw",
+		},
+		Storage: config.StorageConfig{DataDir: t.TempDir()},
+	}
+
+	engine, err := storage.NewEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+	srv, err := New(cfg, engine, zap.NewNop())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := srv.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer srv.Shutdown(context.Background())
+
+	addr := srv.listener.Addr().String()
+	baseURL := fmt.Sprintf("telumdb://admin:admin-pw@%s/main", addr)
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr bool
+	}{
+		{"verify-full with client cert", fmt.Sprintf("?sslmode=verify-full&sslrootcert=%s&sslcert=%s&sslkey=%s", caFile, clientCert, clientKey), false},
+		{"verify-ca with client cert", fmt.Sprintf("?sslmode=verify-ca&sslrootcert=%s&sslcert=%s&sslkey=%s", caFile, clientCert, clientKey), false},
+		{"require with client cert", fmt.Sprintf("?sslmode=require&sslcert=%s&sslkey=%s", clientCert, clientKey), false},
+		{"missing client cert", fmt.Sprintf("?sslmode=verify-full&sslrootcert=%s", caFile), true},
+		{"untrusted server", fmt.Sprintf("?sslmode=verify-full&sslcert=%s&sslkey=%s", clientCert, clientKey), true},
+		{"plain TCP", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := client.New(&client.Config{ServerURL: baseURL + tt.query, Timeout: 5 * time.Second})
+			if err != nil {
+				t.Fatalf("client.New() error = %v", err)
+			}
+			defer c.Close()
+
+			_, err = c.Execute(ctx, "SHOW USERS")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// The HTTP API is served over TLS with the same certificates
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
+	if err != nil {
+		t.Fatalf("LoadX509KeyPair() error = %v", err)
+	}
+	httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{cert},
+	}}}
+
+	resp, err := httpClient.Get(fmt.Sprintf("https://%s/livez", srv.httpListener.Addr()))
+	if err != nil {
+		t.Fatalf("GET /livez over TLS error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /livez status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	// Health probes such as telumdb health hold no client certificate, while
+	// the API still requires one
+	probe := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	for path, want := range map[string]int{
+		"/readyz":         http.StatusOK,
+		"/livez":          http.StatusOK,
+		"/api/v1/tables":  http.StatusForbidden,
+		"/api/v1/tensors": http.StatusForbidden,
+	} {
+		resp, err := probe.Get(fmt.Sprintf("https://%s%s", srv.httpListener.Addr(), path))
+		if err != nil {
+			t.Errorf("GET %s without client cert error = %v", path, err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("GET %s without client cert status = %d, want %d", path, resp.StatusCode, want)
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("https://%s/api/v1/tables", srv.httpListener.Addr()), nil)
+	req.SetBasicAuth("admin", "admin-pw")
+	resp, err = httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /api/v1/tables with client cert error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET /api/v1/tables with client cert status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
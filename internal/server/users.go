@@ -0,0 +1,208 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/telumdb/telumdb/pkg/storage"
+	"go.uber.org/zap"
+)
+
+// adminPasswordFile is the file in the data directory that receives a
+// generated admin password
+const adminPasswordFile = "initial_admin_password"
+
+// bootstrapUsers creates the configured admin account when no users exist yet.
+// If no admin password is configured, a random one is generated and written
+// to a file in the data directory readable only by its owner, so it never
+// reaches the logs.
+func (s *Server) bootstrapUsers() error {
+	if !s.config.Server.AuthEnabled {
+		return nil
+	}
+
+	users, err := s.users.ListUsers()
+	if err != nil {
+		return err
+	}
+	if len(users) > 0 {
+		return nil
+	}
+
+	password := s.config.Server.AdminPassword
+	if password != "" {
+		if err := s.users.CreateUser(s.config.Server.AdminUser, password, true); err != nil {
+			return err
+		}
+		s.logger.Info("Created initial admin user", zap.String("username", s.config.Server.AdminUser))
+		return nil
+	}
+
+	if password, err = generatePassword(); err != nil {
+		return err
+	}
+	path, err := writePasswordFile(s.config.Storage.DataDir, password)
+	if err != nil {
+		return err
+	}
+	if err := s.users.CreateUser(s.config.Server.AdminUser, password, true); err != nil {
+		os.Remove(path)
+		return err
+	}
+
+	s.logger.Warn("Created initial admin user with a generated password; change it with ALTER USER and delete the password file",
+		zap.String("username", s.config.Server.AdminUser),
+		zap.String("password_file", path),
+	)
+	return nil
+}
+
+// writePasswordFile writes a generated admin password to a file in dataDir
+// that only its owner can read and returns the file's path
+func writePasswordFile(dataDir, password string) (string, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	path := filepath.Join(dataDir, adminPasswordFile)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", fmt.Errorf("failed to write admin password file: %w", err)
+	}
+	defer f.Close()
+
+	// A file left from an earlier data directory may have other permissions
+	if err := f.Chmod(0600); err != nil {
+		return "", fmt.Errorf("failed to write admin password file: %w", err)
+	}
+	if _, err := f.WriteString(password + "\n"); err != nil {
+		return "", fmt.Errorf("failed to write admin password file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("failed to write admin password file: %w", err)
+	}
+	return path, nil
+}
+
+// generatePassword returns a random URL-safe password
+func generatePassword() (string, error) {
+	buf := make([]byte, 18)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate password: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// userStore returns the engine's user store or an error if accounts are unsupported
+func (s *Server) userStore() (storage.UserStore, error) {
+	if s.users == nil {
+		return nil, fmt.Errorf("the storage engine does not support user accounts")
+	}
+	return s.users, nil
+}
+
+// createUser handles CREATE USER name [WITH] PASSWORD '...' [SUPERUSER]
+func (s *Server) createUser(ctx context.Context, sess *session, args []string) (storage.Result, error) {
+	if !sess.superuser {
+		return storage.Result{}, fmt.Errorf("%w: only superusers can create users", storage.ErrPermissionDenied)
+	}
+
+	users, err := s.userStore()
+	if err != nil {
+		return storage.Result{}, err
+	}
+
+	superuser := strings.TrimSpace(args[2]) != ""
+	if err := users.CreateUser(args[0], unquoteString(args[1]), superuser); err != nil {
+		return storage.Result{}, err
+	}
+
+	s.logger.Info("User created",
+		zap.String("username", args[0]),
+		zap.Bool("superuser", superuser),
+		zap.String("created_by", sess.username),
+	)
+
+	return storage.Result{Affected: 1}, nil
+}
+
+// alterUser handles ALTER USER name [WITH] PASSWORD '...'
+func (s *Server) alterUser(ctx context.Context, sess *session, args []string) (storage.Result, error) {
+	// Users may always change their own password
+	if !sess.superuser && args[0] != sess.username {
+		return storage.Result{}, fmt.Errorf("%w: only superusers can alter other users", storage.ErrPermissionDenied)
+	}
+
+	users, err := s.userStore()
+	if err != nil {
+		return storage.Result{}, err
+	}
+
+	if err := users.SetPassword(args[0], unquoteString(args[1])); err != nil {
+		return storage.Result{}, err
+	}
+	s.credentials.forget(args[0])
+
+	s.logger.Info("User password changed",
+		zap.String("username", args[0]),
+		zap.String("changed_by", sess.username),
+	)
+
+	return storage.Result{Affected: 1}, nil
+}
+
+// dropUser handles DROP USER name
+func (s *Server) dropUser(ctx context.Context, sess *session, args []string) (storage.Result, error) {
+	if !sess.superuser {
+		return storage.Result{}, fmt.Errorf("%w: only superusers can drop users", storage.ErrPermissionDenied)
+	}
+	if args[0] == sess.username {
+		return storage.Result{}, fmt.Errorf("cannot drop the current user")
+	}
+
+	users, err := s.userStore()
+	if err != nil {
+		return storage.Result{}, err
+	}
+
+	if err := users.DropUser(args[0]); err != nil {
+		return storage.Result{}, err
+	}
+	s.credentials.forget(args[0])
+
+	s.logger.Info("User dropped",
+		zap.String("username", args[0]),
+		zap.String("dropped_by", sess.username),
+	)
+
+	return storage.Result{Affected: 1}, nil
+}
+
+// showUsers handles SHOW USERS
+func (s *Server) showUsers(ctx context.Context, sess *session, args []string) (storage.Result, error) {
+	if !sess.superuser {
+		return storage.Result{}, fmt.Errorf("%w: only superusers can list users", storage.ErrPermissionDenied)
+	}
+
+	users, err := s.userStore()
+	if err != nil {
+		return storage.Result{}, err
+	}
+
+	list, err := users.ListUsers()
+	if err != nil {
+		return storage.Result{}, err
+	}
+
+	result := storage.Result{Columns: []string{"name", "superuser", "created_at"}}
+	for _, user := range list {
+		result.Rows = append(result.Rows, []interface{}{user.Name, user.Superuser, user.CreatedAt})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,376 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// Authorize returns a view of engine that enforces the privileges of user.
+// Superusers get the engine itself; other users get a wrapper that checks
+// grants on every table and tensor operation.
+func Authorize(engine Engine, user *User) (Engine, error) {
+	if user == nil {
+		return nil, fmt.Errorf("no user to authorize")
+	}
+	if user.Superuser {
+		return engine, nil
+	}
+
+	privileges, ok := engine.(PrivilegeStore)
+	if !ok {
+		return nil, fmt.Errorf("the storage engine does not support access control")
+	}
+
+	return &authorizedEngine{
+		engine:     engine,
+		privileges: privileges,
+		user:       user.Name,
+	}, nil
+}
+
+// authorizedEngine wraps an Engine and checks privileges for a single user
+type authorizedEngine struct {
+	engine     Engine
+	privileges PrivilegeStore
+	user       string
+}
+
+// require returns a PermissionError unless the user holds privilege on the object
+func (e *authorizedEngine) require(objectType, objectName, privilege string) error {
+	ok, err := e.privileges.HasPrivilege(e.user, objectType, objectName, privilege)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &PermissionError{
+			User:       e.user,
+			Privilege:  privilege,
+			ObjectType: objectType,
+			ObjectName: objectName,
+		}
+	}
+	return nil
+}
+
+// requireAny returns a PermissionError unless the user holds any privilege on the object
+func (e *authorizedEngine) requireAny(objectType, objectName string) error {
+	for _, privilege := range objectPrivileges[objectType] {
+		ok, err := e.privileges.HasPrivilege(e.user, objectType, objectName, privilege)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+	}
+
+	return &PermissionError{
+		User:       e.user,
+		Privilege:  "any privilege",
+		ObjectType: objectType,
+		ObjectName: objectName,
+	}
+}
+
+// denied reports an operation that only superusers may perform
+func (e *authorizedEngine) denied(operation string) error {
+	return fmt.Errorf("%w: %s requires superuser", ErrPermissionDenied, operation)
+}
+
+// Start is reserved for superusers
+func (e *authorizedEngine) Start(ctx context.Context) error {
+	return e.denied("starting the engine")
+}
+
+// Shutdown is reserved for superusers
+func (e *authorizedEngine) Shutdown(ctx context.Context) error {
+	return e.denied("shutting down the engine")
+}
+
+// CreateTable requires DDL on the table name
+func (e *authorizedEngine) CreateTable(name string, schema TableSchema) error {
+	if err := e.require(ObjectTypeTable, name, PrivilegeDDL); err != nil {
+		return err
+	}
+	return e.engine.CreateTable(name, schema)
+}
+
+// DropTable requires DDL on the table
+func (e *authorizedEngine) DropTable(name string) error {
+	if err := e.require(ObjectTypeTable, name, PrivilegeDDL); err != nil {
+		return err
+	}
+	return e.engine.DropTable(name)
+}
+
+// CreateIndex requires DDL on the table
+func (e *authorizedEngine) CreateIndex(table string, index IndexDefinition) error {
+	if err := e.require(ObjectTypeTable, table, PrivilegeDDL); err != nil {
+		return err
+	}
+	indexes, ok := e.engine.(IndexManager)
+	if !ok {
+		return fmt.Errorf("the storage engine does not support indexes")
+	}
+	return indexes.CreateIndex(table, index)
+}
+
+// DropIndex requires DDL on the table
+func (e *authorizedEngine) DropIndex(table, name string) error {
+	if err := e.require(ObjectTypeTable, table, PrivilegeDDL); err != nil {
+		return err
+	}
+	indexes, ok := e.engine.(IndexManager)
+	if !ok {
+		return fmt.Errorf("the storage engine does not support indexes")
+	}
+	return indexes.DropIndex(table, name)
+}
+
+// GetTable requires at least one privilege on the table
+func (e *authorizedEngine) GetTable(name string) (Table, error) {
+	if err := e.requireAny(ObjectTypeTable, name); err != nil {
+		return nil, err
+	}
+
+	table, err := e.engine.GetTable(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &authorizedTable{table: table, engine: e}, nil
+}
+
+// ListTables lists the tables the user holds any privilege on
+func (e *authorizedEngine) ListTables() ([]string, error) {
+	names, err := e.engine.ListTables()
+	if err != nil {
+		return nil, err
+	}
+	return e.filterVisible(ObjectTypeTable, names)
+}
+
+// CreateTensor requires DDL on the tensor name
+func (e *authorizedEngine) CreateTensor(name string, schema TensorSchema) error {
+	if err := e.require(ObjectTypeTensor, name, PrivilegeDDL); err != nil {
+		return err
+	}
+	return e.engine.CreateTensor(name, schema)
+}
+
+// DropTensor requires DDL on the tensor
+func (e *authorizedEngine) DropTensor(name string) error {
+	if err := e.require(ObjectTypeTensor, name, PrivilegeDDL); err != nil {
+		return err
+	}
+	return e.engine.DropTensor(name)
+}
+
+// GetTensor requires at least one privilege on the tensor
+func (e *authorizedEngine) GetTensor(name string) (Tensor, error) {
+	if err := e.requireAny(ObjectTypeTensor, name); err != nil {
+		return nil, err
+	}
+
+	tensor, err := e.engine.GetTensor(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &authorizedTensor{tensor: tensor, engine: e}, nil
+}
+
+// ListTensors lists the tensors the user holds any privilege on
+func (e *authorizedEngine) ListTensors() ([]string, error) {
+	names, err := e.engine.ListTensors()
+	if err != nil {
+		return nil, err
+	}
+	return e.filterVisible(ObjectTypeTensor, names)
+}
+
+// ExecuteQuery is reserved for superusers since raw SQL bypasses object privileges
+func (e *authorizedEngine) ExecuteQuery(ctx context.Context, query string) (Result, error) {
+	return Result{}, e.denied("raw SQL")
+}
+
+// BeginTransaction is reserved for superusers
+func (e *authorizedEngine) BeginTransaction(ctx context.Context) (Transaction, error) {
+	return nil, e.denied("transactions")
+}
+
+// filterVisible keeps the object names the user holds any privilege on
+func (e *authorizedEngine) filterVisible(objectType string, names []string) ([]string, error) {
+	visible := make([]string, 0, len(names))
+	for _, name := range names {
+		err := e.requireAny(objectType, name)
+		if err == nil {
+			visible = append(visible, name)
+			continue
+		}
+		if _, denied := err.(*PermissionError); !denied {
+			return nil, err
+		}
+	}
+	return visible, nil
+}
+
+// authorizedTable checks table privileges before delegating
+type authorizedTable struct {
+	table  Table
+	engine *authorizedEngine
+}
+
+// Name returns the table name
+func (t *authorizedTable) Name() string {
+	return t.table.Name()
+}
+
+// Schema returns the table schema
+func (t *authorizedTable) Schema() TableSchema {
+	return t.table.Schema()
+}
+
+// Insert requires INSERT on the table
+func (t *authorizedTable) Insert(ctx context.Context, row Row) (int64, error) {
+	if err := t.engine.require(ObjectTypeTable, t.table.Name(), PrivilegeInsert); err != nil {
+		return 0, err
+	}
+	return t.table.Insert(ctx, row)
+}
+
+// Update requires UPDATE on the table
+func (t *authorizedTable) Update(ctx context.Context, row Row, condition Condition) (int64, error) {
+	if err := t.engine.require(ObjectTypeTable, t.table.Name(), PrivilegeUpdate); err != nil {
+		return 0, err
+	}
+	return t.table.Update(ctx, row, condition)
+}
+
+// Delete requires DELETE on the table
+func (t *authorizedTable) Delete(ctx context.Context, condition Condition) (int64, error) {
+	if err := t.engine.require(ObjectTypeTable, t.table.Name(), PrivilegeDelete); err != nil {
+		return 0, err
+	}
+	return t.table.Delete(ctx, condition)
+}
+
+// Select requires SELECT on the table
+func (t *authorizedTable) Select(ctx context.Context, columns []string, condition Condition) (Iterator, error) {
+	if err := t.engine.require(ObjectTypeTable, t.table.Name(), PrivilegeSelect); err != nil {
+		return nil, err
+	}
+	return t.table.Select(ctx, columns, condition)
+}
+
+// SelectWithOptions requires SELECT on the table
+func (t *authorizedTable) SelectWithOptions(ctx context.Context, condition Condition, opts SelectOptions) (Iterator, error) {
+	if err := t.engine.require(ObjectTypeTable, t.table.Name(), PrivilegeSelect); err != nil {
+		return nil, err
+	}
+	return t.table.SelectWithOptions(ctx, condition, opts)
+}
+
+// Count requires SELECT on the table
+func (t *authorizedTable) Count(ctx context.Context, condition Condition) (int64, error) {
+	if err := t.engine.require(ObjectTypeTable, t.table.Name(), PrivilegeSelect); err != nil {
+		return 0, err
+	}
+	return t.table.Count(ctx, condition)
+}
+
+// authorizedTensor checks tensor privileges before delegating
+type authorizedTensor struct {
+	tensor Tensor
+	engine *authorizedEngine
+}
+
+// Name returns the tensor name
+func (t *authorizedTensor) Name() string {
+	return t.tensor.Name()
+}
+
+// Schema returns the tensor schema
+func (t *authorizedTensor) Schema() TensorSchema {
+	return t.tensor.Schema()
+}
+
+// Shape returns the tensor shape
+func (t *authorizedTensor) Shape() []int {
+	return t.tensor.Shape()
+}
+
+// DType returns the tensor data type
+func (t *authorizedTensor) DType() string {
+	return t.tensor.DType()
+}
+
+// StoreChunk requires WRITE on the tensor
+func (t *authorizedTensor) StoreChunk(ctx context.Context, indices []int, data []byte) error {
+	if err := t.engine.require(ObjectTypeTensor, t.tensor.Name(), PrivilegeWrite); err != nil {
+		return err
+	}
+	return t.tensor.StoreChunk(ctx, indices, data)
+}
+
+// GetChunk requires READ on the tensor
+func (t *authorizedTensor) GetChunk(ctx context.Context, indices []int) ([]byte, error) {
+	if err := t.engine.require(ObjectTypeTensor, t.tensor.Name(), PrivilegeRead); err != nil {
+		return nil, err
+	}
+	return t.tensor.GetChunk(ctx, indices)
+}
+
+// Slice requires READ on the tensor
+func (t *authorizedTensor) Slice(ctx context.Context, ranges []Range) (Tensor, error) {
+	if err := t.engine.require(ObjectTypeTensor, t.tensor.Name(), PrivilegeRead); err != nil {
+		return nil, err
+	}
+	return t.tensor.Slice(ctx, ranges)
+}
+
+// Reshape requires DDL on the tensor
+func (t *authorizedTensor) Reshape(ctx context.Context, newShape []int) error {
+	if err := t.engine.require(ObjectTypeTensor, t.tensor.Name(), PrivilegeDDL); err != nil {
+		return err
+	}
+	return t.tensor.Reshape(ctx, newShape)
+}
+
+// ApplyOperation requires READ on the tensor and on any tensor operand
+func (t *authorizedTensor) ApplyOperation(ctx context.Context, op Operation) (Tensor, error) {
+	if err := t.engine.require(ObjectTypeTensor, t.tensor.Name(), PrivilegeRead); err != nil {
+		return nil, err
+	}
+
+	// Operations work on the concrete tensor types, so unwrap operands
+	if operand, ok := op.Operand.(*authorizedTensor); ok {
+		if err := t.engine.require(ObjectTypeTensor, operand.tensor.Name(), PrivilegeRead); err != nil {
+			return nil, err
+		}
+		op.Operand = operand.tensor
+	}
+
+	return t.tensor.ApplyOperation(ctx, op)
+}
+
+// Metadata returns the tensor metadata
+func (t *authorizedTensor) Metadata() map[string]interface{} {
+	return t.tensor.Metadata()
+}
+
+// SetMetadata requires DDL on the tensor
+func (t *authorizedTensor) SetMetadata(key string, value interface{}) error {
+	if err := t.engine.require(ObjectTypeTensor, t.tensor.Name(), PrivilegeDDL); err != nil {
+		return err
+	}
+	return t.tensor.SetMetadata(key, value)
+}
+
+// UpdateMetadata requires DDL on the tensor
+func (t *authorizedTensor) UpdateMetadata(values map[string]interface{}) error {
+	if err := t.engine.require(ObjectTypeTensor, t.tensor.Name(), PrivilegeDDL); err != nil {
+		return err
+	}
+	return t.tensor.UpdateMetadata(values)
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAuthorize(t *testing.T) {
+	engine := newTestEngine(t)
+	ctx := context.Background()
+
+	if err := engine.CreateTable("events", TableSchema{
+		Columns: []ColumnDefinition{{Name: "name", Type: "TEXT"}},
+	}); err != nil {
+		t.Fatalf("CreateTable() error = %v", err)
+	}
+	if err := engine.CreateTensor("weights", TensorSchema{
+		Shape:     []int{4},
+		DType:     "float32",
+		ChunkSize: []int{2},
+	}); err != nil {
+		t.Fatalf("CreateTensor() error = %v", err)
+	}
+	if err := engine.CreateUser("alice", "pw", false); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	alice, err := Authorize(engine, &User{Name: "alice"})
+	if err != nil {
+		t.Fatalf("Authorize() error = %v", err)
+	}
+
+	// Without grants nothing is visible or accessible
+	if _, err := alice.GetTable("events"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("GetTable() without grants error = %v, want ErrPermissionDenied", err)
+	}
+	if _, err := alice.GetTensor("weights"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("GetTensor() without grants error = %v, want ErrPermissionDenied", err)
+	}
+	if tables, _ := alice.ListTables(); len(tables) != 0 {
+		t.Errorf("ListTables() without grants = %v, want none", tables)
+	}
+	if _, err := alice.ExecuteQuery(ctx, "SELECT 1"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("ExecuteQuery() error = %v, want ErrPermissionDenied", err)
+	}
+
+	if err := engine.Grant("alice", ObjectTypeTable, "events", []string{"select"}); err != nil {
+		t.Fatalf("Grant() error = %v", err)
+	}
+	if err := engine.Grant("alice", ObjectTypeTensor, "weights", []string{PrivilegeRead}); err != nil {
+		t.Fatalf("Grant() error = %v", err)
+	}
+	if err := engine.Grant("alice", ObjectTypeTensor, "weights", []string{PrivilegeSelect}); err == nil {
+		t.Error("Grant() should reject table privileges on tensors")
+	}
+
+	table, err := alice.GetTable("events")
+	if err != nil {
+		t.Fatalf("GetTable() error = %v", err)
+	}
+	if _, err := table.Count(ctx, nil); err != nil {
+		t.Errorf("Count() with SELECT error = %v", err)
+	}
+	var permErr *PermissionError
+	if _, err := table.Insert(ctx, Row{"name": "login"}); !errors.As(err, &permErr) || permErr.Privilege != PrivilegeInsert {
+		t.Errorf("Insert() without INSERT error = %v, want PermissionError for INSERT", err)
+	}
+
+	tensor, err := alice.GetTensor("weights")
+	if err != nil {
+		t.Fatalf("GetTensor() error = %v", err)
+	}
+	chunk := float32SliceToBytes([]float32{1, 2})
+	if err := tensor.StoreChunk(ctx, []int{0}, chunk); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("StoreChunk() without WRITE error = %v, want ErrPermissionDenied", err)
+	}
+	if _, err := tensor.GetChunk(ctx, []int{0}); err != nil {
+		t.Errorf("GetChunk() with READ error = %v", err)
+	}
+
+	// Grants are checked on every operation, not only when the object is opened
+	if err := engine.Grant("alice", ObjectTypeTable, "events", []string{"ALL"}); err != nil {
+		t.Fatalf("Grant() error = %v", err)
+	}
+	if _, err := table.Insert(ctx, Row{"name": "login"}); err != nil {
+		t.Errorf("Insert() after GRANT ALL error = %v", err)
+	}
+	if err := engine.Revoke("alice", ObjectTypeTable, "events", []string{PrivilegeInsert}); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if _, err := table.Insert(ctx, Row{"name": "logout"}); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Insert() after REVOKE error = %v, want ErrPermissionDenied", err)
+	}
+
+	grants, err := engine.ListGrants("alice")
+	if err != nil {
+		t.Fatalf("ListGrants() error = %v", err)
+	}
+	if len(grants) != 5 {
+		t.Errorf("ListGrants() returned %d grants, want 5", len(grants))
+	}
+
+	// Dropping an object removes its grants
+	if err := engine.DropTable("events"); err != nil {
+		t.Fatalf("DropTable() error = %v", err)
+	}
+	if grants, _ := engine.ListGrants("alice"); len(grants) != 1 {
+		t.Errorf("ListGrants() after DropTable() = %v, want only the tensor grant", grants)
+	}
+
+	// Superusers get the engine unchanged
+	if admin, _ := Authorize(engine, &User{Name: "admin", Superuser: true}); admin != Engine(engine) {
+		t.Error("Authorize() should not wrap superusers")
+	}
+}
@@ -0,0 +1,248 @@
+package storage
+
+import (
+	"container/list"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/telumdb/telumdb/internal/config"
+	"go.uber.org/zap"
+)
+
+// The chunk cache
+//
+// Chunks of a hybrid engine's tensors are read from their files on first
+// access and kept in a cache shared by all tensors of the engine. When the
+// cache grows past its limit, the least recently used chunks are evicted.
+// Chunks stored since their tensor was last saved are dirty: they are written
+// to their files when evicted, when the tensor is saved and at shutdown, so
+// the cache also bounds the memory held by chunks waiting for a checkpoint.
+//
+// The cache's lock is taken after any tensor lock and no other lock is
+// taken while holding it, so evicting a chunk of one tensor while another
+// is being read can't deadlock.
+
+// CacheStats reports the activity of an engine's chunk cache
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Chunks    int
+	Bytes     int64
+	Limit     int64
+}
+
+// cacheKey identifies a chunk of a tensor
+type cacheKey struct {
+	tensor *tensorImpl
+	chunk  string
+}
+
+// cacheEntry is a cached chunk and the file it belongs to
+type cacheEntry struct {
+	key    cacheKey
+	path   string
+	values []float32
+	dirty  bool
+}
+
+// chunkCache is an LRU cache of tensor chunks bounded in bytes
+type chunkCache struct {
+	limit   int64
+	durable bool
+	logger  *zap.Logger
+
+	mu       sync.Mutex
+	lru      *list.List // most recently used first
+	entries  map[cacheKey]*list.Element
+	byTensor map[*tensorImpl]map[string]*list.Element
+	stats    CacheStats
+}
+
+// cacheLimit returns the size of the chunk cache, the smaller of
+// StorageConfig.CacheSize and TensorConfig.MemoryLimit. Zero means
+// unbounded.
+func cacheLimit(cfg config.StorageConfig) int64 {
+	limit := cfg.CacheSize
+	if memoryLimit := cfg.TensorConfig.MemoryLimit; memoryLimit > 0 && (limit <= 0 || memoryLimit < limit) {
+		limit = memoryLimit
+	}
+	return max(limit, 0)
+}
+
+// newChunkCache creates a cache holding up to limit bytes of chunks, whose
+// dirty chunks are synced to disk when written if durable
+func newChunkCache(limit int64, durable bool, logger *zap.Logger) *chunkCache {
+	return &chunkCache{
+		limit:    limit,
+		durable:  durable,
+		logger:   logger,
+		lru:      list.New(),
+		entries:  make(map[cacheKey]*list.Element),
+		byTensor: make(map[*tensorImpl]map[string]*list.Element),
+		stats:    CacheStats{Limit: limit},
+	}
+}
+
+// CacheStats returns the statistics of the engine's chunk cache
+func (e *engineImpl) CacheStats() CacheStats {
+	if e.cache == nil {
+		return CacheStats{}
+	}
+	e.cache.mu.Lock()
+	defer e.cache.mu.Unlock()
+	return e.cache.stats
+}
+
+// get returns a cached chunk, which must not be modified
+func (c *chunkCache) get(t *tensorImpl, chunk string) ([]float32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[cacheKey{t, chunk}]
+	if !ok {
+		c.stats.Misses++
+		chunkCacheMisses.Inc()
+		return nil, false
+	}
+	c.stats.Hits++
+	chunkCacheHits.Inc()
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*cacheEntry).values, true
+}
+
+// put caches a chunk of t stored in the file at path. A dirty chunk replaces
+// any cached one; a chunk read from its file never replaces a cached one,
+// which is at least as recent. Chunks are evicted to stay within the limit.
+func (c *chunkCache) put(t *tensorImpl, chunk, path string, values []float32, dirty bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := cacheKey{t, chunk}
+	if elem, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(elem)
+		if !dirty {
+			return
+		}
+		entry := elem.Value.(*cacheEntry)
+		c.resize(int64(len(values) - len(entry.values)))
+		entry.path, entry.values, entry.dirty = path, values, true
+	} else {
+		// A clean chunk larger than the whole cache is read but not kept
+		if !dirty && c.limit > 0 && int64(4*len(values)) > c.limit {
+			return
+		}
+		elem := c.lru.PushFront(&cacheEntry{key: key, path: path, values: values, dirty: dirty})
+		c.entries[key] = elem
+		if c.byTensor[t] == nil {
+			c.byTensor[t] = make(map[string]*list.Element)
+		}
+		c.byTensor[t][chunk] = elem
+		c.stats.Chunks++
+		c.resize(int64(len(values)))
+	}
+
+	c.evict()
+}
+
+// evict removes least recently used chunks until the cache is within its
+// limit, writing dirty ones to their files first. A chunk that can't be
+// written stays cached and is retried on the next eviction. The caller
+// holds c.mu.
+func (c *chunkCache) evict() {
+	if c.limit <= 0 {
+		return
+	}
+	for elem := c.lru.Back(); elem != nil && c.stats.Bytes > c.limit; {
+		entry := elem.Value.(*cacheEntry)
+		prev := elem.Prev()
+
+		// The file is written before the entry is removed, so a reader that
+		// misses the cache finds the chunk in its file
+		if entry.dirty {
+			if err := c.writeBack(entry); err != nil {
+				c.logger.Error("Failed to write back evicted chunk", zap.String("path", entry.path), zap.Error(err))
+				elem = prev
+				continue
+			}
+		}
+		c.remove(elem)
+		c.stats.Evictions++
+		chunkCacheEvictions.Inc()
+		elem = prev
+	}
+}
+
+// flush writes the dirty chunks of t to their files
+func (c *chunkCache) flush(t *tensorImpl) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, elem := range c.byTensor[t] {
+		entry := elem.Value.(*cacheEntry)
+		if !entry.dirty {
+			continue
+		}
+		if err := c.writeBack(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drop discards the cached chunks of t without writing them, for tensors
+// that were dropped or whose chunks moved to a new layout
+func (c *chunkCache) drop(t *tensorImpl) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, elem := range c.byTensor[t] {
+		c.remove(elem)
+	}
+}
+
+// clear discards all cached chunks. Dirty chunks are lost, so the engine
+// saves its tensors first.
+func (c *chunkCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for elem := c.lru.Front(); elem != nil; elem = c.lru.Front() {
+		c.remove(elem)
+	}
+}
+
+// writeBack writes a dirty chunk to its file and marks it clean. The caller
+// holds c.mu.
+func (c *chunkCache) writeBack(entry *cacheEntry) error {
+	if err := os.MkdirAll(filepath.Dir(entry.path), 0755); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(entry.path, float32SliceToBytes(entry.values), 0644, c.durable); err != nil {
+		return err
+	}
+	entry.dirty = false
+	return nil
+}
+
+// remove deletes an entry from the cache. The caller holds c.mu.
+func (c *chunkCache) remove(elem *list.Element) {
+	entry := elem.Value.(*cacheEntry)
+	c.lru.Remove(elem)
+	delete(c.entries, entry.key)
+	if chunks := c.byTensor[entry.key.tensor]; chunks != nil {
+		delete(chunks, entry.key.chunk)
+		if len(chunks) == 0 {
+			delete(c.byTensor, entry.key.tensor)
+		}
+	}
+	c.stats.Chunks--
+	c.resize(-int64(len(entry.values)))
+}
+
+// resize adjusts the cache size by delta values. The caller holds c.mu.
+func (c *chunkCache) resize(delta int64) {
+	c.stats.Bytes += 4 * delta
+	tensorMemoryBytes.Add(float64(4 * delta))
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/telumdb/telumdb/internal/config"
+)
+
+func TestCacheLimit(t *testing.T) {
+	tests := []struct {
+		cacheSize   int64
+		memoryLimit int64
+		want        int64
+	}{
+		{0, 0, 0},
+		{1024, 0, 1024},
+		{0, 512, 512},
+		{1024, 512, 512},
+		{512, 1024, 512},
+		{-1, 0, 0},
+	}
+
+	for _, tt := range tests {
+		cfg := config.StorageConfig{CacheSize: tt.cacheSize, TensorConfig: config.TensorConfig{MemoryLimit: tt.memoryLimit}}
+		if got := cacheLimit(cfg); got != tt.want {
+			t.Errorf("cacheLimit(%d, %d) = %d, want %d", tt.cacheSize, tt.memoryLimit, got, tt.want)
+		}
+	}
+}
+
+func TestChunkCache(t *testing.T) {
+	ctx := context.Background()
+	dataDir := t.TempDir()
+
+	// Room for two chunks of two values, with chunks logged and left dirty
+	// until they are evicted
+	cfg := config.StorageConfig{
+		Engine:     "hybrid",
+		DataDir:    dataDir,
+		WALEnabled: true,
+		CacheSize:  16,
+	}
+	engine, _ := NewHybridEngine(cfg)
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	if err := engine.CreateTensor("embeddings", TensorSchema{Shape: []int{8}, DType: "float32", ChunkSize: []int{2}}); err != nil {
+		t.Fatalf("CreateTensor() error = %v", err)
+	}
+	tensor, _ := engine.GetTensor("embeddings")
+	layout := filepath.Join(dataDir, "tensor_embeddings", "layout_8_2")
+
+	for i, values := range [][]float32{{1, 2}, {3, 4}, {5, 6}} {
+		if err := tensor.StoreChunk(ctx, []int{i}, float32SliceToBytes(values)); err != nil {
+			t.Fatalf("StoreChunk(%d) error = %v", i, err)
+		}
+	}
+
+	// The least recently stored chunk was written back to make room
+	stats := engine.CacheStats()
+	if stats.Evictions != 1 || stats.Chunks != 2 || stats.Bytes != 16 {
+		t.Errorf("CacheStats() = %+v, want 1 eviction and 2 chunks in 16 bytes", stats)
+	}
+	if data, err := os.ReadFile(filepath.Join(layout, "c0.bin")); err != nil || !reflect.DeepEqual(bytesToFloat32Slice(data), []float32{1, 2}) {
+		t.Errorf("evicted chunk file = %v, %v, want [1 2]", bytesToFloat32Slice(data), err)
+	}
+	if _, err := os.Stat(filepath.Join(layout, "c2.bin")); !os.IsNotExist(err) {
+		t.Errorf("cached chunk written before eviction: %v", err)
+	}
+
+	// Reading the evicted chunk misses, then hits
+	for i := 0; i < 2; i++ {
+		if got := readChunk(t, tensor, []int{0}); !reflect.DeepEqual(got, []float32{1, 2}) {
+			t.Errorf("GetChunk(0) = %v, want [1 2]", got)
+		}
+	}
+	if got := engine.CacheStats(); got.Misses-stats.Misses != 1 || got.Hits-stats.Hits != 1 || got.Evictions != 2 {
+		t.Errorf("CacheStats() after reads = %+v, want 1 more miss, 1 more hit and 2 evictions", got)
+	}
+
+	// Dirty chunks still cached are written at shutdown
+	if err := engine.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(layout, "c2.bin")); err != nil || !reflect.DeepEqual(bytesToFloat32Slice(data), []float32{5, 6}) {
+		t.Errorf("chunk file after shutdown = %v, %v, want [5 6]", bytesToFloat32Slice(data), err)
+	}
+
+	// Restarting reads no chunks until they are accessed
+	engine, _ = NewHybridEngine(cfg)
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer engine.Shutdown(ctx)
+
+	if stats := engine.CacheStats(); stats.Chunks != 0 || stats.Misses != 0 {
+		t.Errorf("CacheStats() after restart = %+v, want an empty cache", stats)
+	}
+	tensor, _ = engine.GetTensor("embeddings")
+	for i, want := range [][]float32{{1, 2}, {3, 4}, {5, 6}, {0, 0}} {
+		if got := readChunk(t, tensor, []int{i}); !reflect.DeepEqual(got, want) {
+			t.Errorf("GetChunk(%d) after restart = %v, want %v", i, got, want)
+		}
+	}
+	if stats := engine.CacheStats(); stats.Misses != 4 || stats.Bytes > stats.Limit {
+		t.Errorf("CacheStats() after reads = %+v, want 4 misses within the limit", stats)
+	}
+}
@@ -0,0 +1,324 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Tensors of a hybrid engine are stored one file per chunk, laid out as
+//
+//	<data dir>/tensor_<name>/<layout>/<chunk>.bin
+//
+// where the layout names the tensor and chunk shapes, and the chunk file
+// holds the chunk's elements in row-major order as little-endian float32.
+// Chunks that were never written have no file and read as zeros. Reshaping
+// writes the chunks of the new layout next to the old ones, so the catalog
+// always names a complete layout.
+
+// chunkRegion is the block of a tensor covered by one chunk. Chunks at the
+// end of a dimension that isn't a multiple of the chunk size are smaller.
+type chunkRegion struct {
+	key    string
+	origin []int
+	extent []int
+	size   int
+}
+
+// chunkShape returns the extent of a full chunk in each dimension.
+// Dimensions without a chunk size are not split.
+func chunkShape(schema TensorSchema) []int {
+	shape := append([]int(nil), schema.Shape...)
+	for i := range shape {
+		if i < len(schema.ChunkSize) && schema.ChunkSize[i] > 0 {
+			shape[i] = schema.ChunkSize[i]
+		}
+	}
+	return shape
+}
+
+// validateTensorSchema checks the shape and chunk size of a tensor
+func validateTensorSchema(schema TensorSchema) error {
+	if err := validateShape(schema.Shape); err != nil {
+		return err
+	}
+	if len(schema.ChunkSize) > len(schema.Shape) {
+		return fmt.Errorf("chunk size %v has more dimensions than shape %v", schema.ChunkSize, schema.Shape)
+	}
+	for i, size := range schema.ChunkSize {
+		if size <= 0 {
+			return fmt.Errorf("invalid chunk size %d at dimension %d", size, i)
+		}
+	}
+	return nil
+}
+
+// validateShape checks that every dimension of a shape is positive
+func validateShape(shape []int) error {
+	for i, dim := range shape {
+		if dim <= 0 {
+			return fmt.Errorf("invalid size %d at dimension %d", dim, i)
+		}
+	}
+	return nil
+}
+
+// layoutName names the directory holding the chunks of a tensor with the
+// given schema, such as "layout_1000x768_100x768"
+func layoutName(schema TensorSchema) string {
+	return "layout_" + joinInts(schema.Shape, "x") + "_" + joinInts(chunkShape(schema), "x")
+}
+
+// chunkKey names the file of a chunk, such as "c3_0"
+func chunkKey(indices []int) string {
+	return "c" + joinInts(indices, "_")
+}
+
+// joinInts formats ints separated by sep
+func joinInts(values []int, sep string) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, sep)
+}
+
+// chunkRegion validates chunk indices and returns the region of the chunk.
+// The caller holds t.mu.
+func (t *tensorImpl) chunkRegion(indices []int) (chunkRegion, error) {
+	shape := t.schema.Shape
+	if len(indices) != len(shape) {
+		return chunkRegion{}, fmt.Errorf("indices length %d doesn't match tensor dimensions %d", len(indices), len(shape))
+	}
+
+	chunk := chunkShape(t.schema)
+	r := chunkRegion{
+		key:    chunkKey(indices),
+		origin: make([]int, len(shape)),
+		extent: make([]int, len(shape)),
+		size:   1,
+	}
+	for i, idx := range indices {
+		if idx < 0 {
+			return chunkRegion{}, fmt.Errorf("negative index %d at dimension %d", idx, i)
+		}
+		if idx*chunk[i] >= shape[i] {
+			return chunkRegion{}, fmt.Errorf("chunk index %d exceeds dimension %d size", idx, i)
+		}
+		r.origin[i] = idx * chunk[i]
+		r.extent[i] = min(chunk[i], shape[i]-r.origin[i])
+		r.size *= r.extent[i]
+	}
+	return r, nil
+}
+
+// readChunk returns the values of a chunk, which must not be modified.
+// Chunks of chunked tensors come from the engine's chunk cache, which reads
+// them from their files on a miss. The caller holds t.mu.
+func (t *tensorImpl) readChunk(r chunkRegion) ([]float32, error) {
+	if !t.chunked {
+		values := make([]float32, r.size)
+		copyBlock(values, r.extent, make([]int, len(r.extent)), t.data, t.schema.Shape, r.origin, r.extent)
+		return values, nil
+	}
+
+	cache := t.engine.(*engineImpl).cache
+	if values, ok := cache.get(t, r.key); ok {
+		return values, nil
+	}
+	path := t.chunkPath(t.schema, r.key)
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read chunk %s: %w", r.key, err)
+	}
+	values := make([]float32, r.size)
+	if err == nil {
+		if values = bytesToFloat32Slice(data); len(values) != r.size {
+			return nil, fmt.Errorf("chunk file %s has %d bytes, want %d", r.key, len(data), r.size*4)
+		}
+	}
+	cache.put(t, r.key, path, values, false)
+	return values, nil
+}
+
+// writeChunk stores the values of a chunk. Chunked tensors keep them in the
+// chunk cache until the next save or until they are evicted. The caller
+// holds t.mu for writing.
+func (t *tensorImpl) writeChunk(r chunkRegion, values []float32) {
+	if !t.chunked {
+		copyBlock(t.data, t.schema.Shape, r.origin, values, r.extent, make([]int, len(r.extent)), r.extent)
+		return
+	}
+	t.engine.(*engineImpl).cache.put(t, r.key, t.chunkPath(t.schema, r.key), values, true)
+}
+
+// readRegion returns the values of a block of the tensor in row-major order,
+// reading only the chunks that overlap it. The caller holds t.mu.
+func (t *tensorImpl) readRegion(origin, extent []int) ([]float32, error) {
+	size := 1
+	for _, n := range extent {
+		size *= n
+	}
+	values := make([]float32, size)
+	if !t.chunked {
+		copyBlock(values, extent, make([]int, len(extent)), t.data, t.schema.Shape, origin, extent)
+		return values, nil
+	}
+
+	chunk := chunkShape(t.schema)
+	first := make([]int, len(origin))
+	last := make([]int, len(origin))
+	for i := range origin {
+		first[i] = origin[i] / chunk[i]
+		last[i] = (origin[i] + extent[i] - 1) / chunk[i]
+	}
+
+	err := forEachIndex(first, last, func(indices []int) error {
+		r, err := t.chunkRegion(indices)
+		if err != nil {
+			return err
+		}
+		chunkValues, err := t.readChunk(r)
+		if err != nil {
+			return err
+		}
+
+		// Copy the part of the chunk inside the block
+		from := make([]int, len(origin))
+		to := make([]int, len(origin))
+		overlap := make([]int, len(origin))
+		for i := range origin {
+			start := max(origin[i], r.origin[i])
+			end := min(origin[i]+extent[i], r.origin[i]+r.extent[i])
+			from[i], to[i], overlap[i] = start-r.origin[i], start-origin[i], end-start
+		}
+		copyBlock(values, extent, to, chunkValues, r.extent, from, overlap)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// forEachIndex calls fn with every index between first and last inclusive,
+// in row-major order. fn must not keep the slice.
+func forEachIndex(first, last []int, fn func([]int) error) error {
+	index := append([]int(nil), first...)
+	for {
+		if err := fn(index); err != nil {
+			return err
+		}
+		i := len(index) - 1
+		for ; i >= 0; i-- {
+			if index[i] < last[i] {
+				index[i]++
+				break
+			}
+			index[i] = first[i]
+		}
+		if i < 0 {
+			return nil
+		}
+	}
+}
+
+// copyBlock copies a block of the given extent from src, a row-major array
+// of shape srcShape, starting at srcAt, into dst, of shape dstShape,
+// starting at dstAt
+func copyBlock(dst []float32, dstShape, dstAt []int, src []float32, srcShape, srcAt []int, extent []int) {
+	n := len(extent)
+	if n == 0 {
+		dst[0] = src[0]
+		return
+	}
+	for _, e := range extent {
+		if e == 0 {
+			return
+		}
+	}
+
+	// Rows along the last dimension are contiguous in both arrays
+	first := make([]int, n-1)
+	last := make([]int, n-1)
+	for i := range last {
+		last[i] = extent[i] - 1
+	}
+	forEachIndex(first, last, func(row []int) error {
+		dstOff, srcOff := 0, 0
+		for i := 0; i < n; i++ {
+			d, s := dstAt[i], srcAt[i]
+			if i < n-1 {
+				d += row[i]
+				s += row[i]
+			}
+			dstOff = dstOff*dstShape[i] + d
+			srcOff = srcOff*srcShape[i] + s
+		}
+		copy(dst[dstOff:dstOff+extent[n-1]], src[srcOff:srcOff+extent[n-1]])
+		return nil
+	})
+}
+
+// tensorDir returns the directory holding a tensor's chunk files
+func (t *tensorImpl) tensorDir() string {
+	return filepath.Join(t.engine.(*engineImpl).dataDir, "tensor_"+t.name)
+}
+
+// chunkPath returns the file of a chunk in the layout of schema
+func (t *tensorImpl) chunkPath(schema TensorSchema, key string) string {
+	return filepath.Join(t.tensorDir(), layoutName(schema), key+".bin")
+}
+
+// legacyFilePath returns the single data file written by earlier versions
+func (t *tensorImpl) legacyFilePath() string {
+	return filepath.Join(t.engine.(*engineImpl).dataDir, "tensor_"+t.name+".bin")
+}
+
+// writeLayout writes all chunks of data, the full tensor in row-major order,
+// in the layout of schema, replacing whatever the layout held. Chunks that
+// are all zeros are left out.
+func (t *tensorImpl) writeLayout(schema TensorSchema, data []float32) error {
+	dir := filepath.Join(t.tensorDir(), layoutName(schema))
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	layout := &tensorImpl{name: t.name, schema: schema, data: data}
+	chunk := chunkShape(schema)
+	last := make([]int, len(schema.Shape))
+	for i, dim := range schema.Shape {
+		last[i] = (dim - 1) / chunk[i]
+	}
+	durable := t.engine.(*engineImpl).syncFiles()
+
+	return forEachIndex(make([]int, len(last)), last, func(indices []int) error {
+		r, err := layout.chunkRegion(indices)
+		if err != nil {
+			return err
+		}
+		values, _ := layout.readChunk(r)
+		for _, v := range values {
+			if v != 0 {
+				return writeFileAtomic(filepath.Join(dir, r.key+".bin"), float32SliceToBytes(values), 0644, durable)
+			}
+		}
+		return nil
+	})
+}
+
+// removeFiles deletes all files of the tensor
+func (t *tensorImpl) removeFiles() error {
+	if !t.persistent() {
+		return nil
+	}
+	if err := os.Remove(t.legacyFilePath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.RemoveAll(t.tensorDir())
+}
@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestChunkRegions(t *testing.T) {
+	ctx := context.Background()
+
+	// A 5x3 tensor in 2x2 chunks, with smaller chunks along the edges
+	data := make([]float32, 15)
+	for i := range data {
+		data[i] = float32(i)
+	}
+	tensor := &tensorImpl{
+		name:   "grid",
+		schema: TensorSchema{Shape: []int{5, 3}, DType: "float32", ChunkSize: []int{2, 2}},
+		data:   data,
+	}
+
+	tests := []struct {
+		indices []int
+		want    []float32
+	}{
+		{[]int{0, 0}, []float32{0, 1, 3, 4}},
+		{[]int{1, 0}, []float32{6, 7, 9, 10}},
+		{[]int{0, 1}, []float32{2, 5}},
+		{[]int{2, 0}, []float32{12, 13}},
+		{[]int{2, 1}, []float32{14}},
+	}
+	for _, tt := range tests {
+		if got := readChunk(t, tensor, tt.indices); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GetChunk(%v) = %v, want %v", tt.indices, got, tt.want)
+		}
+	}
+
+	if err := tensor.StoreChunk(ctx, []int{1, 1}, float32SliceToBytes([]float32{-1, -2})); err != nil {
+		t.Fatalf("StoreChunk() error = %v", err)
+	}
+	if got := tensor.data[8]; got != -1 {
+		t.Errorf("data[8] after StoreChunk() = %v, want -1", got)
+	}
+	if got := tensor.data[11]; got != -2 {
+		t.Errorf("data[11] after StoreChunk() = %v, want -2", got)
+	}
+
+	for _, indices := range [][]int{{3, 0}, {0, 2}, {-1, 0}, {0}} {
+		if _, err := tensor.GetChunk(ctx, indices); err == nil {
+			t.Errorf("GetChunk(%v) should fail", indices)
+		}
+	}
+	if err := tensor.StoreChunk(ctx, []int{2, 1}, float32SliceToBytes([]float32{1, 2})); err == nil {
+		t.Error("StoreChunk() of a full-size chunk at the edge should fail")
+	}
+}
+
+func TestChunkFiles(t *testing.T) {
+	ctx := context.Background()
+	dataDir := t.TempDir()
+	engine := newHybridEngine(t, dataDir)
+
+	schema := TensorSchema{Shape: []int{4, 4}, DType: "float32", ChunkSize: []int{2, 2}}
+	if err := engine.CreateTensor("weights", schema); err != nil {
+		t.Fatalf("CreateTensor() error = %v", err)
+	}
+	if err := engine.CreateTensor("bad", TensorSchema{Shape: []int{4}, ChunkSize: []int{0}}); err == nil {
+		t.Error("CreateTensor() with a zero chunk size should fail")
+	}
+	tensor, _ := engine.GetTensor("weights")
+	layout := filepath.Join(dataDir, "tensor_weights", "layout_4x4_2x2")
+
+	// Only the written chunk gets a file
+	if err := tensor.StoreChunk(ctx, []int{1, 0}, float32SliceToBytes([]float32{1, 2, 3, 4})); err != nil {
+		t.Fatalf("StoreChunk() error = %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(layout, "*"))
+	if len(files) != 1 || filepath.Base(files[0]) != "c1_0.bin" {
+		t.Errorf("chunk files = %v, want [c1_0.bin]", files)
+	}
+	chunkInfo, err := os.Stat(files[0])
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+
+	// Slices read across chunk boundaries
+	slice, err := tensor.Slice(ctx, []Range{{Start: 1, End: 3}, {Start: 0, End: 2}})
+	if err != nil {
+		t.Fatalf("Slice() error = %v", err)
+	}
+	if got := slice.(*tensorImpl).data; !reflect.DeepEqual(got, []float32{0, 0, 1, 2}) {
+		t.Errorf("Slice() = %v, want [0 0 1 2]", got)
+	}
+	sum, err := tensor.ApplyOperation(ctx, Operation{Type: "sum"})
+	if err != nil {
+		t.Fatalf("ApplyOperation() error = %v", err)
+	}
+	if got := sum.(*tensorImpl).data[0]; got != 10 {
+		t.Errorf("sum = %v, want 10", got)
+	}
+
+	// Metadata goes to the catalog without touching chunk files
+	if err := tensor.SetMetadata("source", "training"); err != nil {
+		t.Fatalf("SetMetadata() error = %v", err)
+	}
+	if info, _ := os.Stat(files[0]); !os.SameFile(info, chunkInfo) || !info.ModTime().Equal(chunkInfo.ModTime()) {
+		t.Error("SetMetadata() rewrote a chunk file")
+	}
+
+	if err := engine.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	engine = newHybridEngine(t, dataDir)
+	defer func() { engine.Shutdown(ctx) }()
+
+	tensor, _ = engine.GetTensor("weights")
+	if got := tensor.Metadata()["source"]; got != "training" {
+		t.Errorf("metadata after restart = %v, want training", got)
+	}
+	if got := tensor.(*tensorImpl).data; got != nil {
+		t.Errorf("tensor data loaded on start: %d values", len(got))
+	}
+	if got := readChunk(t, tensor, []int{1, 0}); !reflect.DeepEqual(got, []float32{1, 2, 3, 4}) {
+		t.Errorf("GetChunk() after restart = %v, want [1 2 3 4]", got)
+	}
+
+	// Reshaping moves the chunks to a layout for the new shape, keeping the
+	// elements in row-major order
+	if err := tensor.Reshape(ctx, []int{2, 8}); err != nil {
+		t.Fatalf("Reshape() error = %v", err)
+	}
+	if err := tensor.Reshape(ctx, []int{3, 5}); err == nil {
+		t.Error("Reshape() to another size should fail")
+	}
+	if _, err := os.Stat(layout); !os.IsNotExist(err) {
+		t.Errorf("old layout kept after Reshape(): %v", err)
+	}
+	if got := readChunk(t, tensor, []int{0, 0}); !reflect.DeepEqual(got, []float32{0, 0, 1, 2}) {
+		t.Errorf("GetChunk(0, 0) after Reshape() = %v, want [0 0 1 2]", got)
+	}
+	if got := readChunk(t, tensor, []int{0, 1}); !reflect.DeepEqual(got, []float32{0, 0, 0, 0}) {
+		t.Errorf("GetChunk(0, 1) after Reshape() = %v, want zeros", got)
+	}
+
+	if err := engine.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	engine = newHybridEngine(t, dataDir)
+	tensor, _ = engine.GetTensor("weights")
+	if files, _ := filepath.Glob(filepath.Join(dataDir, "tensor_weights", "*")); len(files) != 1 {
+		t.Errorf("layouts after restart = %v, want one", files)
+	}
+	if got := tensor.Shape(); !reflect.DeepEqual(got, []int{2, 8}) {
+		t.Errorf("Shape() after restart = %v, want [2 8]", got)
+	}
+	if got := readChunk(t, tensor, []int{0, 2}); !reflect.DeepEqual(got, []float32{0, 0, 3, 4}) {
+		t.Errorf("GetChunk(0, 2) after restart = %v, want [0 0 3 4]", got)
+	}
+}
+
+func TestLegacyTensorFile(t *testing.T) {
+	dataDir := t.TempDir()
+	engine := newHybridEngine(t, dataDir)
+	if err := engine.CreateTensor("embeddings", TensorSchema{Shape: []int{4}, DType: "float32", ChunkSize: []int{2}}); err != nil {
+		t.Fatalf("CreateTensor() error = %v", err)
+	}
+	if err := engine.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	// Earlier versions kept the whole tensor in one file
+	legacy := filepath.Join(dataDir, "tensor_embeddings.bin")
+	if err := os.WriteFile(legacy, float32SliceToBytes([]float32{1, 2, 3, 4}), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	engine = newHybridEngine(t, dataDir)
+	defer engine.Shutdown(context.Background())
+
+	tensor, _ := engine.GetTensor("embeddings")
+	if got := readChunk(t, tensor, []int{1}); !reflect.DeepEqual(got, []float32{3, 4}) {
+		t.Errorf("GetChunk() of a converted tensor = %v, want [3 4]", got)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Errorf("legacy file kept after conversion: %v", err)
+	}
+}
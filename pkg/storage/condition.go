@@ -1,18 +1,47 @@
 package storage
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
 )
 
-// SimpleCondition implements the Condition interface for basic filtering
+// Conditions form a predicate tree that is compiled into a SQL expression over
+// the JSON data of each row, so filtering happens inside SQLite. Column values
+// are read with json_extract and every value is a bound parameter. As in SQL,
+// a predicate can be true, false or unknown: comparisons involving a missing
+// or NULL column are unknown, and a row only matches when the whole tree is
+// true.
+
+// predicate is a Condition the engine knows how to compile
+type predicate interface {
+	Condition
+	compile(w *whereClause) error
+}
+
+// Comparison operators accepted by NewSimpleCondition
+const (
+	OperatorEqual          = "="
+	OperatorNotEqual       = "!="
+	OperatorLessThan       = "<"
+	OperatorLessOrEqual    = "<="
+	OperatorGreaterThan    = ">"
+	OperatorGreaterOrEqual = ">="
+)
+
+// SimpleCondition compares a column with a value
 type SimpleCondition struct {
 	field    string
 	operator string
 	value    interface{}
 }
 
-// NewSimpleCondition creates a new simple condition
+// NewSimpleCondition creates a comparison between a column and a value. The
+// operator is one of =, ==, !=, <>, <, <=, > and >=.
 func NewSimpleCondition(field, operator string, value interface{}) Condition {
 	return &SimpleCondition{
 		field:    field,
@@ -23,7 +52,7 @@ func NewSimpleCondition(field, operator string, value interface{}) Condition {
 
 // String returns the string representation of the condition
 func (c *SimpleCondition) String() string {
-	return fmt.Sprintf("%s %s %v", c.field, c.operator, c.value)
+	return fmt.Sprintf("%s %s %s", c.field, c.operator, formatValue(c.value))
 }
 
 // Field returns the field name
@@ -41,20 +70,462 @@ func (c *SimpleCondition) Value() interface{} {
 	return c.value
 }
 
-// MapCondition converts a map[string]interface{} to a Condition
+func (c *SimpleCondition) compile(w *whereClause) error {
+	op, err := normalizeOperator(c.operator)
+	if err != nil {
+		return err
+	}
+	if op == OperatorNotEqual {
+		op = "<>"
+	}
+
+	if err := w.column(c.field); err != nil {
+		return err
+	}
+	w.sql.WriteString(" " + op + " ")
+	w.bindColumn(c.field, c.value)
+	return nil
+}
+
+// normalizeOperator maps operator aliases to their canonical form
+func normalizeOperator(operator string) (string, error) {
+	switch operator {
+	case "=", "==":
+		return OperatorEqual, nil
+	case "!=", "<>":
+		return OperatorNotEqual, nil
+	case "<", "<=", ">", ">=":
+		return operator, nil
+	default:
+		return "", fmt.Errorf("unsupported operator %q", operator)
+	}
+}
+
+// LogicalCondition combines conditions with AND or OR
+type LogicalCondition struct {
+	operator   string
+	conditions []Condition
+}
+
+// NewAndCondition matches rows that satisfy every condition
+func NewAndCondition(conditions ...Condition) Condition {
+	return &LogicalCondition{operator: "AND", conditions: conditions}
+}
+
+// NewOrCondition matches rows that satisfy at least one condition
+func NewOrCondition(conditions ...Condition) Condition {
+	return &LogicalCondition{operator: "OR", conditions: conditions}
+}
+
+// String returns the string representation of the condition
+func (c *LogicalCondition) String() string {
+	parts := make([]string, len(c.conditions))
+	for i, condition := range c.conditions {
+		parts[i] = formatOperand(condition)
+	}
+	return strings.Join(parts, " "+c.operator+" ")
+}
+
+// Operator returns AND or OR
+func (c *LogicalCondition) Operator() string {
+	return c.operator
+}
+
+// Conditions returns the combined conditions
+func (c *LogicalCondition) Conditions() []Condition {
+	return c.conditions
+}
+
+func (c *LogicalCondition) compile(w *whereClause) error {
+	// An empty AND is true and an empty OR is false
+	if len(c.conditions) == 0 {
+		if c.operator == "OR" {
+			w.sql.WriteString("0")
+		} else {
+			w.sql.WriteString("1")
+		}
+		return nil
+	}
+
+	for i, condition := range c.conditions {
+		if i > 0 {
+			w.sql.WriteString(" " + c.operator + " ")
+		}
+		if err := w.operand(condition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NotCondition negates a condition
+type NotCondition struct {
+	condition Condition
+}
+
+// NewNotCondition matches rows for which condition is false
+func NewNotCondition(condition Condition) Condition {
+	return &NotCondition{condition: condition}
+}
+
+// String returns the string representation of the condition
+func (c *NotCondition) String() string {
+	return "NOT " + formatOperand(c.condition)
+}
+
+// Condition returns the negated condition
+func (c *NotCondition) Condition() Condition {
+	return c.condition
+}
+
+func (c *NotCondition) compile(w *whereClause) error {
+	w.sql.WriteString("NOT ")
+	return w.operand(c.condition)
+}
+
+// InCondition matches a column against a list of values
+type InCondition struct {
+	field  string
+	values []interface{}
+}
+
+// NewInCondition matches rows whose column equals one of the values
+func NewInCondition(field string, values ...interface{}) Condition {
+	return &InCondition{field: field, values: values}
+}
+
+// String returns the string representation of the condition
+func (c *InCondition) String() string {
+	parts := make([]string, len(c.values))
+	for i, value := range c.values {
+		parts[i] = formatValue(value)
+	}
+	return fmt.Sprintf("%s IN (%s)", c.field, strings.Join(parts, ", "))
+}
+
+// Field returns the field name
+func (c *InCondition) Field() string {
+	return c.field
+}
+
+// Values returns the candidate values
+func (c *InCondition) Values() []interface{} {
+	return c.values
+}
+
+func (c *InCondition) compile(w *whereClause) error {
+	if len(c.values) == 0 {
+		w.sql.WriteString("0")
+		return nil
+	}
+
+	if err := w.column(c.field); err != nil {
+		return err
+	}
+	w.sql.WriteString(" IN (")
+	for i, value := range c.values {
+		if i > 0 {
+			w.sql.WriteString(", ")
+		}
+		w.bindColumn(c.field, value)
+	}
+	w.sql.WriteString(")")
+	return nil
+}
+
+// BetweenCondition matches a column within an inclusive range
+type BetweenCondition struct {
+	field string
+	low   interface{}
+	high  interface{}
+}
+
+// NewBetweenCondition matches rows whose column lies in [low, high]
+func NewBetweenCondition(field string, low, high interface{}) Condition {
+	return &BetweenCondition{field: field, low: low, high: high}
+}
+
+// String returns the string representation of the condition
+func (c *BetweenCondition) String() string {
+	return fmt.Sprintf("%s BETWEEN %s AND %s", c.field, formatValue(c.low), formatValue(c.high))
+}
+
+// Field returns the field name
+func (c *BetweenCondition) Field() string {
+	return c.field
+}
+
+// Low returns the lower bound
+func (c *BetweenCondition) Low() interface{} {
+	return c.low
+}
+
+// High returns the upper bound
+func (c *BetweenCondition) High() interface{} {
+	return c.high
+}
+
+func (c *BetweenCondition) compile(w *whereClause) error {
+	if err := w.column(c.field); err != nil {
+		return err
+	}
+	w.sql.WriteString(" BETWEEN ")
+	w.bindColumn(c.field, c.low)
+	w.sql.WriteString(" AND ")
+	w.bindColumn(c.field, c.high)
+	return nil
+}
+
+// LikeCondition matches a column against a SQL LIKE pattern
+type LikeCondition struct {
+	field   string
+	pattern string
+}
+
+// NewLikeCondition matches rows whose column matches pattern, where % matches
+// any sequence of characters and _ a single one. A backslash makes the next
+// character literal. Matching ignores ASCII case, as SQLite does.
+func NewLikeCondition(field, pattern string) Condition {
+	return &LikeCondition{field: field, pattern: pattern}
+}
+
+// String returns the string representation of the condition
+func (c *LikeCondition) String() string {
+	return fmt.Sprintf("%s LIKE %s", c.field, formatValue(c.pattern))
+}
+
+// Field returns the field name
+func (c *LikeCondition) Field() string {
+	return c.field
+}
+
+// Pattern returns the LIKE pattern
+func (c *LikeCondition) Pattern() string {
+	return c.pattern
+}
+
+func (c *LikeCondition) compile(w *whereClause) error {
+	if err := w.column(c.field); err != nil {
+		return err
+	}
+	w.sql.WriteString(" LIKE ")
+	w.bind(c.pattern)
+	w.sql.WriteString(` ESCAPE '\'`)
+	return nil
+}
+
+// NullCondition tests whether a column is NULL or missing
+type NullCondition struct {
+	field string
+}
+
+// NewIsNullCondition matches rows where the column is NULL or missing
+func NewIsNullCondition(field string) Condition {
+	return &NullCondition{field: field}
+}
+
+// NewIsNotNullCondition matches rows where the column has a value
+func NewIsNotNullCondition(field string) Condition {
+	return NewNotCondition(NewIsNullCondition(field))
+}
+
+// String returns the string representation of the condition
+func (c *NullCondition) String() string {
+	return c.field + " IS NULL"
+}
+
+// Field returns the field name
+func (c *NullCondition) Field() string {
+	return c.field
+}
+
+func (c *NullCondition) compile(w *whereClause) error {
+	if err := w.column(c.field); err != nil {
+		return err
+	}
+	w.sql.WriteString(" IS NULL")
+	return nil
+}
+
+// MapCondition converts a map[string]interface{} to a Condition that matches
+// rows where every column equals the given value
 func MapCondition(m map[string]interface{}) Condition {
 	if len(m) == 0 {
 		return nil
 	}
 
-	var conditions []string
-	for field, value := range m {
-		conditions = append(conditions, fmt.Sprintf("%s = %v", field, value))
+	fields := make([]string, 0, len(m))
+	for field := range m {
+		fields = append(fields, field)
 	}
+	sort.Strings(fields)
 
-	return &SimpleCondition{
-		field:    "combined",
-		operator: "AND",
-		value:    strings.Join(conditions, " AND "),
+	conditions := make([]Condition, len(fields))
+	for i, field := range fields {
+		conditions[i] = NewSimpleCondition(field, OperatorEqual, m[field])
+	}
+	if len(conditions) == 1 {
+		return conditions[0]
+	}
+	return NewAndCondition(conditions...)
+}
+
+// compileCondition translates a condition into a SQL expression over the data
+// column of table_data, returning the expression and its arguments. Values
+// compared with columns the schema declares are converted to the column type
+// first. A nil condition compiles to an expression that is always true.
+func compileCondition(condition Condition, schema TableSchema) (string, []interface{}, error) {
+	if condition == nil {
+		return "1", nil, nil
+	}
+
+	w := whereClause{schema: schema}
+	if err := w.condition(condition); err != nil {
+		return "", nil, err
+	}
+	return w.sql.String(), w.args, nil
+}
+
+// whereClause accumulates a compiled SQL expression and its arguments
+type whereClause struct {
+	schema TableSchema
+	sql    strings.Builder
+	args   []interface{}
+}
+
+// condition appends a compiled condition
+func (w *whereClause) condition(condition Condition) error {
+	p, ok := condition.(predicate)
+	if !ok {
+		return fmt.Errorf("unsupported condition type %T", condition)
+	}
+	return p.compile(w)
+}
+
+// operand appends a nested condition in parentheses
+func (w *whereClause) operand(condition Condition) error {
+	w.sql.WriteString("(")
+	if err := w.condition(condition); err != nil {
+		return err
+	}
+	w.sql.WriteString(")")
+	return nil
+}
+
+// column appends an expression reading a column from the row's JSON data
+func (w *whereClause) column(field string) error {
+	expr, err := columnExpr(field)
+	if err != nil {
+		return err
+	}
+	w.sql.WriteString(expr)
+	return nil
+}
+
+// columnExpr returns the SQL expression reading a column from the row's JSON
+// data. The path is written as an escaped literal rather than bound so that
+// the expression matches the indexes built on it.
+func columnExpr(field string) (string, error) {
+	path, err := jsonPath(field)
+	if err != nil {
+		return "", err
+	}
+	return "json_extract(data, '" + strings.ReplaceAll(path, "'", "''") + "')", nil
+}
+
+// jsonPath returns the JSON path of a column in the row's JSON data
+func jsonPath(field string) (string, error) {
+	if field == "" || strings.ContainsRune(field, '"') {
+		return "", fmt.Errorf("unsupported column name %q", field)
+	}
+	if identifierPattern.MatchString(field) {
+		return "$." + field, nil
+	}
+	return `$."` + field + `"`, nil
+}
+
+// bind appends a placeholder for value
+func (w *whereClause) bind(value interface{}) {
+	w.sql.WriteString("?")
+	w.args = append(w.args, sqlValue(value))
+}
+
+// bindColumn appends a placeholder for a value compared with a column. The
+// value is converted as it would be when written to the column, so that a
+// time matches a TIMESTAMP column or an integer a REAL one. Values the column
+// could not store, such as a fractional bound on an INTEGER column, are
+// compared as given.
+func (w *whereClause) bindColumn(field string, value interface{}) {
+	if col, ok := w.schema.column(field); ok && value != nil {
+		if converted, err := coerceValue(col.Type, value); err == nil {
+			value = converted
+		}
+	}
+	w.bind(value)
+}
+
+// identifierPattern matches column names that are safe to embed in a JSON path
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// sqlValue converts a condition value to the form json_extract returns for
+// it: booleans become 0 and 1, times the text stored for TIMESTAMP columns,
+// and values without a SQL counterpart their JSON text.
+func sqlValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, string, float64, float32, int, int8, int16, int32, int64, uint8, uint16, uint32:
+		return v
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	case uint:
+		return sqlValue(uint64(v))
+	case uint64:
+		if v > math.MaxInt64 {
+			return float64(v)
+		}
+		return int64(v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case time.Time:
+		return formatTimestamp(v)
+	case *time.Time:
+		if v == nil {
+			return nil
+		}
+		return formatTimestamp(*v)
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// formatOperand renders a nested condition, parenthesizing compound ones
+func formatOperand(condition Condition) string {
+	switch condition.(type) {
+	case *LogicalCondition, *BetweenCondition:
+		return "(" + condition.String() + ")"
+	}
+	return condition.String()
+}
+
+// formatValue renders a value as a SQL literal
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	case time.Time:
+		return "'" + formatTimestamp(v) + "'"
 	}
+	return fmt.Sprint(value)
 }
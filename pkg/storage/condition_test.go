@@ -0,0 +1,238 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newConditionTable creates a table holding a single row to match against
+func newConditionTable(t *testing.T, schema TableSchema, row Row) Table {
+	t.Helper()
+
+	engine := newMemoryEngine(t)
+	if err := engine.CreateTable("conditions", schema); err != nil {
+		t.Fatalf("CreateTable() error = %v", err)
+	}
+	table, err := engine.GetTable("conditions")
+	if err != nil {
+		t.Fatalf("GetTable() error = %v", err)
+	}
+	if _, err := table.Insert(context.Background(), row); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+	return table
+}
+
+func TestConditionMatching(t *testing.T) {
+	joined := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	table := newConditionTable(t, TableSchema{}, Row{
+		"name":      "Alice",
+		"age":       float64(34),
+		"active":    true,
+		"joined":    joined.Format(time.RFC3339),
+		"email":     nil,
+		"note":      "100%",
+		"full name": "Alice Smith",
+	})
+
+	tests := []struct {
+		name      string
+		condition Condition
+		want      bool
+	}{
+		{"nil condition", nil, true},
+		{"equal", NewSimpleCondition("age", "=", 34), true},
+		{"equal alias", NewSimpleCondition("age", "==", int64(34)), true},
+		{"not equal", NewSimpleCondition("age", "<>", 34), false},
+		{"greater", NewSimpleCondition("age", ">", 30), true},
+		{"less or equal", NewSimpleCondition("age", "<=", 33.5), false},
+		{"string", NewSimpleCondition("name", "=", "Alice"), true},
+		{"boolean", NewSimpleCondition("active", "=", true), true},
+		{"numbers sort before text", NewSimpleCondition("age", "<", "10"), true},
+		{"time", NewSimpleCondition("joined", ">", joined.Add(-time.Hour)), true},
+		{"missing column", NewSimpleCondition("city", "=", "Paris"), false},
+		{"NULL never equals", NewSimpleCondition("email", "=", nil), false},
+		{"and", NewAndCondition(NewSimpleCondition("age", ">", 30), NewSimpleCondition("name", "=", "Alice")), true},
+		{"and false", NewAndCondition(NewSimpleCondition("age", ">", 30), NewSimpleCondition("name", "=", "Bob")), false},
+		{"or", NewOrCondition(NewSimpleCondition("age", ">", 40), NewSimpleCondition("name", "=", "Alice")), true},
+		{"or unknown", NewOrCondition(NewSimpleCondition("age", ">", 40), NewSimpleCondition("city", "=", "Paris")), false},
+		{"empty and", NewAndCondition(), true},
+		{"empty or", NewOrCondition(), false},
+		{"not", NewNotCondition(NewSimpleCondition("age", ">", 40)), true},
+		{"not unknown", NewNotCondition(NewSimpleCondition("city", "=", "Paris")), false},
+		{"not of unknown or true", NewNotCondition(NewOrCondition(NewSimpleCondition("city", "=", "x"), NewSimpleCondition("age", "=", 34))), false},
+		{"in", NewInCondition("name", "Bob", "Alice"), true},
+		{"not in", NewInCondition("name", "Bob", "Carol"), false},
+		{"not in with NULL", NewNotCondition(NewInCondition("name", "Bob", nil)), false},
+		{"between", NewBetweenCondition("age", 30, 40), true},
+		{"between inclusive", NewBetweenCondition("age", 34, 34), true},
+		{"outside between", NewBetweenCondition("age", 35, 40), false},
+		{"like prefix", NewLikeCondition("name", "al%"), true},
+		{"like single char", NewLikeCondition("name", "_lice"), true},
+		{"like no match", NewLikeCondition("name", "%bob%"), false},
+		{"like number", NewLikeCondition("age", "3_"), true},
+		{"like escape", NewLikeCondition("name", `Alice\%`), false},
+		{"like escaped wildcard", NewLikeCondition("note", `100\%`), true},
+		{"like escaped wildcard no match", NewLikeCondition("note", `1\%`), false},
+		{"empty in", NewInCondition("name"), false},
+		{"not empty in", NewNotCondition(NewInCondition("name")), true},
+		{"quoted column", NewSimpleCondition("full name", "=", "Alice Smith"), true},
+		{"quote in column name", NewIsNotNullCondition("x') OR 1=1 --"), false},
+		{"injection attempt", NewSimpleCondition("name", "=", "x' OR '1'='1"), false},
+		{"is null", NewIsNullCondition("email"), true},
+		{"is null missing", NewIsNullCondition("city"), true},
+		{"is not null", NewIsNotNullCondition("name"), true},
+		{"map", MapCondition(map[string]interface{}{"name": "Alice", "age": 34}), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := table.Count(context.Background(), tt.condition)
+			if err != nil {
+				t.Fatalf("Count() error = %v", err)
+			}
+			if got := count == 1; got != tt.want {
+				t.Errorf("condition %v matched = %v, want %v", tt.condition, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConditionColumnTypes(t *testing.T) {
+	seen := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	table := newConditionTable(t, TableSchema{Columns: []ColumnDefinition{
+		{Name: "id", Type: "INTEGER", PrimaryKey: true},
+		{Name: "score", Type: "REAL", Nullable: true},
+		{Name: "seen", Type: "TIMESTAMP", Nullable: true},
+		{Name: "active", Type: "BOOLEAN", Nullable: true},
+	}}, Row{"id": 7, "score": 2.5, "seen": seen, "active": true})
+
+	tests := []struct {
+		name      string
+		condition Condition
+		want      bool
+	}{
+		{"time on timestamp", NewSimpleCondition("seen", "=", seen), true},
+		{"time in another zone", NewSimpleCondition("seen", "=", seen.In(time.FixedZone("CET", 3600))), true},
+		{"date text on timestamp", NewSimpleCondition("seen", "=", "2024-03-01"), true},
+		{"timestamp range", NewBetweenCondition("seen", "2024-02-29", seen.Add(time.Hour)), true},
+		{"text on real", NewSimpleCondition("score", "=", "2.5"), true},
+		{"int on real", NewSimpleCondition("score", ">", 2), true},
+		{"text on integer", NewInCondition("id", "7", "8"), true},
+		{"text on boolean", NewSimpleCondition("active", "=", "true"), true},
+		{"fraction on integer", NewSimpleCondition("id", "<", 7.5), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := table.Count(context.Background(), tt.condition)
+			if err != nil {
+				t.Fatalf("Count() error = %v", err)
+			}
+			if got := count == 1; got != tt.want {
+				t.Errorf("condition %v matched = %v, want %v", tt.condition, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConditionErrors(t *testing.T) {
+	table := newConditionTable(t, TableSchema{}, Row{"age": 1})
+
+	tests := []struct {
+		name      string
+		condition Condition
+	}{
+		{"unknown operator", NewSimpleCondition("age", "~", 1)},
+		{"nested unknown operator", NewNotCondition(NewSimpleCondition("age", "LIKE", 1))},
+		{"foreign condition", stringCondition("age > 1")},
+		{"quote in column name", NewSimpleCondition(`a"b`, "=", 1)},
+		{"empty column name", NewIsNullCondition("")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := table.Count(context.Background(), tt.condition); err == nil {
+				t.Error("Count() should fail")
+			}
+		})
+	}
+}
+
+// stringCondition is a Condition the engine can't evaluate
+type stringCondition string
+
+func (c stringCondition) String() string {
+	return string(c)
+}
+
+func TestConditionString(t *testing.T) {
+	tests := []struct {
+		condition Condition
+		want      string
+	}{
+		{NewSimpleCondition("name", "=", "O'Brien"), "name = 'O''Brien'"},
+		{NewAndCondition(NewSimpleCondition("a", ">", 1), NewOrCondition(NewIsNullCondition("b"), NewInCondition("c", 1, "x"))), "a > 1 AND (b IS NULL OR c IN (1, 'x'))"},
+		{NewNotCondition(NewBetweenCondition("age", 18, 65)), "NOT (age BETWEEN 18 AND 65)"},
+		{NewLikeCondition("name", "a%"), "name LIKE 'a%'"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.condition.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestCompileCondition(t *testing.T) {
+	joined := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		condition Condition
+		wantSQL   string
+		wantArgs  []interface{}
+	}{
+		{nil, "1", nil},
+		{
+			NewSimpleCondition("name", "!=", "O'Brien"),
+			"json_extract(data, '$.name') <> ?",
+			[]interface{}{"O'Brien"},
+		},
+		{
+			NewAndCondition(NewSimpleCondition("active", "=", true), NewOrCondition(NewIsNullCondition("b"), NewInCondition("c", 1, "x"))),
+			"(json_extract(data, '$.active') = ?) AND ((json_extract(data, '$.b') IS NULL) OR (json_extract(data, '$.c') IN (?, ?)))",
+			[]interface{}{1, 1, "x"},
+		},
+		{
+			NewNotCondition(NewBetweenCondition("joined", joined, "2025")),
+			"NOT (json_extract(data, '$.joined') BETWEEN ? AND ?)",
+			[]interface{}{"2024-03-01T12:00:00.000000000Z", "2025"},
+		},
+		{
+			NewLikeCondition("full name", "a%"),
+			`json_extract(data, '$."full name"') LIKE ? ESCAPE '\'`,
+			[]interface{}{"a%"},
+		},
+		{
+			NewIsNullCondition("x') OR 1=1 --"),
+			`json_extract(data, '$."x'') OR 1=1 --"') IS NULL`,
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		gotSQL, gotArgs, err := compileCondition(tt.condition, TableSchema{})
+		if err != nil {
+			t.Errorf("compileCondition(%v) error = %v", tt.condition, err)
+			continue
+		}
+		if gotSQL != tt.wantSQL {
+			t.Errorf("compileCondition(%v) SQL = %q, want %q", tt.condition, gotSQL, tt.wantSQL)
+		}
+		if !reflect.DeepEqual(gotArgs, tt.wantArgs) {
+			t.Errorf("compileCondition(%v) args = %#v, want %#v", tt.condition, gotArgs, tt.wantArgs)
+		}
+	}
+}
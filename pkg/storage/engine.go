@@ -2,11 +2,32 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
 
 	"github.com/telumdb/telumdb/internal/config"
+	"go.uber.org/zap"
 )
 
+// Errors matched with errors.Is by callers that need to tell failures apart
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrAlreadyExists = errors.New("already exists")
+)
+
+// objectNamePattern restricts table and tensor names to plain identifiers,
+// which also keeps them safe to use in file names
+var objectNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,127}$`)
+
+// validateObjectName checks that a table or tensor name is a valid identifier
+func validateObjectName(name string) error {
+	if !objectNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid name: %q", name)
+	}
+	return nil
+}
+
 // Engine represents the storage engine interface
 type Engine interface {
 	Start(ctx context.Context) error
@@ -27,10 +48,11 @@ type Engine interface {
 type Table interface {
 	Name() string
 	Schema() TableSchema
-	Insert(ctx context.Context, row Row) error
-	Update(ctx context.Context, row Row, condition Condition) error
-	Delete(ctx context.Context, condition Condition) error
+	Insert(ctx context.Context, row Row) (int64, error)
+	Update(ctx context.Context, row Row, condition Condition) (int64, error)
+	Delete(ctx context.Context, condition Condition) (int64, error)
 	Select(ctx context.Context, columns []string, condition Condition) (Iterator, error)
+	SelectWithOptions(ctx context.Context, condition Condition, opts SelectOptions) (Iterator, error)
 	Count(ctx context.Context, condition Condition) (int64, error)
 }
 
@@ -47,24 +69,56 @@ type Tensor interface {
 	ApplyOperation(ctx context.Context, op Operation) (Tensor, error)
 	Metadata() map[string]interface{}
 	SetMetadata(key string, value interface{}) error
+	UpdateMetadata(values map[string]interface{}) error
 }
 
-// Transaction represents a database transaction
+// Transaction represents a database transaction. Tables and tensors retrieved
+// through it read and write within the transaction, so their changes commit
+// or roll back together with the catalog changes made through it.
 type Transaction interface {
 	Commit(ctx context.Context) error
 	Rollback(ctx context.Context) error
 	CreateTable(name string, schema TableSchema) error
 	DropTable(name string) error
+	GetTable(name string) (Table, error)
 	CreateTensor(name string, schema TensorSchema) error
 	DropTensor(name string) error
+	GetTensor(name string) (Tensor, error)
 }
 
-// Iterator represents a result iterator
+// Iterator represents a result iterator. Next returns false at the end of
+// the rows or on error; Err tells the two apart.
 type Iterator interface {
 	Next() bool
+	RowID() int64
 	Scan(dest ...interface{}) error
+	ScanStruct(dest interface{}) error
+	Err() error
 	Close() error
 	Columns() []string
+	ColumnTypes() []ColumnDefinition
+	Cursor() string
+}
+
+// SelectOptions controls which columns Select returns and in which order.
+// Rows are sorted by OrderBy and then by row ID, ascending unless the last
+// OrderBy column is descending, so the order is total. Limit
+// caps the number of rows (0 means no limit) after skipping Offset rows.
+// After resumes from the Cursor of the last row of a previous page that used
+// the same OrderBy, without the cost of skipping rows with Offset.
+type SelectOptions struct {
+	Columns []string
+	OrderBy []OrderBy
+	Limit   int
+	Offset  int
+	After   string
+}
+
+// OrderBy sorts rows by a column. NULLs sort first in ascending order and
+// last in descending order.
+type OrderBy struct {
+	Column string
+	Desc   bool
 }
 
 // Result represents a query result
@@ -89,12 +143,15 @@ type TensorSchema struct {
 	Metadata    map[string]interface{}
 }
 
-// ColumnDefinition represents a column definition
+// ColumnDefinition represents a column definition. A table may declare one
+// primary key column; when its type is an integer type its values double as
+// row IDs.
 type ColumnDefinition struct {
-	Name     string
-	Type     string
-	Nullable bool
-	Default  interface{}
+	Name       string
+	Type       string
+	Nullable   bool
+	Default    interface{}
+	PrimaryKey bool
 }
 
 // IndexDefinition represents an index definition
@@ -146,197 +203,57 @@ const (
 	OperationTypeCosineSimilarity = "cosine_similarity"
 )
 
+// Option configures a storage engine
+type Option func(*engineImpl)
+
+// WithLogger makes the engine report background failures, such as failed
+// checkpoints, to logger. Engines log nothing by default.
+func WithLogger(logger *zap.Logger) Option {
+	return func(e *engineImpl) {
+		if logger != nil {
+			e.logger = logger
+		}
+	}
+}
+
 // CreateEngine creates a new storage engine
-func CreateEngine(cfg config.StorageConfig) (Engine, error) {
+func CreateEngine(cfg config.StorageConfig, opts ...Option) (Engine, error) {
 	switch cfg.Engine {
 	case "hybrid":
-		return NewHybridEngine(cfg)
+		return NewHybridEngine(cfg, opts...)
 	case "memory":
-		return NewMemoryEngine(cfg)
+		return NewMemoryEngine(cfg, opts...)
 	default:
 		return nil, fmt.Errorf("unsupported storage engine: %s", cfg.Engine)
 	}
 }
 
-// HybridEngine implements the hybrid storage engine
+// HybridEngine keeps relational data and the catalog in SQLite and tensor
+// data in chunk files next to it in the data directory, reading chunks only
+// as they are accessed into a cache bounded by StorageConfig.CacheSize and
+// TensorConfig.MemoryLimit
 type HybridEngine struct {
-	config config.StorageConfig
-	// TODO: Add engine fields
+	*engineImpl
 }
 
 // NewHybridEngine creates a new hybrid storage engine
-func NewHybridEngine(cfg config.StorageConfig) (*HybridEngine, error) {
+func NewHybridEngine(cfg config.StorageConfig, opts ...Option) (*HybridEngine, error) {
 	return &HybridEngine{
-		config: cfg,
+		engineImpl: newEngineImpl(&config.Config{Storage: cfg}, opts...),
 	}, nil
 }
 
-// Start starts the hybrid engine
-func (e *HybridEngine) Start(ctx context.Context) error {
-	// TODO: Initialize storage components
-	return nil
-}
-
-// Shutdown shuts down the hybrid engine
-func (e *HybridEngine) Shutdown(ctx context.Context) error {
-	// TODO: Cleanup storage components
-	return nil
-}
-
-// CreateTable creates a new table
-func (e *HybridEngine) CreateTable(name string, schema TableSchema) error {
-	// TODO: Implement table creation
-	return fmt.Errorf("not implemented")
-}
-
-// DropTable drops a table
-func (e *HybridEngine) DropTable(name string) error {
-	// TODO: Implement table dropping
-	return fmt.Errorf("not implemented")
-}
-
-// GetTable gets a table by name
-func (e *HybridEngine) GetTable(name string) (Table, error) {
-	// TODO: Implement table retrieval
-	return nil, fmt.Errorf("not implemented")
-}
-
-// ListTables lists all tables
-func (e *HybridEngine) ListTables() ([]string, error) {
-	// TODO: Implement table listing
-	return []string{}, nil
-}
-
-// CreateTensor creates a new tensor
-func (e *HybridEngine) CreateTensor(name string, schema TensorSchema) error {
-	// TODO: Implement tensor creation
-	return fmt.Errorf("not implemented")
-}
-
-// DropTensor drops a tensor
-func (e *HybridEngine) DropTensor(name string) error {
-	// TODO: Implement tensor dropping
-	return fmt.Errorf("not implemented")
-}
-
-// GetTensor gets a tensor by name
-func (e *HybridEngine) GetTensor(name string) (Tensor, error) {
-	// TODO: Implement tensor retrieval
-	return nil, fmt.Errorf("not implemented")
-}
-
-// ListTensors lists all tensors
-func (e *HybridEngine) ListTensors() ([]string, error) {
-	// TODO: Implement tensor listing
-	return []string{}, nil
-}
-
-// ExecuteQuery executes a query
-func (e *HybridEngine) ExecuteQuery(ctx context.Context, query string) (Result, error) {
-	// TODO: Implement query execution
-	return Result{}, fmt.Errorf("not implemented")
-}
-
-// BeginTransaction begins a new transaction
-func (e *HybridEngine) BeginTransaction(ctx context.Context) (Transaction, error) {
-	// TODO: Implement transaction management
-	return nil, fmt.Errorf("not implemented")
-}
-
-// MemoryEngine implements an in-memory storage engine for testing
+// MemoryEngine keeps the catalog and table rows in a private in-memory SQLite
+// database and tensors in process memory. It behaves like HybridEngine without
+// touching disk, and everything is discarded on Shutdown.
 type MemoryEngine struct {
-	tables  map[string]Table
-	tensors map[string]Tensor
+	*engineImpl
 }
 
 // NewMemoryEngine creates a new memory storage engine
-func NewMemoryEngine(cfg config.StorageConfig) (*MemoryEngine, error) {
-	return &MemoryEngine{
-		tables:  make(map[string]Table),
-		tensors: make(map[string]Tensor),
-	}, nil
-}
-
-// Start starts the memory engine
-func (e *MemoryEngine) Start(ctx context.Context) error {
-	return nil
-}
-
-// Shutdown shuts down the memory engine
-func (e *MemoryEngine) Shutdown(ctx context.Context) error {
-	e.tables = nil
-	e.tensors = nil
-	return nil
-}
-
-// CreateTable creates a new table in memory
-func (e *MemoryEngine) CreateTable(name string, schema TableSchema) error {
-	// TODO: Implement memory table creation
-	return fmt.Errorf("not implemented")
-}
-
-// DropTable drops a table from memory
-func (e *MemoryEngine) DropTable(name string) error {
-	delete(e.tables, name)
-	return nil
-}
-
-// GetTable gets a table by name from memory
-func (e *MemoryEngine) GetTable(name string) (Table, error) {
-	table, exists := e.tables[name]
-	if !exists {
-		return nil, fmt.Errorf("table not found: %s", name)
-	}
-	return table, nil
-}
-
-// ListTables lists all tables in memory
-func (e *MemoryEngine) ListTables() ([]string, error) {
-	names := make([]string, 0, len(e.tables))
-	for name := range e.tables {
-		names = append(names, name)
-	}
-	return names, nil
-}
-
-// CreateTensor creates a new tensor in memory
-func (e *MemoryEngine) CreateTensor(name string, schema TensorSchema) error {
-	// TODO: Implement memory tensor creation
-	return fmt.Errorf("not implemented")
-}
-
-// DropTensor drops a tensor from memory
-func (e *MemoryEngine) DropTensor(name string) error {
-	delete(e.tensors, name)
-	return nil
-}
-
-// GetTensor gets a tensor by name from memory
-func (e *MemoryEngine) GetTensor(name string) (Tensor, error) {
-	tensor, exists := e.tensors[name]
-	if !exists {
-		return nil, fmt.Errorf("tensor not found: %s", name)
-	}
-	return tensor, nil
-}
-
-// ListTensors lists all tensors in memory
-func (e *MemoryEngine) ListTensors() ([]string, error) {
-	names := make([]string, 0, len(e.tensors))
-	for name := range e.tensors {
-		names = append(names, name)
-	}
-	return names, nil
-}
-
-// ExecuteQuery executes a query in memory
-func (e *MemoryEngine) ExecuteQuery(ctx context.Context, query string) (Result, error) {
-	// TODO: Implement memory query execution
-	return Result{}, fmt.Errorf("not implemented")
-}
+func NewMemoryEngine(cfg config.StorageConfig, opts ...Option) (*MemoryEngine, error) {
+	engine := newEngineImpl(&config.Config{Storage: cfg}, opts...)
+	engine.inMemory = true
 
-// BeginTransaction begins a new transaction in memory
-func (e *MemoryEngine) BeginTransaction(ctx context.Context) (Transaction, error) {
-	// TODO: Implement memory transaction management
-	return nil, fmt.Errorf("not implemented")
+	return &MemoryEngine{engineImpl: engine}, nil
 }
@@ -7,8 +7,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 
+	"github.com/google/uuid"
 	"github.com/telumdb/telumdb/internal/config"
 	"go.uber.org/zap"
 	_ "modernc.org/sqlite"
@@ -23,67 +25,166 @@ type engineImpl struct {
 	tensors    map[string]*tensorImpl
 	tensorLock sync.RWMutex
 	started    bool
-}
 
-// NewEngine creates a new storage engine instance
-func NewEngine(cfg *config.Config) (Engine, error) {
-	if err := os.MkdirAll(cfg.Storage.DataDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create data directory: %w", err)
-	}
+	// inMemory engines keep the catalog in a private in-memory database and
+	// never write tensor files; memConn pins that database while started
+	inMemory bool
+	memConn  *sql.Conn
+
+	// syncMode is the validated StorageConfig.SyncMode. checkpointMu
+	// serializes checkpoints, and the background checkpointer runs until
+	// stopCheckpoint is closed.
+	syncMode       string
+	checkpointMu   sync.Mutex
+	stopCheckpoint chan struct{}
+	checkpointDone chan struct{}
+
+	// cache holds the chunks of file-backed tensors read or stored since
+	// the engine started
+	cache *chunkCache
+}
 
-	dbPath := filepath.Join(cfg.Storage.DataDir, "telumdb.db")
-	db, err := sql.Open("sqlite3", dbPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
-	}
+// NewEngine creates a new storage engine instance. Nothing is touched on disk
+// until Start is called.
+func NewEngine(cfg *config.Config, opts ...Option) (Engine, error) {
+	return newEngineImpl(cfg, opts...), nil
+}
 
-	engine := &engineImpl{
+// newEngineImpl creates an engine rooted at the configured data directory
+func newEngineImpl(cfg *config.Config, opts ...Option) *engineImpl {
+	e := &engineImpl{
 		config:  cfg,
-		db:      db,
+		logger:  zap.NewNop(),
 		dataDir: cfg.Storage.DataDir,
 		tensors: make(map[string]*tensorImpl),
 	}
-
-	return engine, nil
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
 }
 
-// Start initializes the storage engine
+// Start opens the database in the data directory and loads the schemas of
+// existing tensors; their chunks are read when first accessed
 func (e *engineImpl) Start(ctx context.Context) error {
 	if e.started {
 		return nil
 	}
 
+	mode, err := parseSyncMode(e.config.Storage.SyncMode)
+	if err != nil {
+		return err
+	}
+	e.syncMode = mode
+	e.cache = newChunkCache(cacheLimit(e.config.Storage), e.syncFiles(), e.logger)
+
+	if err := e.openDB(ctx); err != nil {
+		return err
+	}
+
 	// Initialize database schema
 	if err := e.initSchema(); err != nil {
+		e.closeDB()
 		return fmt.Errorf("failed to initialize schema: %w", err)
 	}
 
 	// Load existing tensors
 	if err := e.loadTensors(); err != nil {
+		e.closeDB()
 		return fmt.Errorf("failed to load tensors: %w", err)
 	}
 
+	// Apply logged chunks that had not reached the tensor files before a crash
+	if err := e.replayTensorJournal(); err != nil {
+		e.closeDB()
+		return fmt.Errorf("failed to replay tensor journal: %w", err)
+	}
+
 	e.started = true
+	e.startCheckpointer()
+	return nil
+}
+
+// openDB opens the catalog database: a file in the data directory, or for
+// in-memory engines a database that lives only as long as the engine runs
+func (e *engineImpl) openDB(ctx context.Context) error {
+	dsn := fmt.Sprintf("file:/telumdb-%s?vfs=memdb", uuid.New())
+	if !e.inMemory {
+		if e.dataDir == "" {
+			return fmt.Errorf("data directory not configured")
+		}
+		if err := os.MkdirAll(e.dataDir, 0755); err != nil {
+			return fmt.Errorf("failed to create data directory: %w", err)
+		}
+		dsn = filepath.Join(e.dataDir, "telumdb.db") + "?"
+	} else {
+		dsn += "&"
+	}
+
+	// Concurrent writers wait for each other instead of failing with SQLITE_BUSY
+	pragmas := []string{"busy_timeout(5000)"}
+	if !e.inMemory {
+		if e.walEnabled() {
+			pragmas = append(pragmas, "journal_mode(WAL)")
+		}
+		pragmas = append(pragmas, "synchronous("+syncModes[e.syncMode]+")")
+	}
+	// Transactions take the write lock when they begin: under WAL, a deferred
+	// transaction that reads and then writes fails with SQLITE_BUSY instead of
+	// waiting when another writer committed since its read
+	dsn += "_txlock=immediate&"
+	db, err := sql.Open("sqlite", dsn+"_pragma="+strings.Join(pragmas, "&_pragma="))
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// An in-memory database is freed when its last connection closes
+	if e.inMemory {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			db.Close()
+			return fmt.Errorf("failed to open database: %w", err)
+		}
+		e.memConn = conn
+	}
+
+	e.db = db
 	return nil
 }
 
+// closeDB closes the catalog database
+func (e *engineImpl) closeDB() error {
+	if e.memConn != nil {
+		e.memConn.Close()
+		e.memConn = nil
+	}
+	return e.db.Close()
+}
+
 // Shutdown gracefully shuts down the storage engine
 func (e *engineImpl) Shutdown(ctx context.Context) error {
 	if !e.started {
 		return nil
 	}
 
-	// Save all tensors
-	e.tensorLock.Lock()
-	for name, tensor := range e.tensors {
-		if err := tensor.save(); err != nil {
-			e.logger.Error("Failed to save tensor", zap.String("name", name), zap.Error(err))
+	// Write dirty cached chunks to their files and trim the tensor log
+	e.stopCheckpointer()
+	if !e.inMemory {
+		if err := e.checkpoint(); err != nil {
+			e.logger.Error("Failed to checkpoint tensors", zap.Error(err))
 		}
 	}
+
+	e.tensorLock.Lock()
+	for _, tensor := range e.tensors {
+		tensorMemoryBytes.Add(-tensorBytes(tensor))
+	}
+	e.tensors = make(map[string]*tensorImpl)
 	e.tensorLock.Unlock()
+	e.cache.clear()
 
 	// Close database
-	if err := e.db.Close(); err != nil {
+	if err := e.closeDB(); err != nil {
 		return fmt.Errorf("failed to close database: %w", err)
 	}
 
@@ -106,17 +207,23 @@ func (e *engineImpl) initSchema() error {
 		)`,
 		`CREATE TABLE IF NOT EXISTS table_data (
 			table_name TEXT NOT NULL,
-			row_id TEXT NOT NULL,
+			row_id INTEGER NOT NULL,
 			data TEXT NOT NULL,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			PRIMARY KEY (table_name, row_id),
 			FOREIGN KEY (table_name) REFERENCES tables(name) ON DELETE CASCADE
 		)`,
+		`CREATE TABLE IF NOT EXISTS table_sequences (
+			table_name TEXT PRIMARY KEY,
+			last_id INTEGER NOT NULL,
+			FOREIGN KEY (table_name) REFERENCES tables(name) ON DELETE CASCADE
+		)`,
 		`CREATE TABLE IF NOT EXISTS indexes (
 			name TEXT PRIMARY KEY,
 			table_name TEXT NOT NULL,
 			columns TEXT NOT NULL,
 			type TEXT NOT NULL,
+			is_unique INTEGER NOT NULL DEFAULT 0,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (table_name) REFERENCES tables(name) ON DELETE CASCADE
 		)`,
@@ -127,45 +234,133 @@ func (e *engineImpl) initSchema() error {
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		)`,
+		`CREATE TABLE IF NOT EXISTS tensor_journal (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			tensor_name TEXT NOT NULL,
+			chunk TEXT NOT NULL,
+			data BLOB NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS users (
+			name TEXT PRIMARY KEY,
+			password_hash TEXT NOT NULL,
+			superuser INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS privileges (
+			grantee TEXT NOT NULL,
+			object_type TEXT NOT NULL,
+			object_name TEXT NOT NULL,
+			privilege TEXT NOT NULL,
+			granted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (grantee, object_type, object_name, privilege),
+			FOREIGN KEY (grantee) REFERENCES users(name) ON DELETE CASCADE
+		)`,
 	}
 
 	for _, schema := range schemas {
 		if _, err := e.db.Exec(schema); err != nil {
-			return fmt.Errorf("failed to execute schema: %w", err)
+			return fmt.Errorf("failed to execute schema: %w", sqliteError("schema", err))
 		}
 	}
 
+	if err := e.migrateRowIDs(); err != nil {
+		return err
+	}
+	if err := e.migrateIndexes(); err != nil {
+		return err
+	}
+
 	// Insert schema version
 	_, err := e.db.Exec(`INSERT OR REPLACE INTO telumdb_schema (version) VALUES (?)`, "1.0")
 	if err != nil {
-		return fmt.Errorf("failed to set schema version: %w", err)
+		return fmt.Errorf("failed to set schema version: %w", sqliteError("schema", err))
 	}
 
 	return nil
 }
 
+// migrateRowIDs converts table_data from text row IDs, as written by earlier
+// versions, to integer IDs numbered in insertion order, and seeds each table's
+// row ID sequence
+func (e *engineImpl) migrateRowIDs() error {
+	var rowIDType string
+	err := e.db.QueryRow(`SELECT type FROM pragma_table_info('table_data') WHERE name = 'row_id'`).Scan(&rowIDType)
+	if err != nil {
+		return fmt.Errorf("failed to inspect table_data: %w", sqliteError("schema", err))
+	}
+	if strings.EqualFold(rowIDType, "INTEGER") {
+		return nil
+	}
+
+	tx, err := e.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", sqliteError("transaction", err))
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		`ALTER TABLE table_data RENAME TO table_data_v1`,
+		`CREATE TABLE table_data (
+			table_name TEXT NOT NULL,
+			row_id INTEGER NOT NULL,
+			data TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (table_name, row_id),
+			FOREIGN KEY (table_name) REFERENCES tables(name) ON DELETE CASCADE
+		)`,
+		`INSERT INTO table_data (table_name, row_id, data, created_at)
+			SELECT table_name, ROW_NUMBER() OVER (PARTITION BY table_name ORDER BY rowid), data, created_at
+			FROM table_data_v1`,
+		`DROP TABLE table_data_v1`,
+		`INSERT OR REPLACE INTO table_sequences (table_name, last_id)
+			SELECT table_name, MAX(row_id) FROM table_data GROUP BY table_name`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return fmt.Errorf("failed to migrate row IDs: %w", sqliteError("schema", err))
+		}
+	}
+
+	return tx.Commit()
+}
+
+// migrateIndexes adds the uniqueness flag missing from the index catalog of
+// earlier versions
+func (e *engineImpl) migrateIndexes() error {
+	var exists int
+	err := e.db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('indexes') WHERE name = 'is_unique'`).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to inspect indexes: %w", sqliteError("schema", err))
+	}
+	if exists > 0 {
+		return nil
+	}
+
+	if _, err := e.db.Exec(`ALTER TABLE indexes ADD COLUMN is_unique INTEGER NOT NULL DEFAULT 0`); err != nil {
+		return fmt.Errorf("failed to migrate indexes: %w", sqliteError("schema", err))
+	}
+	return nil
+}
+
 // CreateTable creates a new table
 func (e *engineImpl) CreateTable(name string, schema TableSchema) error {
 	if !e.started {
 		return fmt.Errorf("engine not started")
 	}
 
-	// Serialize schema
-	schemaJSON, err := json.Marshal(schema)
+	tx, err := e.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to serialize schema: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", sqliteError("transaction", err))
 	}
+	defer tx.Rollback()
 
-	// Insert table metadata
-	_, err = e.db.Exec(
-		`INSERT INTO tables (name, schema) VALUES (?, ?)`,
-		name, string(schemaJSON),
-	)
-	if err != nil {
-		return fmt.Errorf("failed to create table: %w", err)
+	if err := createTable(tx, name, schema); err != nil {
+		return err
 	}
 
-	return nil
+	return tx.Commit()
 }
 
 // DropTable removes a table
@@ -176,29 +371,105 @@ func (e *engineImpl) DropTable(name string) error {
 
 	tx, err := e.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", sqliteError("transaction", err))
 	}
 	defer tx.Rollback()
 
+	if err := dropTable(tx, name); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// execer is implemented by *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// createTable adds a table to the catalog and builds its indexes
+func createTable(db execer, name string, schema TableSchema) error {
+	if err := validateObjectName(name); err != nil {
+		return err
+	}
+	if err := validateTableSchema(schema); err != nil {
+		return fmt.Errorf("table %s: %w", name, err)
+	}
+
+	var exists int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM tables WHERE name = ?`, name).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check table: %w", sqliteError("catalog", err))
+	}
+	if exists > 0 {
+		return fmt.Errorf("table %s %w", name, ErrAlreadyExists)
+	}
+
+	// Serialize schema; indexes are recorded in their own catalog table
+	columns := schema
+	columns.Indexes = nil
+	schemaJSON, err := json.Marshal(columns)
+	if err != nil {
+		return fmt.Errorf("failed to serialize schema: %w", err)
+	}
+
+	// Insert table metadata
+	_, err = db.Exec(
+		`INSERT INTO tables (name, schema) VALUES (?, ?)`,
+		name, string(schemaJSON),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create table: %w", sqliteError("catalog", err))
+	}
+
+	if err := createPrimaryKeyIndex(db, name, schema); err != nil {
+		return err
+	}
+	for _, index := range schema.Indexes {
+		if err := createIndex(db, name, schema, index); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dropTable removes a table with its rows, indexes and privileges
+func dropTable(tx *sql.Tx, name string) error {
 	// Delete table data
-	_, err = tx.Exec(`DELETE FROM table_data WHERE table_name = ?`, name)
+	_, err := tx.Exec(`DELETE FROM table_data WHERE table_name = ?`, name)
 	if err != nil {
-		return fmt.Errorf("failed to delete table data: %w", err)
+		return fmt.Errorf("failed to delete table data: %w", sqliteError("catalog", err))
+	}
+
+	// Delete row ID sequence
+	_, err = tx.Exec(`DELETE FROM table_sequences WHERE table_name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete row ID sequence: %w", sqliteError("catalog", err))
 	}
 
 	// Delete indexes
-	_, err = tx.Exec(`DELETE FROM indexes WHERE table_name = ?`, name)
+	if err := dropTableIndexes(tx, name); err != nil {
+		return err
+	}
+
+	// Delete privileges
+	_, err = tx.Exec(`DELETE FROM privileges WHERE object_type = ? AND object_name = ?`, ObjectTypeTable, name)
 	if err != nil {
-		return fmt.Errorf("failed to delete indexes: %w", err)
+		return fmt.Errorf("failed to delete privileges: %w", sqliteError("catalog", err))
 	}
 
 	// Delete table
-	_, err = tx.Exec(`DELETE FROM tables WHERE name = ?`, name)
+	res, err := tx.Exec(`DELETE FROM tables WHERE name = ?`, name)
 	if err != nil {
-		return fmt.Errorf("failed to delete table: %w", err)
+		return fmt.Errorf("failed to delete table: %w", sqliteError("catalog", err))
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("table %s %w", name, ErrNotFound)
 	}
 
-	return tx.Commit()
+	return nil
 }
 
 // GetTable retrieves a table
@@ -207,21 +478,9 @@ func (e *engineImpl) GetTable(name string) (Table, error) {
 		return nil, fmt.Errorf("engine not started")
 	}
 
-	var schemaJSON string
-	err := e.db.QueryRow(
-		`SELECT schema FROM tables WHERE name = ?`,
-		name,
-	).Scan(&schemaJSON)
+	schema, err := loadTableSchema(e.db, name)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("table not found: %s", name)
-		}
-		return nil, fmt.Errorf("failed to get table: %w", err)
-	}
-
-	var schema TableSchema
-	if err := json.Unmarshal([]byte(schemaJSON), &schema); err != nil {
-		return nil, fmt.Errorf("failed to deserialize schema: %w", err)
+		return nil, err
 	}
 
 	return &memoryTable{
@@ -239,7 +498,7 @@ func (e *engineImpl) ListTables() ([]string, error) {
 
 	rows, err := e.db.Query(`SELECT name FROM tables ORDER BY name`)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list tables: %w", err)
+		return nil, fmt.Errorf("failed to list tables: %w", sqliteError("catalog", err))
 	}
 	defer rows.Close()
 
@@ -261,12 +520,26 @@ func (e *engineImpl) CreateTensor(name string, schema TensorSchema) error {
 		return fmt.Errorf("engine not started")
 	}
 
+	if err := validateObjectName(name); err != nil {
+		return err
+	}
+	if err := validateTensorSchema(schema); err != nil {
+		return fmt.Errorf("tensor %s: %w", name, err)
+	}
+
 	e.tensorLock.Lock()
 	defer e.tensorLock.Unlock()
 
 	// Check if tensor already exists
 	if _, exists := e.tensors[name]; exists {
-		return fmt.Errorf("tensor already exists: %s", name)
+		return fmt.Errorf("tensor %s %w", name, ErrAlreadyExists)
+	}
+
+	// Chunks read as zeros until written, so there is nothing to save; only
+	// files left behind by an earlier tensor of the same name are removed
+	tensor := e.newTensor(name, schema)
+	if err := tensor.removeFiles(); err != nil {
+		return fmt.Errorf("failed to remove old tensor files: %w", err)
 	}
 
 	// Serialize schema
@@ -281,24 +554,11 @@ func (e *engineImpl) CreateTensor(name string, schema TensorSchema) error {
 		name, string(schemaJSON), "{}",
 	)
 	if err != nil {
-		return fmt.Errorf("failed to create tensor: %w", err)
-	}
-
-	// Create tensor instance
-	tensor := &tensorImpl{
-		name:   name,
-		schema: schema,
-		engine: e,
-		data:   make([]float32, e.calculateTensorSize(schema)),
+		return fmt.Errorf("failed to create tensor: %w", sqliteError("catalog", err))
 	}
 
 	e.tensors[name] = tensor
-
-	// Save tensor data
-	if err := tensor.save(); err != nil {
-		delete(e.tensors, name)
-		return fmt.Errorf("failed to save tensor: %w", err)
-	}
+	tensorMemoryBytes.Add(tensorBytes(tensor))
 
 	return nil
 }
@@ -312,19 +572,48 @@ func (e *engineImpl) DropTensor(name string) error {
 	e.tensorLock.Lock()
 	defer e.tensorLock.Unlock()
 
-	// Remove from memory
-	if tensor, exists := e.tensors[name]; exists {
-		tensorPath := tensor.getFilePath()
-		os.Remove(tensorPath)
-		delete(e.tensors, name)
+	tensor, exists := e.tensors[name]
+	if !exists {
+		return fmt.Errorf("tensor %s %w", name, ErrNotFound)
 	}
 
-	// Remove from database
-	_, err := e.db.Exec(`DELETE FROM tensors WHERE name = ?`, name)
+	tx, err := e.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to delete tensor: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", sqliteError("transaction", err))
 	}
+	defer tx.Rollback()
 
+	if err := dropTensor(tx, name); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", sqliteError("transaction", err))
+	}
+
+	// The data goes only once the catalog no longer lists the tensor
+	e.cache.drop(tensor)
+	tensor.removeFiles()
+	delete(e.tensors, name)
+	tensorMemoryBytes.Add(-tensorBytes(tensor))
+
+	return nil
+}
+
+// dropTensor removes a tensor from the catalog along with its privileges and
+// logged chunks, which must not be replayed into a tensor created later with
+// the same name
+func dropTensor(db execer, name string) error {
+	_, err := db.Exec(`DELETE FROM tensors WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete tensor: %w", sqliteError("catalog", err))
+	}
+	_, err = db.Exec(`DELETE FROM privileges WHERE object_type = ? AND object_name = ?`, ObjectTypeTensor, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete tensor privileges: %w", sqliteError("catalog", err))
+	}
+	if _, err := db.Exec(`DELETE FROM tensor_journal WHERE tensor_name = ?`, name); err != nil {
+		return fmt.Errorf("failed to delete tensor journal: %w", sqliteError("journal", err))
+	}
 	return nil
 }
 
@@ -339,7 +628,7 @@ func (e *engineImpl) GetTensor(name string) (Tensor, error) {
 	e.tensorLock.RUnlock()
 
 	if !exists {
-		return nil, fmt.Errorf("tensor not found: %s", name)
+		return nil, fmt.Errorf("tensor %s %w", name, ErrNotFound)
 	}
 
 	return tensor, nil
@@ -353,7 +642,7 @@ func (e *engineImpl) ListTensors() ([]string, error) {
 
 	rows, err := e.db.Query(`SELECT name FROM tensors ORDER BY name`)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list tensors: %w", err)
+		return nil, fmt.Errorf("failed to list tensors: %w", sqliteError("catalog", err))
 	}
 	defer rows.Close()
 
@@ -379,7 +668,7 @@ func (e *engineImpl) ExecuteQuery(ctx context.Context, query string) (Result, er
 	// TODO: Add TQL parsing and execution
 	rows, err := e.db.QueryContext(ctx, query)
 	if err != nil {
-		return Result{}, fmt.Errorf("failed to execute query: %w", err)
+		return Result{}, fmt.Errorf("failed to execute query: %w", sqliteError("query", err))
 	}
 	defer rows.Close()
 
@@ -425,7 +714,7 @@ func (e *engineImpl) BeginTransaction(ctx context.Context) (Transaction, error)
 
 	tx, err := e.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", sqliteError("transaction", err))
 	}
 
 	return &memoryTransaction{
@@ -436,6 +725,21 @@ func (e *engineImpl) BeginTransaction(ctx context.Context) (Transaction, error)
 
 // Helper methods
 
+// newTensor returns an empty tensor of the engine. Tensors of in-memory
+// engines hold all of their data; others keep it in chunk files.
+func (e *engineImpl) newTensor(name string, schema TensorSchema) *tensorImpl {
+	tensor := &tensorImpl{
+		name:    name,
+		schema:  schema,
+		engine:  e,
+		chunked: !e.inMemory,
+	}
+	if e.inMemory {
+		tensor.data = make([]float32, e.calculateTensorSize(schema))
+	}
+	return tensor
+}
+
 func (e *engineImpl) calculateTensorSize(schema TensorSchema) int {
 	size := 1
 	for _, dim := range schema.Shape {
@@ -448,9 +752,17 @@ func (e *engineImpl) loadTensors() error {
 	e.tensorLock.Lock()
 	defer e.tensorLock.Unlock()
 
+	// Remove temporary files left by saves interrupted by a crash
+	if !e.inMemory {
+		leftovers, _ := filepath.Glob(filepath.Join(e.dataDir, "tensor_*.tmp"))
+		for _, path := range leftovers {
+			os.Remove(path)
+		}
+	}
+
 	rows, err := e.db.Query(`SELECT name, schema, metadata FROM tensors`)
 	if err != nil {
-		return fmt.Errorf("failed to load tensors: %w", err)
+		return fmt.Errorf("failed to load tensors: %w", sqliteError("catalog", err))
 	}
 	defer rows.Close()
 
@@ -465,19 +777,15 @@ func (e *engineImpl) loadTensors() error {
 			return fmt.Errorf("failed to deserialize tensor schema: %w", err)
 		}
 
-		tensor := &tensorImpl{
-			name:   name,
-			schema: schema,
-			engine: e,
-			data:   make([]float32, e.calculateTensorSize(schema)),
-		}
+		tensor := e.newTensor(name, schema)
 
-		// Load tensor data from file
+		// Prepare tensor files; chunks are read when accessed
 		if err := tensor.load(); err != nil {
-			e.logger.Warn("Failed to load tensor data", zap.String("name", name), zap.Error(err))
+			e.logger.Error("Failed to load tensor data", zap.String("name", name), zap.Error(err))
 		}
 
 		e.tensors[name] = tensor
+		tensorMemoryBytes.Add(tensorBytes(tensor))
 	}
 
 	return nil
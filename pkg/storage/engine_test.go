@@ -2,9 +2,17 @@ package storage
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/telumdb/telumdb/internal/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestNewEngine(t *testing.T) {
@@ -46,6 +54,193 @@ func TestNewEngine(t *testing.T) {
 	}
 }
 
+func TestHybridEngine(t *testing.T) {
+	cfg := config.StorageConfig{
+		Engine:  "hybrid",
+		DataDir: t.TempDir(),
+	}
+
+	engine, err := CreateEngine(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create hybrid engine: %v", err)
+	}
+	if _, ok := engine.(UserStore); !ok {
+		t.Error("hybrid engine should support user accounts")
+	}
+	if _, ok := engine.(PrivilegeStore); !ok {
+		t.Error("hybrid engine should support privileges")
+	}
+
+	ctx := context.Background()
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	schema := TableSchema{
+		Columns: []ColumnDefinition{
+			{Name: "id", Type: "INTEGER", Nullable: false},
+			{Name: "name", Type: "TEXT", Nullable: true},
+		},
+	}
+	if err := engine.CreateTable("users_table", schema); err != nil {
+		t.Fatalf("CreateTable() error = %v", err)
+	}
+	table, err := engine.GetTable("users_table")
+	if err != nil {
+		t.Fatalf("GetTable() error = %v", err)
+	}
+	if _, err := table.Insert(ctx, Row{"id": 1, "name": "alice"}); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	if err := engine.CreateTensor("weights", TensorSchema{Shape: []int{2, 2}, DType: "float32", ChunkSize: []int{2, 2}}); err != nil {
+		t.Fatalf("CreateTensor() error = %v", err)
+	}
+	tensor, err := engine.GetTensor("weights")
+	if err != nil {
+		t.Fatalf("GetTensor() error = %v", err)
+	}
+	chunk := float32SliceToBytes([]float32{1, 2, 3, 4})
+	if err := tensor.StoreChunk(ctx, []int{0, 0}, chunk); err != nil {
+		t.Fatalf("StoreChunk() error = %v", err)
+	}
+
+	if err := engine.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	// Everything is still there after a restart
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("restart error = %v", err)
+	}
+	defer engine.Shutdown(ctx)
+
+	table, err = engine.GetTable("users_table")
+	if err != nil {
+		t.Fatalf("GetTable() after restart error = %v", err)
+	}
+	if count, err := table.Count(ctx, nil); err != nil || count != 1 {
+		t.Errorf("Count() after restart = %d, %v, want 1", count, err)
+	}
+
+	tensor, err = engine.GetTensor("weights")
+	if err != nil {
+		t.Fatalf("GetTensor() after restart error = %v", err)
+	}
+	got, err := tensor.GetChunk(ctx, []int{0, 0})
+	if err != nil {
+		t.Fatalf("GetChunk() after restart error = %v", err)
+	}
+	if string(got) != string(chunk) {
+		t.Errorf("GetChunk() after restart = %v, want %v", got, chunk)
+	}
+}
+
+func TestHybridEngineRequiresDataDir(t *testing.T) {
+	engine, err := CreateEngine(config.StorageConfig{Engine: "hybrid"})
+	if err != nil {
+		t.Fatalf("CreateEngine() error = %v", err)
+	}
+	if err := engine.Start(context.Background()); err == nil {
+		t.Error("Start() without a data directory should fail")
+	}
+}
+
+func TestHybridEngineMigratesRowIDs(t *testing.T) {
+	dataDir := t.TempDir()
+
+	// Lay out a database the way earlier versions did, with text row IDs
+	db, err := sql.Open("sqlite", filepath.Join(dataDir, "telumdb.db"))
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	statements := []string{
+		`CREATE TABLE tables (name TEXT PRIMARY KEY, schema TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP)`,
+		`CREATE TABLE table_data (table_name TEXT NOT NULL, row_id TEXT NOT NULL, data TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (table_name, row_id))`,
+		`INSERT INTO tables (name, schema) VALUES ('people', '{}')`,
+		`INSERT INTO table_data (table_name, row_id, data) VALUES ('people', 'f0', '{"name":"alice"}')`,
+		`INSERT INTO table_data (table_name, row_id, data) VALUES ('people', 'a1', '{"name":"bob"}')`,
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("Exec(%q) error = %v", statement, err)
+		}
+	}
+	db.Close()
+
+	engine, err := CreateEngine(config.StorageConfig{Engine: "hybrid", DataDir: dataDir})
+	if err != nil {
+		t.Fatalf("CreateEngine() error = %v", err)
+	}
+	ctx := context.Background()
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer engine.Shutdown(ctx)
+
+	table, err := engine.GetTable("people")
+	if err != nil {
+		t.Fatalf("GetTable() error = %v", err)
+	}
+	it, err := table.Select(ctx, []string{"name"}, nil)
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+
+	// Rows are numbered in insertion order
+	for _, want := range []string{"alice", "bob"} {
+		if !it.Next() {
+			t.Fatalf("Next() = false, want row %s", want)
+		}
+		var name string
+		if err := it.Scan(&name); err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+		if name != want {
+			t.Errorf("row %d name = %q, want %q", it.RowID(), name, want)
+		}
+	}
+	it.Close()
+
+	if id, err := table.Insert(ctx, Row{"name": "carol"}); err != nil || id != 3 {
+		t.Errorf("Insert() = %d, %v, want 3", id, err)
+	}
+}
+
+func TestEngineLogger(t *testing.T) {
+	ctx := context.Background()
+	dataDir := t.TempDir()
+	engine := newHybridEngine(t, dataDir)
+	if err := engine.CreateTensor("embeddings", TensorSchema{Shape: []int{4}, DType: "float32", ChunkSize: []int{2}}); err != nil {
+		t.Fatalf("CreateTensor() error = %v", err)
+	}
+	if err := engine.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	// A data file of the wrong size can't be loaded; the engine starts anyway
+	// and reports the tensor to its logger
+	if err := os.WriteFile(filepath.Join(dataDir, "tensor_embeddings.bin"), []byte{1, 2, 3, 4}, 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	core, logs := observer.New(zap.WarnLevel)
+	engine, err := CreateEngine(config.StorageConfig{Engine: "hybrid", DataDir: dataDir}, WithLogger(zap.New(core)))
+	if err != nil {
+		t.Fatalf("CreateEngine() error = %v", err)
+	}
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer engine.Shutdown(ctx)
+
+	if got := logs.FilterMessage("Failed to load tensor data").Len(); got != 1 {
+		t.Errorf("%d load failures logged, want 1 (logged: %v)", got, logs.All())
+	}
+}
+
 func TestMemoryEngine(t *testing.T) {
 	cfg := config.StorageConfig{
 		Engine: "memory",
@@ -68,12 +263,11 @@ func TestMemoryEngine(t *testing.T) {
 	}
 }
 
-func TestMemoryEngineTables(t *testing.T) {
-	cfg := config.StorageConfig{
-		Engine: "memory",
-	}
+// newMemoryEngine starts a memory engine for the duration of the test
+func newMemoryEngine(t *testing.T) Engine {
+	t.Helper()
 
-	engine, err := CreateEngine(cfg)
+	engine, err := CreateEngine(config.StorageConfig{Engine: "memory"})
 	if err != nil {
 		t.Fatalf("Failed to create memory engine: %v", err)
 	}
@@ -82,7 +276,14 @@ func TestMemoryEngineTables(t *testing.T) {
 	if err := engine.Start(ctx); err != nil {
 		t.Fatalf("Failed to start engine: %v", err)
 	}
-	defer engine.Shutdown(ctx)
+	t.Cleanup(func() { engine.Shutdown(ctx) })
+
+	return engine
+}
+
+func TestMemoryEngineTables(t *testing.T) {
+	engine := newMemoryEngine(t)
+	ctx := context.Background()
 
 	// Test empty table list
 	tables, err := engine.ListTables()
@@ -102,38 +303,96 @@ func TestMemoryEngineTables(t *testing.T) {
 		},
 	}
 
-	// Create table
-	if err := engine.CreateTable(tableName, schema); err == nil {
-		t.Error("CreateTable() should not be implemented yet")
+	// Get non-existent table
+	if _, err := engine.GetTable(tableName); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetTable() error = %v, want ErrNotFound", err)
 	}
 
-	// Get non-existent table
-	_, err = engine.GetTable(tableName)
-	if err == nil {
-		t.Error("GetTable() should return error for non-existent table")
+	if err := engine.CreateTable(tableName, schema); err != nil {
+		t.Fatalf("CreateTable() error = %v", err)
+	}
+	if err := engine.CreateTable(tableName, schema); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("CreateTable() twice error = %v, want ErrAlreadyExists", err)
+	}
+
+	table, err := engine.GetTable(tableName)
+	if err != nil {
+		t.Fatalf("GetTable() error = %v", err)
+	}
+	for i, name := range []string{"alice", "bob", "carol"} {
+		if _, err := table.Insert(ctx, Row{"id": i + 1, "name": name}); err != nil {
+			t.Fatalf("Insert() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		condition Condition
+		want      []string
+	}{
+		{"no condition", nil, []string{"alice", "bob", "carol"}},
+		{"equality", NewSimpleCondition("name", "=", "bob"), []string{"bob"}},
+		{"greater than", NewSimpleCondition("id", ">", 1), []string{"bob", "carol"}},
+		{"not equal", NewSimpleCondition("id", "!=", int64(2)), []string{"alice", "carol"}},
+		{"missing column", NewSimpleCondition("age", "=", 1), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			it, err := table.Select(ctx, []string{"name"}, tt.condition)
+			if err != nil {
+				t.Fatalf("Select() error = %v", err)
+			}
+			defer it.Close()
+
+			var got []string
+			for it.Next() {
+				var name string
+				if err := it.Scan(&name); err != nil {
+					t.Fatalf("Scan() error = %v", err)
+				}
+				got = append(got, name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Select() = %v, want %v", got, tt.want)
+			}
+
+			count, err := table.Count(ctx, tt.condition)
+			if err != nil {
+				t.Fatalf("Count() error = %v", err)
+			}
+			if count != int64(len(tt.want)) {
+				t.Errorf("Count() = %d, want %d", count, len(tt.want))
+			}
+		})
+	}
+
+	if _, err := table.Update(ctx, Row{"name": "robert"}, NewSimpleCondition("id", "=", 2)); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if count, _ := table.Count(ctx, NewSimpleCondition("name", "=", "robert")); count != 1 {
+		t.Errorf("Count() after Update() = %d, want 1", count)
+	}
+
+	if _, err := table.Delete(ctx, NewSimpleCondition("id", "<=", 2)); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if count, _ := table.Count(ctx, nil); count != 1 {
+		t.Errorf("Count() after Delete() = %d, want 1", count)
 	}
 
 	// Drop table
 	if err := engine.DropTable(tableName); err != nil {
 		t.Errorf("DropTable() error = %v", err)
 	}
+	if err := engine.DropTable(tableName); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DropTable() twice error = %v, want ErrNotFound", err)
+	}
 }
 
 func TestMemoryEngineTensors(t *testing.T) {
-	cfg := config.StorageConfig{
-		Engine: "memory",
-	}
-
-	engine, err := CreateEngine(cfg)
-	if err != nil {
-		t.Fatalf("Failed to create memory engine: %v", err)
-	}
-
+	engine := newMemoryEngine(t)
 	ctx := context.Background()
-	if err := engine.Start(ctx); err != nil {
-		t.Fatalf("Failed to start engine: %v", err)
-	}
-	defer engine.Shutdown(ctx)
 
 	// Test empty tensor list
 	tensors, err := engine.ListTensors()
@@ -147,26 +406,307 @@ func TestMemoryEngineTensors(t *testing.T) {
 	// Test tensor operations
 	tensorName := "test_tensor"
 	schema := TensorSchema{
-		Shape:       []int{10, 20},
+		Shape:       []int{2, 2},
 		DType:       "float32",
-		ChunkSize:   []int{5, 5},
+		ChunkSize:   []int{2, 2},
 		Compression: "none",
 		Metadata:    map[string]interface{}{"description": "test tensor"},
 	}
 
-	// Create tensor
-	if err := engine.CreateTensor(tensorName, schema); err == nil {
-		t.Error("CreateTensor() should not be implemented yet")
+	// Get non-existent tensor
+	if _, err := engine.GetTensor(tensorName); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetTensor() error = %v, want ErrNotFound", err)
 	}
 
-	// Get non-existent tensor
-	_, err = engine.GetTensor(tensorName)
-	if err == nil {
-		t.Error("GetTensor() should return error for non-existent tensor")
+	if err := engine.CreateTensor(tensorName, schema); err != nil {
+		t.Fatalf("CreateTensor() error = %v", err)
+	}
+	tensor, err := engine.GetTensor(tensorName)
+	if err != nil {
+		t.Fatalf("GetTensor() error = %v", err)
+	}
+
+	if err := tensor.StoreChunk(ctx, []int{0, 0}, float32SliceToBytes([]float32{1, 2, 3, 4})); err != nil {
+		t.Fatalf("StoreChunk() error = %v", err)
+	}
+	sum, err := tensor.ApplyOperation(ctx, Operation{Type: OperationTypeAdd, Operand: tensor})
+	if err != nil {
+		t.Fatalf("ApplyOperation() error = %v", err)
+	}
+	got, err := sum.GetChunk(ctx, []int{0, 0})
+	if err != nil {
+		t.Fatalf("GetChunk() error = %v", err)
+	}
+	if want := float32SliceToBytes([]float32{2, 4, 6, 8}); string(got) != string(want) {
+		t.Errorf("add result = %v, want %v", got, want)
+	}
+
+	if err := tensor.SetMetadata("owner", "tests"); err != nil {
+		t.Errorf("SetMetadata() error = %v", err)
+	}
+
+	// Metadata updates are all or nothing
+	if err := tensor.UpdateMetadata(map[string]interface{}{"stage": "eval", "bad": make(chan int)}); err == nil {
+		t.Error("UpdateMetadata() with an unserializable value should fail")
+	}
+	if err := tensor.UpdateMetadata(map[string]interface{}{"owner": "ml", "stage": "train"}); err != nil {
+		t.Errorf("UpdateMetadata() error = %v", err)
+	}
+	if got := tensor.Metadata(); !reflect.DeepEqual(got, map[string]interface{}{"description": "test tensor", "owner": "ml", "stage": "train"}) {
+		t.Errorf("Metadata() = %v, want owner and stage from the last update only", got)
 	}
 
 	// Drop tensor
 	if err := engine.DropTensor(tensorName); err != nil {
 		t.Errorf("DropTensor() error = %v", err)
 	}
+	if err := engine.DropTensor(tensorName); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DropTensor() twice error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryEngineTransactions(t *testing.T) {
+	engine := newMemoryEngine(t)
+	ctx := context.Background()
+
+	schema := TableSchema{Columns: []ColumnDefinition{{Name: "id", Type: "INTEGER"}}}
+	tensorSchema := TensorSchema{Shape: []int{4}, DType: "float32", ChunkSize: []int{4}}
+
+	tx, err := engine.BeginTransaction(ctx)
+	if err != nil {
+		t.Fatalf("BeginTransaction() error = %v", err)
+	}
+	if err := tx.CreateTable("rolled_back", schema); err != nil {
+		t.Fatalf("CreateTable() in transaction error = %v", err)
+	}
+	if err := tx.CreateTensor("rolled_back", tensorSchema); err != nil {
+		t.Fatalf("CreateTensor() in transaction error = %v", err)
+	}
+	if err := tx.Rollback(ctx); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+
+	if _, err := engine.GetTable("rolled_back"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("table after rollback: error = %v, want ErrNotFound", err)
+	}
+	if _, err := engine.GetTensor("rolled_back"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("tensor after rollback: error = %v, want ErrNotFound", err)
+	}
+
+	tx, err = engine.BeginTransaction(ctx)
+	if err != nil {
+		t.Fatalf("BeginTransaction() error = %v", err)
+	}
+	if err := tx.CreateTable("committed", schema); err != nil {
+		t.Fatalf("CreateTable() in transaction error = %v", err)
+	}
+	if err := tx.CreateTensor("committed", tensorSchema); err != nil {
+		t.Fatalf("CreateTensor() in transaction error = %v", err)
+	}
+	if err := tx.CreateTensor("committed", tensorSchema); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("CreateTensor() twice in transaction error = %v, want ErrAlreadyExists", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	if _, err := engine.GetTable("committed"); err != nil {
+		t.Errorf("table after commit: error = %v", err)
+	}
+	if _, err := engine.GetTensor("committed"); err != nil {
+		t.Errorf("tensor after commit: error = %v", err)
+	}
+
+	tx, err = engine.BeginTransaction(ctx)
+	if err != nil {
+		t.Fatalf("BeginTransaction() error = %v", err)
+	}
+	if err := tx.DropTable("committed"); err != nil {
+		t.Fatalf("DropTable() in transaction error = %v", err)
+	}
+	if err := tx.DropTensor("committed"); err != nil {
+		t.Fatalf("DropTensor() in transaction error = %v", err)
+	}
+	if _, err := engine.GetTensor("committed"); err != nil {
+		t.Errorf("tensor dropped in open transaction should still be visible: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+	if _, err := engine.GetTensor("committed"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("tensor after dropping commit: error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestTransactionTables(t *testing.T) {
+	engine := newMemoryEngine(t)
+	ctx := context.Background()
+	schema := TableSchema{Columns: []ColumnDefinition{
+		{Name: "id", Type: "INTEGER", PrimaryKey: true},
+		{Name: "item", Type: "TEXT"},
+	}}
+
+	// Rows written in a rolled back transaction go with its table
+	tx, err := engine.BeginTransaction(ctx)
+	if err != nil {
+		t.Fatalf("BeginTransaction() error = %v", err)
+	}
+	if err := tx.CreateTable("orders", schema); err != nil {
+		t.Fatalf("CreateTable() in transaction error = %v", err)
+	}
+	table, err := tx.GetTable("orders")
+	if err != nil {
+		t.Fatalf("GetTable() in transaction error = %v", err)
+	}
+	if _, err := table.Insert(ctx, Row{"item": "book"}); err != nil {
+		t.Fatalf("Insert() in transaction error = %v", err)
+	}
+	if err := tx.Rollback(ctx); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	if _, err := engine.GetTable("orders"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("table after rollback: error = %v, want ErrNotFound", err)
+	}
+	if _, err := table.Insert(ctx, Row{"item": "pen"}); err == nil {
+		t.Error("Insert() after rollback should fail")
+	}
+
+	tx, err = engine.BeginTransaction(ctx)
+	if err != nil {
+		t.Fatalf("BeginTransaction() error = %v", err)
+	}
+	if err := tx.CreateTable("orders", schema); err != nil {
+		t.Fatalf("CreateTable() in transaction error = %v", err)
+	}
+	table, err = tx.GetTable("orders")
+	if err != nil {
+		t.Fatalf("GetTable() in transaction error = %v", err)
+	}
+	for _, item := range []string{"book", "pen", "lamp"} {
+		if _, err := table.Insert(ctx, Row{"item": item}); err != nil {
+			t.Fatalf("Insert() in transaction error = %v", err)
+		}
+	}
+
+	// A failed write leaves the rest of the transaction intact
+	if _, err := table.Insert(ctx, Row{"id": 1, "item": "mug"}); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("Insert() with duplicate key error = %v, want ErrAlreadyExists", err)
+	}
+	if _, err := table.Update(ctx, Row{"id": 9}, NewSimpleCondition("item", "=", "pen")); err == nil {
+		t.Error("Update() changing the primary key should fail")
+	}
+	if n, err := table.Update(ctx, Row{"item": "pencil"}, NewSimpleCondition("item", "=", "pen")); err != nil || n != 1 {
+		t.Errorf("Update() in transaction = %d, %v, want 1", n, err)
+	}
+	if n, err := table.Delete(ctx, NewSimpleCondition("item", "=", "lamp")); err != nil || n != 1 {
+		t.Errorf("Delete() in transaction = %d, %v, want 1", n, err)
+	}
+
+	it, err := table.Select(ctx, []string{"item"}, nil)
+	if err != nil {
+		t.Fatalf("Select() in transaction error = %v", err)
+	}
+	var items []string
+	for it.Next() {
+		var item string
+		if err := it.Scan(&item); err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+		items = append(items, item)
+	}
+	it.Close()
+	if !reflect.DeepEqual(items, []string{"book", "pencil"}) {
+		t.Errorf("Select() in transaction = %v, want [book pencil]", items)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	committed, err := engine.GetTable("orders")
+	if err != nil {
+		t.Fatalf("GetTable() after commit error = %v", err)
+	}
+	if count, _ := committed.Count(ctx, nil); count != 2 {
+		t.Errorf("Count() after commit = %d, want 2", count)
+	}
+	if id, err := committed.Insert(ctx, Row{"item": "mug"}); err != nil || id != 4 {
+		t.Errorf("Insert() after commit = %d, %v, want 4", id, err)
+	}
+}
+
+func TestMemoryEngineConcurrency(t *testing.T) {
+	dataDir := t.TempDir()
+	engine, err := CreateEngine(config.StorageConfig{Engine: "memory", DataDir: dataDir})
+	if err != nil {
+		t.Fatalf("CreateEngine() error = %v", err)
+	}
+	ctx := context.Background()
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer engine.Shutdown(ctx)
+
+	if err := engine.CreateTable("events", TableSchema{}); err != nil {
+		t.Fatalf("CreateTable() error = %v", err)
+	}
+	if err := engine.CreateTensor("counters", TensorSchema{Shape: []int{8}, DType: "float32", ChunkSize: []int{1}}); err != nil {
+		t.Fatalf("CreateTensor() error = %v", err)
+	}
+
+	const workers, perWorker = 8, 20
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			table, err := engine.GetTable("events")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			tensor, err := engine.GetTensor("counters")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			for i := 0; i < perWorker; i++ {
+				if _, err := table.Insert(ctx, Row{"worker": w, "seq": i}); err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := table.Count(ctx, NewSimpleCondition("worker", "=", w)); err != nil {
+					t.Error(err)
+					return
+				}
+				if err := tensor.StoreChunk(ctx, []int{w}, float32SliceToBytes([]float32{float32(i)})); err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := tensor.ApplyOperation(ctx, Operation{Type: OperationTypeSum}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	table, err := engine.GetTable("events")
+	if err != nil {
+		t.Fatalf("GetTable() error = %v", err)
+	}
+	if count, err := table.Count(ctx, nil); err != nil || count != workers*perWorker {
+		t.Errorf("Count() = %d, %v, want %d", count, err, workers*perWorker)
+	}
+
+	// Nothing was written to the configured data directory
+	entries, err := os.ReadDir(dataDir)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("memory engine wrote %d files to the data directory", len(entries))
+	}
 }
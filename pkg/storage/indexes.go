@@ -0,0 +1,325 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// IndexManager is implemented by engines that support secondary indexes on
+// tables. Indexes are kept up to date on every write and used by Select and
+// Count for equality and range conditions on their leading columns.
+type IndexManager interface {
+	CreateIndex(table string, index IndexDefinition) error
+	DropIndex(table, name string) error
+}
+
+// IndexTypeBTree is the only index type, and the default
+const IndexTypeBTree = "btree"
+
+// Index names in SQLite are prefixed to keep them apart from the catalog's
+const (
+	sqliteIndexPrefix      = "telumdb_idx_"
+	sqlitePrimaryKeyPrefix = "telumdb_pkey_"
+)
+
+// normalizeIndex validates an index definition against the table schema and
+// fills in its name and type when left empty
+func normalizeIndex(table string, schema TableSchema, index IndexDefinition) (IndexDefinition, error) {
+	if len(index.Columns) == 0 {
+		return index, fmt.Errorf("index on %s has no columns", table)
+	}
+	if index.Name == "" {
+		index.Name = table + "_" + strings.Join(index.Columns, "_") + "_idx"
+	}
+	if err := validateObjectName(index.Name); err != nil {
+		return index, fmt.Errorf("index: %w", err)
+	}
+
+	switch strings.ToLower(index.Type) {
+	case "", IndexTypeBTree:
+		index.Type = IndexTypeBTree
+	default:
+		return index, fmt.Errorf("index %s: unsupported index type %q", index.Name, index.Type)
+	}
+
+	columns := make(map[string]bool, len(schema.Columns))
+	for _, col := range schema.Columns {
+		columns[col.Name] = true
+	}
+	for _, col := range index.Columns {
+		if _, err := jsonPath(col); err != nil {
+			return index, fmt.Errorf("index %s: %w", index.Name, err)
+		}
+		if len(columns) > 0 && !columns[col] {
+			return index, &ColumnError{Table: table, Column: col, Err: ErrUnknownColumn}
+		}
+	}
+
+	return index, nil
+}
+
+// indexDDL returns the statement creating the SQLite index over table_data
+// that backs an index of table. It is a partial index over the rows of the
+// table only, so writes to other tables neither maintain it nor add entries
+// to it. Leading with table_name still lets the planner match the equality
+// on it that every query has; non-unique indexes end with row_id so that
+// they also deliver rows in the order Select sorts them.
+func indexDDL(sqliteName, table string, columns []string, unique bool) (string, error) {
+	exprs := []string{"table_name"}
+	for _, col := range columns {
+		expr, err := columnExpr(col)
+		if err != nil {
+			return "", err
+		}
+		exprs = append(exprs, expr)
+	}
+	if !unique {
+		exprs = append(exprs, "row_id")
+	}
+
+	stmt := "CREATE INDEX "
+	if unique {
+		stmt = "CREATE UNIQUE INDEX "
+	}
+	return stmt + `"` + sqliteName + `" ON table_data (` + strings.Join(exprs, ", ") + ") WHERE " + tableRows(table), nil
+}
+
+// tableRows returns the SQL term selecting the rows of table from
+// table_data. The name is written as a literal rather than bound: SQLite only
+// uses a partial index when the query's WHERE clause contains the index's
+// term as written, which a parameter never matches.
+func tableRows(table string) string {
+	return "table_name = '" + strings.ReplaceAll(table, "'", "''") + "'"
+}
+
+// createIndex builds an index over the rows of table and records it in the
+// catalog
+func createIndex(db execer, table string, schema TableSchema, index IndexDefinition) error {
+	index, err := normalizeIndex(table, schema, index)
+	if err != nil {
+		return err
+	}
+
+	var exists int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM indexes WHERE name = ?`, index.Name).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check index: %w", sqliteError("catalog", err))
+	}
+	if exists > 0 {
+		return fmt.Errorf("index %s %w", index.Name, ErrAlreadyExists)
+	}
+
+	columnsJSON, err := json.Marshal(index.Columns)
+	if err != nil {
+		return fmt.Errorf("failed to serialize index columns: %w", err)
+	}
+	_, err = db.Exec(
+		`INSERT INTO indexes (name, table_name, columns, type, is_unique) VALUES (?, ?, ?, ?, ?)`,
+		index.Name, table, string(columnsJSON), index.Type, index.Unique,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create index: %w", sqliteError("catalog", err))
+	}
+
+	ddl, err := indexDDL(sqliteIndexPrefix+index.Name, table, index.Columns, index.Unique)
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec(ddl); err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("index %s: table %s has duplicate values: %w", index.Name, table, ErrAlreadyExists)
+		}
+		return fmt.Errorf("failed to build index %s: %w", index.Name, sqliteError("catalog", err))
+	}
+
+	return nil
+}
+
+// createPrimaryKeyIndex enforces a primary key that isn't the row ID with a
+// unique index
+func createPrimaryKeyIndex(db execer, table string, schema TableSchema) error {
+	pk, ok := schema.primaryKey()
+	if !ok || isIntegerType(pk.Type) {
+		return nil
+	}
+
+	ddl, err := indexDDL(sqlitePrimaryKeyPrefix+table, table, []string{pk.Name}, true)
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec(ddl); err != nil {
+		return fmt.Errorf("failed to build primary key index: %w", sqliteError("catalog", err))
+	}
+	return nil
+}
+
+// dropIndex removes an index of table
+func dropIndex(db execer, table, name string) error {
+	res, err := db.Exec(`DELETE FROM indexes WHERE name = ? AND table_name = ?`, name, table)
+	if err != nil {
+		return fmt.Errorf("failed to delete index: %w", sqliteError("catalog", err))
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("index %s on table %s %w", name, table, ErrNotFound)
+	}
+
+	if _, err := db.Exec(`DROP INDEX IF EXISTS "` + sqliteIndexPrefix + name + `"`); err != nil {
+		return fmt.Errorf("failed to drop index %s: %w", name, sqliteError("catalog", err))
+	}
+	return nil
+}
+
+// dropTableIndexes removes every index of table, including the primary key's
+func dropTableIndexes(db execer, table string) error {
+	indexes, err := loadIndexes(db, table)
+	if err != nil {
+		return err
+	}
+	for _, index := range indexes {
+		if err := dropIndex(db, table, index.Name); err != nil {
+			return err
+		}
+	}
+
+	if _, err := db.Exec(`DROP INDEX IF EXISTS "` + sqlitePrimaryKeyPrefix + table + `"`); err != nil {
+		return fmt.Errorf("failed to drop primary key index: %w", sqliteError("catalog", err))
+	}
+	return nil
+}
+
+// loadIndexes returns the indexes of table in the order they were created
+func loadIndexes(db execer, table string) ([]IndexDefinition, error) {
+	rows, err := db.Query(
+		`SELECT name, columns, type, is_unique FROM indexes WHERE table_name = ? ORDER BY rowid`,
+		table,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexes: %w", sqliteError("catalog", err))
+	}
+	defer rows.Close()
+
+	var indexes []IndexDefinition
+	for rows.Next() {
+		var index IndexDefinition
+		var columnsJSON string
+		if err := rows.Scan(&index.Name, &columnsJSON, &index.Type, &index.Unique); err != nil {
+			return nil, fmt.Errorf("failed to scan index: %w", err)
+		}
+		if err := json.Unmarshal([]byte(columnsJSON), &index.Columns); err != nil {
+			return nil, fmt.Errorf("failed to parse index columns: %w", err)
+		}
+		indexes = append(indexes, index)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list indexes: %w", sqliteError("catalog", err))
+	}
+
+	return indexes, nil
+}
+
+// isUniqueViolation reports whether err is a unique index violation
+func isUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
+
+// uniqueViolation converts a write rejected by a unique index into an error
+// wrapping ErrAlreadyExists that names the index, or returns nil for any
+// other error
+func uniqueViolation(table string, err error) error {
+	if !isUniqueViolation(err) {
+		return nil
+	}
+
+	// SQLite reports "UNIQUE constraint failed: index 'name'"
+	msg := err.Error()
+	name := ""
+	if i := strings.Index(msg, "index '"); i >= 0 {
+		name = msg[i+len("index '"):]
+		if j := strings.IndexByte(name, '\''); j >= 0 {
+			name = name[:j]
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(name, sqlitePrimaryKeyPrefix):
+		return fmt.Errorf("duplicate primary key in table %s: %w", table, ErrAlreadyExists)
+	case strings.HasPrefix(name, sqliteIndexPrefix):
+		return fmt.Errorf("duplicate value for unique index %s: %w", strings.TrimPrefix(name, sqliteIndexPrefix), ErrAlreadyExists)
+	}
+	return fmt.Errorf("duplicate value in table %s: %w", table, ErrAlreadyExists)
+}
+
+// CreateIndex builds an index on an existing table
+func (e *engineImpl) CreateIndex(table string, index IndexDefinition) error {
+	if !e.started {
+		return fmt.Errorf("engine not started")
+	}
+
+	tx, err := e.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", sqliteError("transaction", err))
+	}
+	defer tx.Rollback()
+
+	schema, err := loadTableSchema(tx, table)
+	if err != nil {
+		return err
+	}
+	if err := createIndex(tx, table, schema, index); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DropIndex removes an index from a table
+func (e *engineImpl) DropIndex(table, name string) error {
+	if !e.started {
+		return fmt.Errorf("engine not started")
+	}
+
+	tx, err := e.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", sqliteError("transaction", err))
+	}
+	defer tx.Rollback()
+
+	if _, err := loadTableSchema(tx, table); err != nil {
+		return err
+	}
+	if err := dropIndex(tx, table, name); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// loadTableSchema reads a table's schema from the catalog, with the indexes
+// currently defined on it
+func loadTableSchema(db execer, name string) (TableSchema, error) {
+	var schemaJSON string
+	err := db.QueryRow(`SELECT schema FROM tables WHERE name = ?`, name).Scan(&schemaJSON)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return TableSchema{}, fmt.Errorf("table %s %w", name, ErrNotFound)
+		}
+		return TableSchema{}, fmt.Errorf("failed to get table: %w", sqliteError("catalog", err))
+	}
+
+	var schema TableSchema
+	if err := json.Unmarshal([]byte(schemaJSON), &schema); err != nil {
+		return TableSchema{}, fmt.Errorf("failed to deserialize schema: %w", err)
+	}
+
+	schema.Indexes, err = loadIndexes(db, name)
+	if err != nil {
+		return TableSchema{}, err
+	}
+	return schema, nil
+}
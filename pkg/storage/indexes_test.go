@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTableIndexes(t *testing.T) {
+	ctx := context.Background()
+	schema := TableSchema{
+		Columns: []ColumnDefinition{
+			{Name: "email", Type: "TEXT"},
+			{Name: "age", Type: "INTEGER", Nullable: true},
+			{Name: "city", Type: "TEXT", Nullable: true},
+		},
+		Indexes: []IndexDefinition{
+			{Name: "people_email", Columns: []string{"email"}, Unique: true},
+			{Columns: []string{"age"}},
+		},
+	}
+
+	for kind, table := range newTestTablesWithSchema(t, schema) {
+		t.Run(kind, func(t *testing.T) {
+			indexes := table.Schema().Indexes
+			if len(indexes) != 2 || indexes[0].Name != "people_email" || indexes[1].Name != "people_age_idx" || indexes[1].Type != IndexTypeBTree {
+				t.Errorf("Schema().Indexes = %+v", indexes)
+			}
+
+			if _, err := table.Insert(ctx, Row{"email": "a@example.com", "age": 30}); err != nil {
+				t.Fatalf("Insert() error = %v", err)
+			}
+			if _, err := table.Insert(ctx, Row{"email": "b@example.com", "age": 30}); err != nil {
+				t.Fatalf("Insert() error = %v", err)
+			}
+
+			_, err := table.Insert(ctx, Row{"email": "a@example.com"})
+			if !errors.Is(err, ErrAlreadyExists) || !strings.Contains(err.Error(), "people_email") {
+				t.Errorf("Insert() with duplicate email error = %v, want ErrAlreadyExists naming the index", err)
+			}
+			_, err = table.Update(ctx, Row{"email": "a@example.com", "age": 31}, NewSimpleCondition("email", "=", "b@example.com"))
+			if !errors.Is(err, ErrAlreadyExists) {
+				t.Errorf("Update() to a duplicate email error = %v, want ErrAlreadyExists", err)
+			}
+
+			// Deleted rows leave the index
+			if _, err := table.Delete(ctx, NewSimpleCondition("email", "=", "a@example.com")); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if _, err := table.Insert(ctx, Row{"email": "a@example.com", "age": 25}); err != nil {
+				t.Errorf("Insert() after Delete() error = %v", err)
+			}
+			if count, _ := table.Count(ctx, NewSimpleCondition("age", "<", 30)); count != 1 {
+				t.Errorf("Count(age < 30) = %d, want 1", count)
+			}
+		})
+	}
+}
+
+func TestCreateAndDropIndex(t *testing.T) {
+	engine := newMemoryEngine(t)
+	ctx := context.Background()
+	indexes := engine.(IndexManager)
+
+	if err := engine.CreateTable("people", TableSchema{}); err != nil {
+		t.Fatalf("CreateTable() error = %v", err)
+	}
+	table, _ := engine.GetTable("people")
+	for _, city := range []string{"Paris", "Paris", "Berlin"} {
+		if _, err := table.Insert(ctx, Row{"city": city}); err != nil {
+			t.Fatalf("Insert() error = %v", err)
+		}
+	}
+
+	if err := indexes.CreateIndex("people", IndexDefinition{Name: "people_city", Columns: []string{"city"}, Unique: true}); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("CreateIndex() unique over duplicates error = %v, want ErrAlreadyExists", err)
+	}
+	if err := indexes.CreateIndex("people", IndexDefinition{Name: "people_city", Columns: []string{"city"}}); err != nil {
+		t.Fatalf("CreateIndex() error = %v", err)
+	}
+	if err := indexes.CreateIndex("people", IndexDefinition{Name: "people_city", Columns: []string{"city"}}); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("CreateIndex() twice error = %v, want ErrAlreadyExists", err)
+	}
+	if err := indexes.CreateIndex("missing", IndexDefinition{Columns: []string{"city"}}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("CreateIndex() on a missing table error = %v, want ErrNotFound", err)
+	}
+	if err := indexes.CreateIndex("people", IndexDefinition{Columns: []string{"city"}, Type: "hnsw"}); err == nil {
+		t.Error("CreateIndex() with an unsupported type should fail")
+	}
+
+	table, _ = engine.GetTable("people")
+	if got := table.Schema().Indexes; len(got) != 1 || got[0].Name != "people_city" {
+		t.Errorf("Schema().Indexes = %+v, want people_city", got)
+	}
+
+	if err := indexes.DropIndex("people", "people_city"); err != nil {
+		t.Fatalf("DropIndex() error = %v", err)
+	}
+	if err := indexes.DropIndex("people", "people_city"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DropIndex() twice error = %v, want ErrNotFound", err)
+	}
+
+	// Dropping a table removes its SQLite indexes too
+	if err := indexes.CreateIndex("people", IndexDefinition{Name: "people_city", Columns: []string{"city"}}); err != nil {
+		t.Fatalf("CreateIndex() error = %v", err)
+	}
+	if err := engine.DropTable("people"); err != nil {
+		t.Fatalf("DropTable() error = %v", err)
+	}
+	var remaining int
+	db := engine.(*MemoryEngine).db
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name LIKE 'telumdb%'`).Scan(&remaining); err != nil {
+		t.Fatalf("QueryRow() error = %v", err)
+	}
+	if remaining != 0 {
+		t.Errorf("%d SQLite indexes left after DropTable()", remaining)
+	}
+}
+
+func TestIndexQueryPlan(t *testing.T) {
+	engine := newMemoryEngine(t)
+	schema := TableSchema{Indexes: []IndexDefinition{
+		{Name: "people_age", Columns: []string{"age"}},
+		{Name: "people_full_name", Columns: []string{"full name"}},
+	}}
+	if err := engine.CreateTable("people", schema); err != nil {
+		t.Fatalf("CreateTable() error = %v", err)
+	}
+	// Indexes of another table on the same column must not be picked
+	pets := TableSchema{Indexes: []IndexDefinition{{Name: "pets_age", Columns: []string{"age"}}}}
+	if err := engine.CreateTable("pets", pets); err != nil {
+		t.Fatalf("CreateTable() error = %v", err)
+	}
+	db := engine.(*MemoryEngine).db
+
+	tests := []struct {
+		table     string
+		condition Condition
+		index     string
+	}{
+		{"people", NewSimpleCondition("age", "=", 30), "people_age"},
+		{"people", NewSimpleCondition("age", ">", 30), "people_age"},
+		{"people", NewBetweenCondition("age", 20, 30), "people_age"},
+		{"people", NewSimpleCondition("full name", "=", "Alice Smith"), "people_full_name"},
+		{"pets", NewSimpleCondition("age", "=", 3), "pets_age"},
+		{"pets", NewSimpleCondition("age", "<", 3), "pets_age"},
+	}
+
+	for _, tt := range tests {
+		where, args, err := compileCondition(tt.condition, TableSchema{})
+		if err != nil {
+			t.Fatalf("compileCondition() error = %v", err)
+		}
+		rows, err := db.Query(
+			`EXPLAIN QUERY PLAN SELECT COUNT(*) FROM table_data WHERE `+tableRows(tt.table)+` AND (`+where+`)`,
+			args...,
+		)
+		if err != nil {
+			t.Fatalf("EXPLAIN error = %v", err)
+		}
+
+		var plan []string
+		for rows.Next() {
+			var id, parent, unused int
+			var detail string
+			if err := rows.Scan(&id, &parent, &unused, &detail); err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+			plan = append(plan, detail)
+		}
+		rows.Close()
+
+		if got := strings.Join(plan, "; "); !strings.Contains(got, sqliteIndexPrefix+tt.index+" ") {
+			t.Errorf("plan for %s %v = %q, want index %s", tt.table, tt.condition, got, tt.index)
+		}
+	}
+
+	// Each index only holds the rows of its own table
+	var partial int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM pragma_index_list('table_data') WHERE name LIKE 'telumdb_idx_%' AND partial = 1`).Scan(&partial); err != nil || partial != 3 {
+		t.Errorf("partial table indexes = %d, %v, want 3", partial, err)
+	}
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/telumdb/telumdb/internal/metrics"
+)
+
+// Storage metrics, exposed through the server's metrics endpoint
+var (
+	tensorMemoryBytes = metrics.Default.NewGauge("tensor_memory_bytes",
+		"Bytes of tensor data currently held in memory.").With()
+	chunkReads = metrics.Default.NewCounter("chunk_reads_total",
+		"Number of tensor chunks read.").With()
+	chunkWrites = metrics.Default.NewCounter("chunk_writes_total",
+		"Number of tensor chunks written.").With()
+	chunkCacheHits = metrics.Default.NewCounter("chunk_cache_hits_total",
+		"Number of tensor chunk reads served from the chunk cache.").With()
+	chunkCacheMisses = metrics.Default.NewCounter("chunk_cache_misses_total",
+		"Number of tensor chunk reads that went to the chunk files.").With()
+	chunkCacheEvictions = metrics.Default.NewCounter("chunk_cache_evictions_total",
+		"Number of tensor chunks evicted from the chunk cache.").With()
+	sqliteErrors = metrics.Default.NewCounter("sqlite_errors_total",
+		"Number of errors returned by SQLite, by operation.", "operation")
+)
+
+// sqliteError counts err as a failed SQLite operation and returns it unchanged.
+// Missing rows and cancelled contexts are not counted as errors.
+func sqliteError(operation string, err error) error {
+	if err == nil || errors.Is(err, sql.ErrNoRows) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	sqliteErrors.With(operation).Inc()
+	return err
+}
+
+// tensorBytes returns the in-memory size of a tensor's data. Chunks of
+// tensors backed by files are counted by the chunk cache.
+func tensorBytes(t *tensorImpl) float64 {
+	return float64(len(t.data) * 4)
+}
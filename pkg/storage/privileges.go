@@ -0,0 +1,231 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrPermissionDenied is returned when a user lacks the privilege for an operation
+var ErrPermissionDenied = errors.New("permission denied")
+
+// Object types that privileges can be granted on
+const (
+	ObjectTypeTable  = "table"
+	ObjectTypeTensor = "tensor"
+)
+
+// Privilege constants
+const (
+	PrivilegeSelect = "SELECT"
+	PrivilegeInsert = "INSERT"
+	PrivilegeUpdate = "UPDATE"
+	PrivilegeDelete = "DELETE"
+	PrivilegeRead   = "READ"
+	PrivilegeWrite  = "WRITE"
+	PrivilegeDDL    = "DDL"
+	PrivilegeAll    = "ALL"
+)
+
+// objectPrivileges lists the privileges that apply to each object type
+var objectPrivileges = map[string][]string{
+	ObjectTypeTable:  {PrivilegeSelect, PrivilegeInsert, PrivilegeUpdate, PrivilegeDelete, PrivilegeDDL},
+	ObjectTypeTensor: {PrivilegeRead, PrivilegeWrite, PrivilegeDDL},
+}
+
+// Grant represents a single privilege held by a user on an object
+type Grant struct {
+	Grantee    string
+	ObjectType string
+	ObjectName string
+	Privilege  string
+}
+
+// PrivilegeStore is implemented by engines that persist access grants
+type PrivilegeStore interface {
+	Grant(grantee, objectType, objectName string, privileges []string) error
+	Revoke(grantee, objectType, objectName string, privileges []string) error
+	HasPrivilege(grantee, objectType, objectName, privilege string) (bool, error)
+	ListGrants(grantee string) ([]Grant, error)
+}
+
+// PermissionError describes a denied operation
+type PermissionError struct {
+	User       string
+	Privilege  string
+	ObjectType string
+	ObjectName string
+}
+
+// Error implements the error interface
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf("permission denied: user %s lacks %s on %s %s", e.User, e.Privilege, e.ObjectType, e.ObjectName)
+}
+
+// Unwrap allows errors.Is(err, ErrPermissionDenied)
+func (e *PermissionError) Unwrap() error {
+	return ErrPermissionDenied
+}
+
+// normalizePrivileges upper-cases privilege names, expands ALL and checks
+// that every privilege applies to the object type
+func normalizePrivileges(objectType string, privileges []string) ([]string, error) {
+	valid, ok := objectPrivileges[objectType]
+	if !ok {
+		return nil, fmt.Errorf("invalid object type: %s", objectType)
+	}
+	if len(privileges) == 0 {
+		return nil, fmt.Errorf("no privileges specified")
+	}
+
+	seen := make(map[string]bool)
+	var result []string
+	for _, p := range privileges {
+		p = strings.ToUpper(strings.TrimSpace(p))
+		if p == PrivilegeAll {
+			for _, v := range valid {
+				if !seen[v] {
+					seen[v] = true
+					result = append(result, v)
+				}
+			}
+			continue
+		}
+
+		allowed := false
+		for _, v := range valid {
+			if v == p {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return nil, fmt.Errorf("privilege %s does not apply to %s objects", p, objectType)
+		}
+
+		if !seen[p] {
+			seen[p] = true
+			result = append(result, p)
+		}
+	}
+
+	return result, nil
+}
+
+// Grant gives a user privileges on a table or tensor
+func (e *engineImpl) Grant(grantee, objectType, objectName string, privileges []string) error {
+	if !e.started {
+		return fmt.Errorf("engine not started")
+	}
+
+	privs, err := normalizePrivileges(objectType, privileges)
+	if err != nil {
+		return err
+	}
+
+	var exists int
+	if err := e.db.QueryRow(`SELECT COUNT(*) FROM users WHERE name = ?`, grantee).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check user: %w", err)
+	}
+	if exists == 0 {
+		return fmt.Errorf("user not found: %s", grantee)
+	}
+
+	tx, err := e.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, p := range privs {
+		_, err := tx.Exec(
+			`INSERT OR IGNORE INTO privileges (grantee, object_type, object_name, privilege) VALUES (?, ?, ?, ?)`,
+			grantee, objectType, objectName, p,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to grant privilege: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Revoke removes a user's privileges on a table or tensor
+func (e *engineImpl) Revoke(grantee, objectType, objectName string, privileges []string) error {
+	if !e.started {
+		return fmt.Errorf("engine not started")
+	}
+
+	privs, err := normalizePrivileges(objectType, privileges)
+	if err != nil {
+		return err
+	}
+
+	tx, err := e.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, p := range privs {
+		_, err := tx.Exec(
+			`DELETE FROM privileges WHERE grantee = ? AND object_type = ? AND object_name = ? AND privilege = ?`,
+			grantee, objectType, objectName, p,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to revoke privilege: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// HasPrivilege reports whether a user holds a privilege on an object
+func (e *engineImpl) HasPrivilege(grantee, objectType, objectName, privilege string) (bool, error) {
+	if !e.started {
+		return false, fmt.Errorf("engine not started")
+	}
+
+	var count int
+	err := e.db.QueryRow(
+		`SELECT COUNT(*) FROM privileges WHERE grantee = ? AND object_type = ? AND object_name = ? AND privilege = ?`,
+		grantee, objectType, objectName, strings.ToUpper(privilege),
+	).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check privilege: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+// ListGrants returns the privileges held by a user, or by all users if grantee is empty
+func (e *engineImpl) ListGrants(grantee string) ([]Grant, error) {
+	if !e.started {
+		return nil, fmt.Errorf("engine not started")
+	}
+
+	query := `SELECT grantee, object_type, object_name, privilege FROM privileges`
+	var args []interface{}
+	if grantee != "" {
+		query += ` WHERE grantee = ?`
+		args = append(args, grantee)
+	}
+	query += ` ORDER BY grantee, object_type, object_name, privilege`
+
+	rows, err := e.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list grants: %w", err)
+	}
+	defer rows.Close()
+
+	var grants []Grant
+	for rows.Next() {
+		var g Grant
+		if err := rows.Scan(&g.Grantee, &g.ObjectType, &g.ObjectName, &g.Privilege); err != nil {
+			return nil, fmt.Errorf("failed to scan grant: %w", err)
+		}
+		grants = append(grants, g)
+	}
+
+	return grants, rows.Err()
+}
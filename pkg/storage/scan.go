@@ -0,0 +1,285 @@
+package storage
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	bytesType   = reflect.TypeOf([]byte(nil))
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// decodeRow parses the stored JSON of a row, keeping numbers exact
+func decodeRow(data []byte) (Row, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var row Row
+	if err := dec.Decode(&row); err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+// decodeValue converts a stored value to the Go value of the declared column
+// type: int64, float64, string, bool, time.Time or []byte. Values of untyped
+// columns keep their JSON shape, with integral numbers as int64.
+func decodeValue(declared string, value interface{}) (interface{}, error) {
+	t, err := columnType(declared)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := value.(type) {
+	case json.Number:
+		if t != ColumnTypeReal {
+			if i, err := v.Int64(); err == nil {
+				return i, nil
+			}
+		}
+		return v.Float64()
+	case string:
+		switch t {
+		case ColumnTypeTimestamp:
+			return time.Parse(time.RFC3339Nano, v)
+		case ColumnTypeBlob:
+			return base64.StdEncoding.DecodeString(v)
+		}
+	case []interface{}:
+		decoded := make([]interface{}, len(v))
+		for i, elem := range v {
+			if decoded[i], err = decodeValue("", elem); err != nil {
+				return nil, err
+			}
+		}
+		return decoded, nil
+	case map[string]interface{}:
+		decoded := make(map[string]interface{}, len(v))
+		for k, elem := range v {
+			if decoded[k], err = decodeValue("", elem); err != nil {
+				return nil, err
+			}
+		}
+		return decoded, nil
+	}
+	return value, nil
+}
+
+// assignValue stores a decoded column value in dest, which is a pointer or a
+// sql.Scanner, failing with ErrTypeMismatch when the value doesn't fit
+func assignValue(dest, value interface{}) error {
+	if scanner, ok := dest.(sql.Scanner); ok {
+		return scanner.Scan(driverValue(value))
+	}
+
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("destination must be a non-nil pointer, not %T", dest)
+	}
+	return assignReflect(v.Elem(), value)
+}
+
+// assignReflect stores a decoded column value in v
+func assignReflect(v reflect.Value, value interface{}) error {
+	if v.CanAddr() && v.Addr().Type().Implements(scannerType) {
+		return v.Addr().Interface().(sql.Scanner).Scan(driverValue(value))
+	}
+	if v.Kind() == reflect.Interface && v.NumMethod() == 0 {
+		if value == nil {
+			v.Set(reflect.Zero(v.Type()))
+		} else {
+			v.Set(reflect.ValueOf(value))
+		}
+		return nil
+	}
+
+	if value == nil {
+		switch v.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map:
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		return fmt.Errorf("%w: cannot scan NULL into %s", ErrTypeMismatch, v.Type())
+	}
+	if v.Kind() == reflect.Ptr {
+		elem := reflect.New(v.Type().Elem())
+		if err := assignReflect(elem.Elem(), value); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+
+	switch v.Type() {
+	case timeType:
+		switch t := value.(type) {
+		case time.Time:
+			v.Set(reflect.ValueOf(t))
+			return nil
+		case string:
+			if ts, ok := coerceTimestamp(t); ok {
+				parsed, _ := time.Parse(time.RFC3339Nano, ts)
+				v.Set(reflect.ValueOf(parsed))
+				return nil
+			}
+		}
+		return mismatch(v, value)
+	case bytesType:
+		switch b := value.(type) {
+		case []byte:
+			v.SetBytes(append([]byte(nil), b...))
+			return nil
+		case string:
+			v.SetBytes([]byte(b))
+			return nil
+		}
+		return mismatch(v, value)
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		switch s := value.(type) {
+		case string:
+			v.SetString(s)
+		case int64:
+			v.SetString(strconv.FormatInt(s, 10))
+		case float64:
+			v.SetString(strconv.FormatFloat(s, 'g', -1, 64))
+		case bool:
+			v.SetString(strconv.FormatBool(s))
+		case time.Time:
+			v.SetString(formatTimestamp(s))
+		default:
+			return mismatch(v, value)
+		}
+		return nil
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			return mismatch(v, value)
+		}
+		v.SetBool(b)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := scanInteger(value)
+		if !ok || v.OverflowInt(i) {
+			return mismatch(v, value)
+		}
+		v.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, ok := scanInteger(value)
+		if !ok || i < 0 || v.OverflowUint(uint64(i)) {
+			return mismatch(v, value)
+		}
+		v.SetUint(uint64(i))
+		return nil
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch n := value.(type) {
+		case float64:
+			f = n
+		case int64:
+			f = float64(n)
+		default:
+			return mismatch(v, value)
+		}
+		if v.OverflowFloat(f) {
+			return mismatch(v, value)
+		}
+		v.SetFloat(f)
+		return nil
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		// Nested JSON values decode into matching Go structures
+		data, err := json.Marshal(value)
+		if err != nil {
+			return mismatch(v, value)
+		}
+		if err := json.Unmarshal(data, v.Addr().Interface()); err != nil {
+			return mismatch(v, value)
+		}
+		return nil
+	}
+	return mismatch(v, value)
+}
+
+// scanInteger accepts int64 values and integral float64 values
+func scanInteger(value interface{}) (int64, bool) {
+	switch n := value.(type) {
+	case int64:
+		return n, true
+	case float64:
+		return toInt64(n)
+	}
+	return 0, false
+}
+
+// mismatch reports a value that can't be stored in v
+func mismatch(v reflect.Value, value interface{}) error {
+	return fmt.Errorf("%w: cannot scan %T value %v into %s", ErrTypeMismatch, value, value, v.Type())
+}
+
+// driverValue converts a decoded column value to one of the types passed to
+// sql.Scanner implementations such as sql.NullString
+func driverValue(value interface{}) driver.Value {
+	switch v := value.(type) {
+	case nil, int64, float64, bool, string, []byte, time.Time:
+		return v
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return data
+}
+
+// structField is a struct field filled by ScanStruct
+type structField struct {
+	column string
+	index  []int
+}
+
+// structFields lists the fields of a struct type with the column each is
+// scanned from: the telumdb tag, or the field name when untagged. Fields
+// tagged "-" are skipped and embedded structs are flattened.
+func structFields(t reflect.Type) []structField {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, tagged := f.Tag.Lookup("telumdb")
+		if tag == "-" {
+			continue
+		}
+		if name, _, _ := strings.Cut(tag, ","); name != "" {
+			tag = name
+		} else {
+			tagged = false
+		}
+
+		if f.Anonymous && !tagged && f.Type.Kind() == reflect.Struct {
+			for _, inner := range structFields(f.Type) {
+				inner.index = append([]int{i}, inner.index...)
+				fields = append(fields, inner)
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if !tagged {
+			tag = f.Name
+		}
+		fields = append(fields, structField{column: tag, index: []int{i}})
+	}
+	return fields
+}
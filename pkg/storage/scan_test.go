@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAssignValue(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		dest    interface{}
+		value   interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{"string", new(string), "alice", "alice", false},
+		{"int into string", new(string), int64(7), "7", false},
+		{"int", new(int), int64(42), 42, false},
+		{"integral float into int", new(int64), float64(42), int64(42), false},
+		{"fraction into int", new(int), 4.5, nil, true},
+		{"overflow", new(int8), int64(300), nil, true},
+		{"negative into uint", new(uint), int64(-1), nil, true},
+		{"string into int", new(int), "42", nil, true},
+		{"float", new(float64), 2.5, 2.5, false},
+		{"int into float", new(float32), int64(2), float32(2), false},
+		{"bool", new(bool), true, true, false},
+		{"int into bool", new(bool), int64(1), nil, true},
+		{"time", new(time.Time), ts, ts, false},
+		{"timestamp text into time", new(time.Time), "2024-03-01T12:30:00Z", ts, false},
+		{"bad time", new(time.Time), "soon", nil, true},
+		{"bytes", new([]byte), []byte("hi"), []byte("hi"), false},
+		{"NULL into string", new(string), nil, nil, true},
+		{"NULL into pointer", new(*string), nil, (*string)(nil), false},
+		{"pointer", new(*int64), int64(5), func() *int64 { i := int64(5); return &i }(), false},
+		{"interface", new(interface{}), int64(5), int64(5), false},
+		{"slice", new([]string), []interface{}{"a", "b"}, []string{"a", "b"}, false},
+		{"NullString", new(sql.NullString), "x", sql.NullString{String: "x", Valid: true}, false},
+		{"NULL NullString", new(sql.NullString), nil, sql.NullString{}, false},
+		{"NullInt64", new(sql.NullInt64), int64(3), sql.NullInt64{Int64: 3, Valid: true}, false},
+		{"NullFloat64", new(sql.NullFloat64), 1.5, sql.NullFloat64{Float64: 1.5, Valid: true}, false},
+		{"NullBool", new(sql.NullBool), true, sql.NullBool{Bool: true, Valid: true}, false},
+		{"NullTime", new(sql.NullTime), ts, sql.NullTime{Time: ts, Valid: true}, false},
+		{"not a pointer", "x", "x", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assignValue(tt.dest, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("assignValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := reflect.ValueOf(tt.dest).Elem().Interface(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("assignValue() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIteratorScan(t *testing.T) {
+	ctx := context.Background()
+	schema := TableSchema{Columns: []ColumnDefinition{
+		{Name: "id", Type: "INTEGER", PrimaryKey: true},
+		{Name: "name", Type: "TEXT"},
+		{Name: "score", Type: "REAL", Nullable: true},
+		{Name: "active", Type: "BOOLEAN"},
+		{Name: "joined", Type: "TIMESTAMP"},
+		{Name: "avatar", Type: "BLOB", Nullable: true},
+	}}
+	joined := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	type person struct {
+		ID     int64           `telumdb:"id"`
+		Name   string          `telumdb:"name"`
+		Score  sql.NullFloat64 `telumdb:"score"`
+		Active bool
+		Joined time.Time `telumdb:"joined"`
+		Avatar []byte    `telumdb:"avatar"`
+		Note   string    `telumdb:"-"`
+	}
+
+	for kind, table := range newTestTablesWithSchema(t, schema) {
+		t.Run(kind, func(t *testing.T) {
+			rows := []Row{
+				{"name": "alice", "score": 9.5, "active": true, "joined": joined, "avatar": []byte{0xff, 0}},
+				{"name": "bob", "active": false, "joined": joined},
+			}
+			for _, row := range rows {
+				if _, err := table.Insert(ctx, row); err != nil {
+					t.Fatalf("Insert() error = %v", err)
+				}
+			}
+
+			it, err := table.Select(ctx, nil, nil)
+			if err != nil {
+				t.Fatalf("Select() error = %v", err)
+			}
+			defer it.Close()
+
+			if got := it.Columns(); !reflect.DeepEqual(got, []string{"id", "name", "score", "active", "joined", "avatar"}) {
+				t.Errorf("Columns() = %v", got)
+			}
+			if got := it.ColumnTypes(); len(got) != 6 || got[4].Type != "TIMESTAMP" || !got[2].Nullable {
+				t.Errorf("ColumnTypes() = %+v", got)
+			}
+
+			var people []person
+			for it.Next() {
+				p := person{Note: "kept"}
+				if err := it.ScanStruct(&p); err != nil {
+					t.Fatalf("ScanStruct() error = %v", err)
+				}
+				people = append(people, p)
+			}
+			if err := it.Err(); err != nil {
+				t.Fatalf("Err() = %v", err)
+			}
+			want := []person{
+				{ID: 1, Name: "alice", Score: sql.NullFloat64{Float64: 9.5, Valid: true}, Active: true, Joined: joined, Avatar: []byte{0xff, 0}, Note: "kept"},
+				{ID: 2, Name: "bob", Active: false, Joined: joined, Note: "kept"},
+			}
+			if !reflect.DeepEqual(people, want) {
+				t.Errorf("ScanStruct() rows = %+v, want %+v", people, want)
+			}
+
+			// Positional scans convert to the destination or fail
+			it, err = table.Select(ctx, []string{"name", "score", "joined"}, NewSimpleCondition("name", "=", "bob"))
+			if err != nil {
+				t.Fatalf("Select() error = %v", err)
+			}
+			defer it.Close()
+			if !it.Next() {
+				t.Fatalf("Next() = false, err %v", it.Err())
+			}
+			var name string
+			var score sql.NullFloat64
+			var ts time.Time
+			if err := it.Scan(&name, &score, &ts); err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+			if name != "bob" || score.Valid || !ts.Equal(joined) {
+				t.Errorf("Scan() = %q, %v, %v", name, score, ts)
+			}
+			var bad float64
+			if err := it.Scan(&name, &bad, &ts); !errors.Is(err, ErrTypeMismatch) {
+				t.Errorf("Scan() of NULL into float64 error = %v, want ErrTypeMismatch", err)
+			}
+			var number int
+			if err := it.Scan(&number, &score, &ts); !errors.Is(err, ErrTypeMismatch) {
+				t.Errorf("Scan() of text into int error = %v, want ErrTypeMismatch", err)
+			}
+			if err := it.Scan(&name); err == nil {
+				t.Error("Scan() with too few destinations should fail")
+			}
+			it.Close()
+
+			if _, err := table.Select(ctx, []string{"nmae"}, nil); !errors.Is(err, ErrUnknownColumn) {
+				t.Errorf("Select() of an unknown column error = %v, want ErrUnknownColumn", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,402 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Column types understood by the engine. Common aliases such as BIGINT,
+// VARCHAR(n) or DATETIME map to one of these; a column without a type
+// accepts any value.
+const (
+	ColumnTypeInteger   = "INTEGER"
+	ColumnTypeReal      = "REAL"
+	ColumnTypeText      = "TEXT"
+	ColumnTypeBoolean   = "BOOLEAN"
+	ColumnTypeTimestamp = "TIMESTAMP"
+	ColumnTypeBlob      = "BLOB"
+)
+
+// columnTypeAliases maps declared type names to column types
+var columnTypeAliases = map[string]string{
+	"INTEGER":           ColumnTypeInteger,
+	"INT":               ColumnTypeInteger,
+	"BIGINT":            ColumnTypeInteger,
+	"SMALLINT":          ColumnTypeInteger,
+	"TINYINT":           ColumnTypeInteger,
+	"REAL":              ColumnTypeReal,
+	"FLOAT":             ColumnTypeReal,
+	"DOUBLE":            ColumnTypeReal,
+	"DOUBLE PRECISION":  ColumnTypeReal,
+	"NUMERIC":           ColumnTypeReal,
+	"DECIMAL":           ColumnTypeReal,
+	"TEXT":              ColumnTypeText,
+	"VARCHAR":           ColumnTypeText,
+	"CHAR":              ColumnTypeText,
+	"CHARACTER VARYING": ColumnTypeText,
+	"STRING":            ColumnTypeText,
+	"BOOLEAN":           ColumnTypeBoolean,
+	"BOOL":              ColumnTypeBoolean,
+	"TIMESTAMP":         ColumnTypeTimestamp,
+	"TIMESTAMPTZ":       ColumnTypeTimestamp,
+	"DATETIME":          ColumnTypeTimestamp,
+	"DATE":              ColumnTypeTimestamp,
+	"BLOB":              ColumnTypeBlob,
+	"BYTEA":             ColumnTypeBlob,
+	"BINARY":            ColumnTypeBlob,
+	"VARBINARY":         ColumnTypeBlob,
+}
+
+// Errors wrapped by ColumnError
+var (
+	ErrUnknownColumn = errors.New("unknown column")
+	ErrNotNull       = errors.New("value is required")
+	ErrTypeMismatch  = errors.New("type mismatch")
+)
+
+// ColumnError describes a row value rejected by the table schema
+type ColumnError struct {
+	Table  string
+	Column string
+	Err    error
+}
+
+// Error implements the error interface
+func (e *ColumnError) Error() string {
+	return fmt.Sprintf("table %s column %s: %v", e.Table, e.Column, e.Err)
+}
+
+// Unwrap allows errors.Is(err, ErrNotNull) and friends
+func (e *ColumnError) Unwrap() error {
+	return e.Err
+}
+
+// timestampFormat is how TIMESTAMP values are stored: fixed width and in UTC,
+// so comparing the text in SQL orders timestamps correctly
+const timestampFormat = "2006-01-02T15:04:05.000000000Z"
+
+// timestampLayouts are the text forms accepted for TIMESTAMP values
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// formatTimestamp renders t in the stored TIMESTAMP format
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(timestampFormat)
+}
+
+// columnType resolves a declared type name to a column type, or "" for an
+// untyped column
+func columnType(declared string) (string, error) {
+	name := strings.ToUpper(strings.TrimSpace(declared))
+	if i := strings.IndexByte(name, '('); i >= 0 && strings.HasSuffix(name, ")") {
+		name = strings.TrimSpace(name[:i])
+	}
+	if name == "" {
+		return "", nil
+	}
+
+	t, ok := columnTypeAliases[name]
+	if !ok {
+		return "", fmt.Errorf("unsupported column type %q", declared)
+	}
+	return t, nil
+}
+
+// isIntegerType reports whether a declared type is INTEGER or an alias of it
+func isIntegerType(declared string) bool {
+	t, _ := columnType(declared)
+	return t == ColumnTypeInteger
+}
+
+// primaryKey returns the schema's primary key column, if it declares one
+func (s TableSchema) primaryKey() (ColumnDefinition, bool) {
+	for _, col := range s.Columns {
+		if col.PrimaryKey {
+			return col, true
+		}
+	}
+	return ColumnDefinition{}, false
+}
+
+// column returns the definition of the named column
+func (s TableSchema) column(name string) (ColumnDefinition, bool) {
+	for _, col := range s.Columns {
+		if col.Name == name {
+			return col, true
+		}
+	}
+	return ColumnDefinition{}, false
+}
+
+// validateTableSchema checks that column names are usable and unique, that
+// types and defaults are valid and that at most one column is the primary key
+func validateTableSchema(schema TableSchema) error {
+	seen := make(map[string]bool, len(schema.Columns))
+	primaryKey := ""
+	for _, col := range schema.Columns {
+		if _, err := jsonPath(col.Name); err != nil {
+			return err
+		}
+		if seen[col.Name] {
+			return fmt.Errorf("duplicate column %s", col.Name)
+		}
+		seen[col.Name] = true
+
+		if _, err := columnType(col.Type); err != nil {
+			return fmt.Errorf("column %s: %w", col.Name, err)
+		}
+		if col.Default != nil {
+			if _, err := coerceValue(col.Type, col.Default); err != nil {
+				return fmt.Errorf("column %s default: %w", col.Name, err)
+			}
+		}
+
+		if col.PrimaryKey {
+			if primaryKey != "" {
+				return fmt.Errorf("multiple primary key columns: %s and %s", primaryKey, col.Name)
+			}
+			primaryKey = col.Name
+		}
+	}
+	return nil
+}
+
+// conformRow checks row against the schema of table and returns a copy with
+// missing columns set to their defaults and values converted to the column
+// types. The primary key may be left out when keyOptional is set, for callers
+// that assign or preserve it. Tables without columns accept any row.
+func (s TableSchema) conformRow(table string, row Row, keyOptional bool) (Row, error) {
+	if len(s.Columns) == 0 {
+		return copyRow(row), nil
+	}
+
+	columns := make(map[string]bool, len(s.Columns))
+	for _, col := range s.Columns {
+		columns[col.Name] = true
+	}
+	for name := range row {
+		if !columns[name] {
+			return nil, &ColumnError{Table: table, Column: name, Err: ErrUnknownColumn}
+		}
+	}
+
+	conformed := make(Row, len(s.Columns))
+	for _, col := range s.Columns {
+		value, ok := row[col.Name]
+		if !ok {
+			value = col.Default
+		}
+
+		if value == nil {
+			if col.PrimaryKey && keyOptional {
+				continue
+			}
+			if !col.Nullable || col.PrimaryKey {
+				return nil, &ColumnError{Table: table, Column: col.Name, Err: ErrNotNull}
+			}
+			conformed[col.Name] = nil
+			continue
+		}
+
+		converted, err := coerceValue(col.Type, value)
+		if err != nil {
+			return nil, &ColumnError{Table: table, Column: col.Name, Err: err}
+		}
+		conformed[col.Name] = converted
+	}
+	return conformed, nil
+}
+
+// conformUpdate checks the columns set by an update against the schema of
+// table and returns a copy with values converted to the column types. Unlike
+// conformRow, columns left out keep their current values.
+func (s TableSchema) conformUpdate(table string, row Row) (Row, error) {
+	conformed := make(Row, len(row))
+	if len(s.Columns) == 0 {
+		for name, value := range row {
+			if _, err := jsonPath(name); err != nil {
+				return nil, err
+			}
+			conformed[name] = value
+		}
+		return conformed, nil
+	}
+
+	columns := make(map[string]ColumnDefinition, len(s.Columns))
+	for _, col := range s.Columns {
+		columns[col.Name] = col
+	}
+	for name, value := range row {
+		col, ok := columns[name]
+		if !ok {
+			return nil, &ColumnError{Table: table, Column: name, Err: ErrUnknownColumn}
+		}
+
+		if value == nil {
+			if !col.Nullable || col.PrimaryKey {
+				return nil, &ColumnError{Table: table, Column: name, Err: ErrNotNull}
+			}
+			conformed[name] = nil
+			continue
+		}
+
+		converted, err := coerceValue(col.Type, value)
+		if err != nil {
+			return nil, &ColumnError{Table: table, Column: name, Err: err}
+		}
+		conformed[name] = converted
+	}
+	return conformed, nil
+}
+
+// coerceValue converts a non-NULL value to the representation stored for the
+// declared column type, failing with ErrTypeMismatch when it doesn't fit
+func coerceValue(declared string, value interface{}) (interface{}, error) {
+	t, err := columnType(declared)
+	if err != nil {
+		return nil, err
+	}
+
+	var converted interface{}
+	ok := false
+	switch t {
+	case "":
+		return value, nil
+	case ColumnTypeInteger:
+		converted, ok = coerceInteger(value)
+	case ColumnTypeReal:
+		converted, ok = coerceReal(value)
+	case ColumnTypeText:
+		converted, ok = value.(string)
+	case ColumnTypeBoolean:
+		converted, ok = coerceBoolean(value)
+	case ColumnTypeTimestamp:
+		converted, ok = coerceTimestamp(value)
+	case ColumnTypeBlob:
+		converted, ok = coerceBlob(value)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: cannot store %T value %v as %s", ErrTypeMismatch, value, value, t)
+	}
+	return converted, nil
+}
+
+// coerceInteger accepts integral numbers and decimal strings
+func coerceInteger(value interface{}) (int64, bool) {
+	if s, ok := value.(string); ok {
+		i, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		return i, err == nil
+	}
+	return toInt64(value)
+}
+
+// coerceReal accepts numbers and numeric strings
+func coerceReal(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	if i, ok := toInt64(value); ok {
+		return float64(i), true
+	}
+	return 0, false
+}
+
+// coerceBoolean accepts booleans, 0 and 1, and strings such as "true"
+func coerceBoolean(value interface{}) (bool, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(v))
+		return b, err == nil
+	}
+	if i, ok := toInt64(value); ok && (i == 0 || i == 1) {
+		return i == 1, true
+	}
+	return false, false
+}
+
+// coerceTimestamp accepts times and strings in one of timestampLayouts
+func coerceTimestamp(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return formatTimestamp(v), true
+	case *time.Time:
+		if v != nil {
+			return formatTimestamp(*v), true
+		}
+	case string:
+		for _, layout := range timestampLayouts {
+			if t, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+				return formatTimestamp(t), true
+			}
+		}
+	}
+	return "", false
+}
+
+// coerceBlob accepts byte slices and base64 strings, and stores base64 as
+// encoding/json does for []byte
+func coerceBlob(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v), true
+	case string:
+		_, err := base64.StdEncoding.DecodeString(v)
+		return v, err == nil
+	}
+	return "", false
+}
+
+// toInt64 converts an integral Go or JSON number to int64
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint8:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case uint:
+		return int64(n), n <= math.MaxInt64
+	case uint64:
+		return int64(n), n <= math.MaxInt64
+	case float64:
+		return int64(n), n == math.Trunc(n) && math.Abs(n) < 1<<63
+	case float32:
+		return toInt64(float64(n))
+	case json.Number:
+		i, err := n.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestColumnType(t *testing.T) {
+	tests := []struct {
+		declared string
+		want     string
+		wantErr  bool
+	}{
+		{"", "", false},
+		{"integer", ColumnTypeInteger, false},
+		{"BIGINT", ColumnTypeInteger, false},
+		{"VARCHAR(255)", ColumnTypeText, false},
+		{"double precision", ColumnTypeReal, false},
+		{"DATETIME", ColumnTypeTimestamp, false},
+		{"bool", ColumnTypeBoolean, false},
+		{"BYTEA", ColumnTypeBlob, false},
+		{"INTEGR", "", true},
+		{"VECTOR", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := columnType(tt.declared)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("columnType(%q) error = %v, wantErr %v", tt.declared, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("columnType(%q) = %q, want %q", tt.declared, got, tt.want)
+		}
+	}
+}
+
+func TestCoerceValue(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 13, 30, 0, 0, time.FixedZone("CET", 3600))
+
+	tests := []struct {
+		declared string
+		value    interface{}
+		want     interface{}
+		wantErr  bool
+	}{
+		{"", []int{1}, []int{1}, false},
+		{"INTEGER", 42, int64(42), false},
+		{"INTEGER", float64(42), int64(42), false},
+		{"INTEGER", json.Number("42"), int64(42), false},
+		{"INTEGER", " 42 ", int64(42), false},
+		{"INTEGER", 4.5, nil, true},
+		{"INTEGER", "forty-two", nil, true},
+		{"INTEGER", true, nil, true},
+		{"REAL", 3, float64(3), false},
+		{"REAL", "2.5", 2.5, false},
+		{"REAL", false, nil, true},
+		{"TEXT", "hello", "hello", false},
+		{"TEXT", 42, nil, true},
+		{"BOOLEAN", true, true, false},
+		{"BOOLEAN", 0, false, false},
+		{"BOOLEAN", "true", true, false},
+		{"BOOLEAN", 2, nil, true},
+		{"TIMESTAMP", ts, "2024-03-01T12:30:00.000000000Z", false},
+		{"TIMESTAMP", "2024-03-01T13:30:00+01:00", "2024-03-01T12:30:00.000000000Z", false},
+		{"TIMESTAMP", "2024-03-01", "2024-03-01T00:00:00.000000000Z", false},
+		{"TIMESTAMP", "yesterday", nil, true},
+		{"BLOB", []byte("hi"), "aGk=", false},
+		{"BLOB", "aGk=", "aGk=", false},
+		{"BLOB", "not base64!", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := coerceValue(tt.declared, tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("coerceValue(%q, %v) error = %v, wantErr %v", tt.declared, tt.value, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			if !errors.Is(err, ErrTypeMismatch) {
+				t.Errorf("coerceValue(%q, %v) error = %v, want ErrTypeMismatch", tt.declared, tt.value, err)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("coerceValue(%q, %v) = %#v, want %#v", tt.declared, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestConformRow(t *testing.T) {
+	schema := TableSchema{Columns: []ColumnDefinition{
+		{Name: "id", Type: "INTEGER", PrimaryKey: true},
+		{Name: "name", Type: "TEXT"},
+		{Name: "score", Type: "REAL", Nullable: true},
+		{Name: "active", Type: "BOOLEAN", Default: true},
+	}}
+
+	tests := []struct {
+		name        string
+		row         Row
+		keyOptional bool
+		want        Row
+		wantErr     error
+		wantColumn  string
+	}{
+		{
+			name: "defaults and conversions",
+			row:  Row{"id": 1, "name": "alice", "score": "9.5"},
+			want: Row{"id": int64(1), "name": "alice", "score": 9.5, "active": true},
+		},
+		{
+			name: "nullable column left out",
+			row:  Row{"id": 1, "name": "alice", "active": false},
+			want: Row{"id": int64(1), "name": "alice", "score": nil, "active": false},
+		},
+		{
+			name:        "optional key",
+			row:         Row{"name": "alice"},
+			keyOptional: true,
+			want:        Row{"name": "alice", "score": nil, "active": true},
+		},
+		{name: "unknown column", row: Row{"id": 1, "name": "alice", "nmae": "x"}, wantErr: ErrUnknownColumn, wantColumn: "nmae"},
+		{name: "missing required column", row: Row{"id": 1}, wantErr: ErrNotNull, wantColumn: "name"},
+		{name: "explicit NULL", row: Row{"id": 1, "name": "alice", "active": nil}, wantErr: ErrNotNull, wantColumn: "active"},
+		{name: "missing key", row: Row{"name": "alice"}, wantErr: ErrNotNull, wantColumn: "id"},
+		{name: "wrong type", row: Row{"id": 1, "name": 7}, wantErr: ErrTypeMismatch, wantColumn: "name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := schema.conformRow("people", tt.row, tt.keyOptional)
+			if tt.wantErr != nil {
+				var colErr *ColumnError
+				if !errors.As(err, &colErr) || !errors.Is(err, tt.wantErr) {
+					t.Fatalf("conformRow() error = %v, want ColumnError wrapping %v", err, tt.wantErr)
+				}
+				if colErr.Table != "people" || colErr.Column != tt.wantColumn {
+					t.Errorf("ColumnError = %s.%s, want people.%s", colErr.Table, colErr.Column, tt.wantColumn)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("conformRow() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("conformRow() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidCursor is returned for a SelectOptions.After token that is
+// malformed or was produced with a different OrderBy
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursorToken is the decoded form of an iterator cursor: the sort keys and
+// row ID of the last row returned, and the order they were taken in
+type cursorToken struct {
+	Order []string      `json:"o,omitempty"`
+	Keys  []interface{} `json:"k,omitempty"`
+	RowID int64         `json:"r"`
+}
+
+// orderSpec renders OrderBy as a list such as ["name", "-age"], used to
+// check that a cursor is resumed with the order it was made for
+func orderSpec(orderBy []OrderBy) []string {
+	spec := make([]string, len(orderBy))
+	for i, o := range orderBy {
+		spec[i] = o.Column
+		if o.Desc {
+			spec[i] = "-" + o.Column
+		}
+	}
+	return spec
+}
+
+// encodeCursor returns the token that resumes a scan after the row with the
+// given sort keys and row ID
+func encodeCursor(orderBy []OrderBy, keys []interface{}, rowID int64) string {
+	data, err := json.Marshal(cursorToken{Order: orderSpec(orderBy), Keys: keys, RowID: rowID})
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a token made by encodeCursor for the same order
+func decodeCursor(token string, orderBy []OrderBy) (cursorToken, error) {
+	var cursor cursorToken
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return cursor, ErrInvalidCursor
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&cursor); err != nil {
+		return cursor, ErrInvalidCursor
+	}
+
+	spec := orderSpec(orderBy)
+	if len(cursor.Keys) != len(spec) || strings.Join(cursor.Order, ",") != strings.Join(spec, ",") {
+		return cursor, fmt.Errorf("%w: made for order %v, not %v", ErrInvalidCursor, cursor.Order, spec)
+	}
+	return cursor, nil
+}
+
+// selectQuery builds the statement behind Select for a table. It returns
+// row_id, data and then one sort key per OrderBy column.
+func selectQuery(table string, schema TableSchema, condition Condition, opts SelectOptions) (string, []interface{}, error) {
+	where, args, err := compileCondition(condition, schema)
+	if err != nil {
+		return "", nil, err
+	}
+	if opts.Limit < 0 || opts.Offset < 0 {
+		return "", nil, fmt.Errorf("limit and offset must not be negative")
+	}
+
+	keys := make([]string, len(opts.OrderBy))
+	order := make([]string, 0, len(opts.OrderBy)+1)
+	for i, o := range opts.OrderBy {
+		if len(schema.Columns) > 0 {
+			if _, ok := schema.column(o.Column); !ok {
+				return "", nil, &ColumnError{Table: table, Column: o.Column, Err: ErrUnknownColumn}
+			}
+		}
+		if keys[i], err = columnExpr(o.Column); err != nil {
+			return "", nil, err
+		}
+		if o.Desc {
+			order = append(order, keys[i]+" DESC")
+		} else {
+			order = append(order, keys[i])
+		}
+	}
+	// Breaking ties by row ID in the direction of the last sort key lets a
+	// single index on the sort columns serve the whole order
+	rowIDOrder := "row_id"
+	if n := len(opts.OrderBy); n > 0 && opts.OrderBy[n-1].Desc {
+		rowIDOrder = "row_id DESC"
+	}
+	order = append(order, rowIDOrder)
+
+	var query strings.Builder
+	query.WriteString("SELECT row_id, data")
+	for _, key := range keys {
+		query.WriteString(", " + key)
+	}
+	query.WriteString(" FROM table_data WHERE " + tableRows(table) + " AND (" + where + ")")
+
+	if opts.After != "" {
+		cursor, err := decodeCursor(opts.After, opts.OrderBy)
+		if err != nil {
+			return "", nil, err
+		}
+		after, afterArgs := keysetCondition(keys, opts.OrderBy, cursor)
+		query.WriteString(" AND (" + after + ")")
+		args = append(args, afterArgs...)
+	}
+
+	query.WriteString(" ORDER BY " + strings.Join(order, ", "))
+	if opts.Limit > 0 || opts.Offset > 0 {
+		limit := opts.Limit
+		if limit == 0 {
+			limit = -1
+		}
+		query.WriteString(" LIMIT " + strconv.Itoa(limit) + " OFFSET " + strconv.Itoa(opts.Offset))
+	}
+
+	return query.String(), args, nil
+}
+
+// keysetCondition matches the rows that sort after the cursor: those greater
+// on the first sort key, or equal on it and greater on the next one, and so
+// on down to the row ID. Equality uses IS so that NULL keys compare equal.
+func keysetCondition(keys []string, orderBy []OrderBy, cursor cursorToken) (string, []interface{}) {
+	var terms []string
+	var args []interface{}
+	prefix := ""
+	var prefixArgs []interface{}
+
+	// A plain lower bound on the first key lets SQLite start the index
+	// scan at the cursor instead of filtering the rows before it
+	bound := ""
+	var boundArgs []interface{}
+	if len(keys) > 0 && !orderBy[0].Desc {
+		if value := sqlValue(cursor.Keys[0]); value != nil {
+			bound, boundArgs = keys[0]+" >= ? AND ", []interface{}{value}
+		}
+	}
+
+	for i, key := range keys {
+		value := sqlValue(cursor.Keys[i])
+		var after string
+		var afterArgs []interface{}
+		switch {
+		case value == nil && !orderBy[i].Desc:
+			after = key + " IS NOT NULL"
+		case value == nil:
+			after = "0"
+		case !orderBy[i].Desc:
+			after, afterArgs = key+" > ?", []interface{}{value}
+		default:
+			after, afterArgs = "("+key+" < ? OR "+key+" IS NULL)", []interface{}{value}
+		}
+
+		terms = append(terms, "("+prefix+after+")")
+		args = append(append(args, prefixArgs...), afterArgs...)
+
+		prefix += key + " IS ? AND "
+		prefixArgs = append(prefixArgs, value)
+	}
+
+	after := "row_id > ?"
+	if n := len(orderBy); n > 0 && orderBy[n-1].Desc {
+		after = "row_id < ?"
+	}
+	terms = append(terms, "("+prefix+after+")")
+	args = append(append(args, prefixArgs...), cursor.RowID)
+	return bound + "(" + strings.Join(terms, " OR ") + ")", append(boundArgs, args...)
+}
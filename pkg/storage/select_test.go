@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// selectNames returns the name column of the rows selected with opts, and
+// the cursor of the last one
+func selectNames(t *testing.T, table Table, condition Condition, opts SelectOptions) ([]string, string) {
+	t.Helper()
+
+	it, err := table.SelectWithOptions(context.Background(), condition, opts)
+	if err != nil {
+		t.Fatalf("SelectWithOptions(%+v) error = %v", opts, err)
+	}
+	defer it.Close()
+
+	var names []string
+	cursor := ""
+	for it.Next() {
+		var row Row
+		if err := it.Scan(&row); err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+		names = append(names, row["name"].(string))
+		cursor = it.Cursor()
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+	return names, cursor
+}
+
+func TestSelectOptions(t *testing.T) {
+	schema := TableSchema{Columns: []ColumnDefinition{
+		{Name: "name", Type: "TEXT"},
+		{Name: "age", Type: "INTEGER", Nullable: true},
+		{Name: "city", Type: "TEXT", Nullable: true},
+	}}
+	people := []Row{
+		{"name": "alice", "age": 25, "city": "Paris"},
+		{"name": "bob", "age": 31, "city": "Berlin"},
+		{"name": "carol", "age": 25, "city": "Berlin"},
+		{"name": "dave"},
+		{"name": "erin", "age": 40, "city": "Paris"},
+		{"name": "frank", "age": 31},
+	}
+
+	tests := []struct {
+		name      string
+		condition Condition
+		opts      SelectOptions
+		want      []string
+	}{
+		{"row ID order", nil, SelectOptions{}, []string{"alice", "bob", "carol", "dave", "erin", "frank"}},
+		{"ascending with NULLs first", nil, SelectOptions{OrderBy: []OrderBy{{Column: "age"}}},
+			[]string{"dave", "alice", "carol", "bob", "frank", "erin"}},
+		{"descending with NULLs last", nil, SelectOptions{OrderBy: []OrderBy{{Column: "age", Desc: true}}},
+			[]string{"erin", "frank", "bob", "carol", "alice", "dave"}},
+		{"multiple columns", nil, SelectOptions{OrderBy: []OrderBy{{Column: "city", Desc: true}, {Column: "name"}}},
+			[]string{"alice", "erin", "bob", "carol", "dave", "frank"}},
+		{"limit", nil, SelectOptions{OrderBy: []OrderBy{{Column: "name", Desc: true}}, Limit: 2}, []string{"frank", "erin"}},
+		{"offset", nil, SelectOptions{Offset: 4}, []string{"erin", "frank"}},
+		{"limit and offset", nil, SelectOptions{Limit: 2, Offset: 1}, []string{"bob", "carol"}},
+		{"with condition", NewSimpleCondition("age", ">", 25), SelectOptions{OrderBy: []OrderBy{{Column: "age", Desc: true}}, Limit: 2},
+			[]string{"erin", "frank"}},
+	}
+
+	for kind, table := range newTestTablesWithSchema(t, schema) {
+		t.Run(kind, func(t *testing.T) {
+			for _, row := range people {
+				if _, err := table.Insert(context.Background(), row); err != nil {
+					t.Fatalf("Insert() error = %v", err)
+				}
+			}
+
+			for _, tt := range tests {
+				if got, _ := selectNames(t, table, tt.condition, tt.opts); !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("%s: rows = %v, want %v", tt.name, got, tt.want)
+				}
+
+				// Paging with cursors visits the same rows in the same order
+				if tt.opts.Limit != 0 || tt.opts.Offset != 0 {
+					continue
+				}
+				var paged []string
+				opts := tt.opts
+				opts.Limit = 2
+				for i := 0; i < len(people); i++ {
+					page, cursor := selectNames(t, table, tt.condition, opts)
+					paged = append(paged, page...)
+					if len(page) < opts.Limit {
+						break
+					}
+					opts.After = cursor
+				}
+				if !reflect.DeepEqual(paged, tt.want) {
+					t.Errorf("%s: paged rows = %v, want %v", tt.name, paged, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSelectOptionsErrors(t *testing.T) {
+	ctx := context.Background()
+	schema := TableSchema{Columns: []ColumnDefinition{{Name: "name", Type: "TEXT"}}}
+
+	for kind, table := range newTestTablesWithSchema(t, schema) {
+		t.Run(kind, func(t *testing.T) {
+			if _, err := table.Insert(ctx, Row{"name": "alice"}); err != nil {
+				t.Fatalf("Insert() error = %v", err)
+			}
+			_, cursor := selectNames(t, table, nil, SelectOptions{OrderBy: []OrderBy{{Column: "name"}}})
+
+			if _, err := table.SelectWithOptions(ctx, nil, SelectOptions{After: cursor}); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("SelectWithOptions() with a cursor for another order error = %v, want ErrInvalidCursor", err)
+			}
+			if _, err := table.SelectWithOptions(ctx, nil, SelectOptions{After: "not a cursor"}); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("SelectWithOptions() with a bad cursor error = %v, want ErrInvalidCursor", err)
+			}
+			if _, err := table.SelectWithOptions(ctx, nil, SelectOptions{OrderBy: []OrderBy{{Column: "age"}}}); !errors.Is(err, ErrUnknownColumn) {
+				t.Errorf("SelectWithOptions() ordered by an unknown column error = %v, want ErrUnknownColumn", err)
+			}
+			if _, err := table.SelectWithOptions(ctx, nil, SelectOptions{Limit: -1}); err == nil {
+				t.Error("SelectWithOptions() with a negative limit should fail")
+			}
+		})
+	}
+}
+
+func TestSelectQueryPlan(t *testing.T) {
+	engine := newMemoryEngine(t)
+	schema := TableSchema{Indexes: []IndexDefinition{{Name: "people_age", Columns: []string{"age"}}}}
+	if err := engine.CreateTable("people", schema); err != nil {
+		t.Fatalf("CreateTable() error = %v", err)
+	}
+	db := engine.(*MemoryEngine).db
+	cursor := encodeCursor([]OrderBy{{Column: "age"}}, []interface{}{30}, 7)
+
+	tests := []SelectOptions{
+		{OrderBy: []OrderBy{{Column: "age"}}, Limit: 20},
+		{OrderBy: []OrderBy{{Column: "age", Desc: true}}, Limit: 20},
+		{OrderBy: []OrderBy{{Column: "age"}}, Limit: 20, After: cursor},
+	}
+
+	for _, opts := range tests {
+		query, args, err := selectQuery("people", schema, nil, opts)
+		if err != nil {
+			t.Fatalf("selectQuery() error = %v", err)
+		}
+		rows, err := db.Query("EXPLAIN QUERY PLAN "+query, args...)
+		if err != nil {
+			t.Fatalf("EXPLAIN error = %v", err)
+		}
+
+		var plan []string
+		for rows.Next() {
+			var id, parent, unused int
+			var detail string
+			if err := rows.Scan(&id, &parent, &unused, &detail); err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+			plan = append(plan, detail)
+		}
+		rows.Close()
+
+		got := strings.Join(plan, "; ")
+		if !strings.Contains(got, sqliteIndexPrefix+"people_age") || strings.Contains(got, "TEMP B-TREE") {
+			t.Errorf("plan for %+v = %q, want an ordered scan of people_age", opts, got)
+		}
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"sort"
 	"strings"
 )
 
@@ -13,6 +15,10 @@ type memoryTable struct {
 	name   string
 	schema TableSchema
 	engine Engine
+
+	// tx is set for tables opened through a Transaction, whose reads and
+	// writes then go through it
+	tx *sql.Tx
 }
 
 // Name returns the table name
@@ -25,153 +31,370 @@ func (t *memoryTable) Schema() TableSchema {
 	return t.schema
 }
 
-// Insert inserts a new row into the table
-func (t *memoryTable) Insert(ctx context.Context, row Row) error {
-	// Serialize row data
-	rowJSON, err := json.Marshal(row)
+// Insert inserts a new row into the table and returns its row ID. Row IDs
+// come from a per-table sequence and are never reused. An integer primary key
+// column supplies the row ID when set and is filled in from the sequence
+// when not.
+func (t *memoryTable) Insert(ctx context.Context, row Row) (int64, error) {
+	pk, hasKey := t.schema.primaryKey()
+	row, err := t.schema.conformRow(t.name, row, hasKey && isIntegerType(pk.Type))
 	if err != nil {
-		return fmt.Errorf("failed to serialize row: %w", err)
+		return 0, err
 	}
 
-	// Generate row ID
-	rowID := generateRowID()
+	var rowID int64
+	err = t.write(ctx, func(tx *sql.Tx) error {
+		var err error
+		rowID, err = t.assignRowID(ctx, tx, row)
+		if err != nil {
+			return err
+		}
+
+		// Serialize row data
+		rowJSON, err := json.Marshal(row)
+		if err != nil {
+			return fmt.Errorf("failed to serialize row: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO table_data (table_name, row_id, data) VALUES (?, ?, ?)`,
+			t.name, rowID, string(rowJSON),
+		)
+		if err != nil {
+			if dup := uniqueViolation(t.name, err); dup != nil {
+				return dup
+			}
+			return fmt.Errorf("failed to insert row: %w", sqliteError("insert", err))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return rowID, nil
+}
+
+// write runs fn in a transaction. Tables opened through a Transaction use
+// it, with a savepoint so that a failed write leaves no partial changes;
+// other tables begin a transaction of their own and commit it when fn
+// succeeds.
+func (t *memoryTable) write(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	if t.tx != nil {
+		if _, err := t.tx.ExecContext(ctx, `SAVEPOINT telumdb_write`); err != nil {
+			return fmt.Errorf("failed to begin savepoint: %w", sqliteError("transaction", err))
+		}
+		if err := fn(t.tx); err != nil {
+			t.tx.ExecContext(ctx, `ROLLBACK TO telumdb_write`)
+			t.tx.ExecContext(ctx, `RELEASE telumdb_write`)
+			return err
+		}
+		if _, err := t.tx.ExecContext(ctx, `RELEASE telumdb_write`); err != nil {
+			return fmt.Errorf("failed to release savepoint: %w", sqliteError("transaction", err))
+		}
+		return nil
+	}
 
-	// Insert into database
 	engine := t.engine.(*engineImpl)
-	_, err = engine.db.Exec(
-		`INSERT INTO table_data (table_name, row_id, data) VALUES (?, ?, ?)`,
-		t.name, rowID, string(rowJSON),
-	)
+	tx, err := engine.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to insert row: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", sqliteError("transaction", err))
 	}
+	defer tx.Rollback()
 
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", sqliteError("transaction", err))
+	}
 	return nil
 }
 
-// Update updates rows matching the condition
-func (t *memoryTable) Update(ctx context.Context, row Row, condition Condition) error {
-	// For now, implement simple update based on row ID
-	// TODO: Implement proper condition parsing
-	if id, ok := row["id"]; ok {
-		rowJSON, err := json.Marshal(row)
+// conn returns where the table's reads go: its transaction, if any, or the
+// engine's database
+func (t *memoryTable) conn() dbConn {
+	if t.tx != nil {
+		return t.tx
+	}
+	return t.engine.(*engineImpl).db
+}
+
+// assignRowID picks the row ID for a new row, filling in an integer primary
+// key that was left unset and rejecting duplicate keys
+func (t *memoryTable) assignRowID(ctx context.Context, tx *sql.Tx, row Row) (int64, error) {
+	pk, ok := t.schema.primaryKey()
+	if !ok {
+		return nextRowID(ctx, tx, t.name)
+	}
+
+	value := row[pk.Name]
+	if !isIntegerType(pk.Type) {
+		count, err := t.count(ctx, tx, NewSimpleCondition(pk.Name, OperatorEqual, value))
 		if err != nil {
-			return fmt.Errorf("failed to serialize row: %w", err)
+			return 0, err
 		}
+		if count > 0 {
+			return 0, fmt.Errorf("row with %s = %v %w", pk.Name, value, ErrAlreadyExists)
+		}
+		return nextRowID(ctx, tx, t.name)
+	}
 
-		engine := t.engine.(*engineImpl)
-		_, err = engine.db.Exec(
-			`UPDATE table_data SET data = ? WHERE table_name = ? AND row_id = ?`,
-			string(rowJSON), t.name, fmt.Sprintf("%v", id),
-		)
+	if value == nil {
+		rowID, err := nextRowID(ctx, tx, t.name)
 		if err != nil {
-			return fmt.Errorf("failed to update row: %w", err)
+			return 0, err
 		}
+		row[pk.Name] = rowID
+		return rowID, nil
 	}
 
-	return nil
+	rowID, ok := toInt64(value)
+	if !ok {
+		return 0, fmt.Errorf("primary key column %s must be an integer, got %v", pk.Name, value)
+	}
+	var exists int
+	err := tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM table_data WHERE table_name = ? AND row_id = ?`,
+		t.name, rowID,
+	).Scan(&exists)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check primary key: %w", sqliteError("insert", err))
+	}
+	if exists > 0 {
+		return 0, fmt.Errorf("row with %s = %d %w", pk.Name, rowID, ErrAlreadyExists)
+	}
+
+	// Keep the sequence ahead of explicit keys so generated IDs never collide
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO table_sequences (table_name, last_id) VALUES (?, ?)
+		ON CONFLICT (table_name) DO UPDATE SET last_id = MAX(last_id, excluded.last_id)`,
+		t.name, rowID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to advance row ID sequence: %w", sqliteError("insert", err))
+	}
+	row[pk.Name] = rowID
+	return rowID, nil
 }
 
-// Delete deletes rows matching the condition
-func (t *memoryTable) Delete(ctx context.Context, condition Condition) error {
-	// For now, implement simple delete based on ID condition
-	// TODO: Implement proper condition parsing
-	engine := t.engine.(*engineImpl)
+// nextRowID advances a table's row ID sequence and returns the new value
+func nextRowID(ctx context.Context, tx *sql.Tx, table string) (int64, error) {
+	var rowID int64
+	err := tx.QueryRowContext(ctx,
+		`INSERT INTO table_sequences (table_name, last_id) VALUES (?, 1)
+		ON CONFLICT (table_name) DO UPDATE SET last_id = last_id + 1
+		RETURNING last_id`,
+		table,
+	).Scan(&rowID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate row ID: %w", sqliteError("insert", err))
+	}
+	return rowID, nil
+}
 
-	if condition == nil {
-		// Delete all rows
-		_, err := engine.db.Exec(
-			`DELETE FROM table_data WHERE table_name = ?`,
-			t.name,
-		)
+// Update sets the columns in row on every row matching the condition and
+// returns the number of rows changed. Columns left out of row keep their
+// values; a nil condition updates every row. The primary key can't be changed.
+func (t *memoryTable) Update(ctx context.Context, row Row, condition Condition) (int64, error) {
+	if len(row) == 0 {
+		return 0, fmt.Errorf("no columns to update in table %s", t.name)
+	}
+	where, args, err := compileCondition(condition, t.schema)
+	if err != nil {
+		return 0, err
+	}
+	row, err = t.schema.conformUpdate(t.name, row)
+	if err != nil {
+		return 0, err
+	}
+
+	// Sort the columns so the statement is the same for the same row shape
+	names := make([]string, 0, len(row))
+	for name := range row {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	setArgs := make([]interface{}, 0, 2*len(names))
+	for _, name := range names {
+		path, err := jsonPath(name)
 		if err != nil {
-			return fmt.Errorf("failed to delete rows: %w", err)
+			return 0, err
 		}
-	} else {
-		// Simple ID-based deletion using condition string
-		conditionStr := condition.String()
-		if strings.Contains(conditionStr, "id") {
-			// Extract ID from condition string (simple parsing)
-			parts := strings.Fields(conditionStr)
-			if len(parts) >= 3 {
-				id := parts[2]
-				_, err := engine.db.Exec(
-					`DELETE FROM table_data WHERE table_name = ? AND row_id = ?`,
-					t.name, id,
-				)
+		valueJSON, err := json.Marshal(row[name])
+		if err != nil {
+			return 0, fmt.Errorf("failed to serialize column %s: %w", name, err)
+		}
+		setArgs = append(setArgs, path, string(valueJSON))
+	}
+
+	var affected int64
+	err = t.write(ctx, func(tx *sql.Tx) error {
+		if pk, ok := t.schema.primaryKey(); ok {
+			if value, ok := row[pk.Name]; ok {
+				// Rows matched by the condition whose key differs from value
+				other := NewNotCondition(NewSimpleCondition(pk.Name, OperatorEqual, value))
+				if condition != nil {
+					other = NewAndCondition(condition, other)
+				}
+				changed, err := t.count(ctx, tx, other)
 				if err != nil {
-					return fmt.Errorf("failed to delete row: %w", err)
+					return err
 				}
+				if changed > 0 {
+					return fmt.Errorf("primary key column %s cannot be updated", pk.Name)
+				}
+			}
+		}
+
+		set := "json_set(data" + strings.Repeat(", ?, json(?)", len(names)) + ")"
+		res, err := tx.ExecContext(ctx,
+			`UPDATE table_data SET data = `+set+` WHERE `+tableRows(t.name)+` AND (`+where+`)`,
+			append(setArgs, args...)...,
+		)
+		if err != nil {
+			if dup := uniqueViolation(t.name, err); dup != nil {
+				return dup
 			}
+			return fmt.Errorf("failed to update rows: %w", sqliteError("update", err))
+		}
+		affected, err = res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to update rows: %w", sqliteError("update", err))
 		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return affected, nil
+}
+
+// Delete deletes rows matching the condition and returns how many were
+// removed. A nil condition deletes every row.
+func (t *memoryTable) Delete(ctx context.Context, condition Condition) (int64, error) {
+	where, args, err := compileCondition(condition, t.schema)
+	if err != nil {
+		return 0, err
 	}
 
-	return nil
+	res, err := t.conn().ExecContext(ctx,
+		`DELETE FROM table_data WHERE `+tableRows(t.name)+` AND (`+where+`)`,
+		args...,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete rows: %w", sqliteError("delete", err))
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete rows: %w", sqliteError("delete", err))
+	}
+
+	return affected, nil
 }
 
-// Select retrieves rows matching the condition
+// Select retrieves rows matching the condition in row ID order. Rows are
+// read as the iterator advances; columns lists what Scan returns, or all
+// columns if empty.
 func (t *memoryTable) Select(ctx context.Context, columns []string, condition Condition) (Iterator, error) {
-	// Build query
-	query := fmt.Sprintf("SELECT row_id, data FROM table_data WHERE table_name = '%s'", t.name)
-
-	if condition != nil {
-		// Simple condition handling using condition string
-		conditionStr := condition.String()
-		if strings.Contains(conditionStr, "id") {
-			// Extract ID from condition string (simple parsing)
-			parts := strings.Fields(conditionStr)
-			if len(parts) >= 3 {
-				id := parts[2]
-				query += fmt.Sprintf(" AND row_id = '%s'", id)
+	return t.SelectWithOptions(ctx, condition, SelectOptions{Columns: columns})
+}
+
+// SelectWithOptions retrieves rows matching the condition, sorted and paged
+// in SQL so that only the requested rows are read
+func (t *memoryTable) SelectWithOptions(ctx context.Context, condition Condition, opts SelectOptions) (Iterator, error) {
+	columns := opts.Columns
+	if len(columns) == 0 {
+		for _, col := range t.schema.Columns {
+			columns = append(columns, col.Name)
+		}
+	} else if len(t.schema.Columns) > 0 {
+		for _, col := range columns {
+			if _, ok := t.schema.column(col); !ok {
+				return nil, &ColumnError{Table: t.name, Column: col, Err: ErrUnknownColumn}
 			}
 		}
 	}
 
-	engine := t.engine.(*engineImpl)
-	rows, err := engine.db.Query(query)
+	query, args, err := selectQuery(t.name, t.schema, condition, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to select rows: %w", err)
+		return nil, err
+	}
+
+	rows, err := t.conn().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select rows: %w", sqliteError("select", err))
 	}
 
 	return &memoryIterator{
 		rows:    rows,
 		columns: columns,
+		orderBy: opts.OrderBy,
 		table:   t,
 	}, nil
 }
 
 // Count returns the number of rows matching the condition
 func (t *memoryTable) Count(ctx context.Context, condition Condition) (int64, error) {
-	query := fmt.Sprintf("SELECT COUNT(*) FROM table_data WHERE table_name = '%s'", t.name)
-
-	if condition != nil {
-		// Simple condition handling using condition string
-		conditionStr := condition.String()
-		if strings.Contains(conditionStr, "id") {
-			// Extract ID from condition string (simple parsing)
-			parts := strings.Fields(conditionStr)
-			if len(parts) >= 3 {
-				id := parts[2]
-				query += fmt.Sprintf(" AND row_id = '%s'", id)
-			}
-		}
+	return t.count(ctx, t.conn(), condition)
+}
+
+// dbConn is implemented by *sql.DB and *sql.Tx
+type dbConn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// count returns the number of rows matching the condition as seen by q
+func (t *memoryTable) count(ctx context.Context, q dbConn, condition Condition) (int64, error) {
+	where, args, err := compileCondition(condition, t.schema)
+	if err != nil {
+		return 0, err
 	}
 
-	engine := t.engine.(*engineImpl)
 	var count int64
-	err := engine.db.QueryRow(query).Scan(&count)
+	err = q.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM table_data WHERE `+tableRows(t.name)+` AND (`+where+`)`,
+		args...,
+	).Scan(&count)
 	if err != nil {
-		return 0, fmt.Errorf("failed to count rows: %w", err)
+		return 0, fmt.Errorf("failed to count rows: %w", sqliteError("count", err))
 	}
 
 	return count, nil
 }
 
+// scanRow reads the row ID and decoded data of the current table_data row,
+// and any further columns into keys
+func scanRow(rows *sql.Rows, keys []interface{}) (int64, Row, error) {
+	var rowID int64
+	var dataJSON string
+	dest := []interface{}{&rowID, &dataJSON}
+	for i := range keys {
+		dest = append(dest, &keys[i])
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return 0, nil, fmt.Errorf("failed to scan row: %w", err)
+	}
+
+	row, err := decodeRow([]byte(dataJSON))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to parse row data: %w", err)
+	}
+	return rowID, row, nil
+}
+
 // memoryIterator implements the Iterator interface
 type memoryIterator struct {
 	rows    *sql.Rows
 	columns []string
+	orderBy []OrderBy
 	table   *memoryTable
+	rowID   int64
+	current Row
+	keys    []interface{}
+	err     error
 	closed  bool
 }
 
@@ -181,74 +404,180 @@ func (it *memoryIterator) Next() bool {
 		return false
 	}
 
-	hasNext := it.rows.Next()
-	if !hasNext {
-		it.Close()
+	if it.rows.Next() {
+		keys := make([]interface{}, len(it.orderBy))
+		rowID, row, err := scanRow(it.rows, keys)
+		if err == nil {
+			it.rowID, it.current, it.keys = rowID, row, keys
+			return true
+		}
+		it.err = err
+	} else if err := it.rows.Err(); err != nil {
+		it.err = fmt.Errorf("failed to read rows: %w", sqliteError("select", err))
+	}
+
+	it.rowID, it.current, it.keys = 0, nil, nil
+	it.Close()
+	return false
+}
+
+// RowID returns the row ID of the current row, or 0 before the first call to
+// Next and after the last row
+func (it *memoryIterator) RowID() int64 {
+	return it.rowID
+}
+
+// Cursor returns a token for SelectOptions.After that resumes the scan after
+// the current row, or "" when there is no current row
+func (it *memoryIterator) Cursor() string {
+	if it.current == nil {
+		return ""
 	}
-	return hasNext
+	return encodeCursor(it.orderBy, it.keys, it.rowID)
 }
 
-// Scan copies the current row's values into the provided destinations
+// Scan copies the selected columns of the current row into dest, one
+// destination per column. Destinations may be pointers to Go basic types,
+// time.Time, []byte or interface{}, or sql.Scanner implementations such as
+// sql.NullString; a value that doesn't fit fails with ErrTypeMismatch. A
+// single *map[string]interface{} or *Row destination receives the selected
+// columns by name, which is the only way to scan all columns of a table
+// without a schema.
 func (it *memoryIterator) Scan(dest ...interface{}) error {
-	if it.closed {
-		return fmt.Errorf("iterator is closed")
+	if err := it.check(); err != nil {
+		return err
 	}
 
-	var rowID string
-	var dataJSON string
+	if len(dest) == 1 && isRowDest(dest[0]) {
+		columns := it.columns
+		if len(columns) == 0 {
+			for col := range it.current {
+				columns = append(columns, col)
+			}
+		}
+		row := make(map[string]interface{}, len(columns))
+		for _, col := range columns {
+			value, err := it.value(col, it.current[col])
+			if err != nil {
+				return err
+			}
+			row[col] = value
+		}
+		switch d := dest[0].(type) {
+		case *map[string]interface{}:
+			*d = row
+		case *Row:
+			*d = row
+		}
+		return nil
+	}
+	if len(it.columns) == 0 {
+		return fmt.Errorf("no columns selected: scan into a *map[string]interface{}")
+	}
 
-	err := it.rows.Scan(&rowID, &dataJSON)
-	if err != nil {
-		return fmt.Errorf("failed to scan row: %w", err)
+	if len(dest) != len(it.columns) {
+		return fmt.Errorf("expected %d destinations for columns %v, got %d", len(it.columns), it.columns, len(dest))
 	}
+	for i, col := range it.columns {
+		value, err := it.value(col, it.current[col])
+		if err != nil {
+			return err
+		}
+		if err := assignValue(dest[i], value); err != nil {
+			return &ColumnError{Table: it.table.name, Column: col, Err: err}
+		}
+	}
+	return nil
+}
 
-	// Parse row data
-	var rowData map[string]interface{}
-	if err := json.Unmarshal([]byte(dataJSON), &rowData); err != nil {
-		return fmt.Errorf("failed to parse row data: %w", err)
+// ScanStruct copies the current row into the fields of the struct dest points
+// to. A field is filled from the column named by its telumdb tag, or else
+// from the column matching its name regardless of case; fields tagged "-" and
+// fields of columns that weren't selected are left alone.
+func (it *memoryIterator) ScanStruct(dest interface{}) error {
+	if err := it.check(); err != nil {
+		return err
 	}
 
-	// Map columns to destinations
-	if len(it.columns) == 0 {
-		// Return all columns
-		if len(dest) > 0 {
-			if destPtr, ok := dest[0].(*map[string]interface{}); ok {
-				*destPtr = rowData
-			}
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("ScanStruct destination must be a pointer to a struct, not %T", dest)
+	}
+	v = v.Elem()
+
+	selected := it.columns
+	if len(selected) == 0 {
+		for col := range it.current {
+			selected = append(selected, col)
 		}
-	} else {
-		// Return specific columns
-		for i, col := range it.columns {
-			if i < len(dest) {
-				if value, ok := rowData[col]; ok {
-					switch v := dest[i].(type) {
-					case *string:
-						*v = fmt.Sprintf("%v", value)
-					case *int:
-						if intVal, ok := value.(float64); ok {
-							*v = int(intVal)
-						}
-					case *int64:
-						if intVal, ok := value.(float64); ok {
-							*v = int64(intVal)
-						}
-					case *float64:
-						if floatVal, ok := value.(float64); ok {
-							*v = floatVal
-						}
-					case *bool:
-						if boolVal, ok := value.(bool); ok {
-							*v = boolVal
-						}
-					}
-				}
-			}
+	}
+
+	for _, field := range structFields(v.Type()) {
+		col, ok := matchColumn(selected, field.column)
+		if !ok {
+			continue
+		}
+		value, err := it.value(col, it.current[col])
+		if err != nil {
+			return err
+		}
+		if err := assignReflect(v.FieldByIndex(field.index), value); err != nil {
+			return &ColumnError{Table: it.table.name, Column: col, Err: err}
 		}
 	}
+	return nil
+}
 
+// check reports whether the iterator is positioned on a row
+func (it *memoryIterator) check() error {
+	if it.current == nil {
+		if it.closed {
+			return fmt.Errorf("iterator is closed")
+		}
+		return fmt.Errorf("no current row: call Next first")
+	}
 	return nil
 }
 
+// value decodes the stored value of a column according to its type
+func (it *memoryIterator) value(col string, value interface{}) (interface{}, error) {
+	def, _ := it.table.schema.column(col)
+	decoded, err := decodeValue(def.Type, value)
+	if err != nil {
+		return nil, &ColumnError{Table: it.table.name, Column: col, Err: fmt.Errorf("%w: %v", ErrTypeMismatch, err)}
+	}
+	return decoded, nil
+}
+
+// isRowDest reports whether dest receives a whole row
+func isRowDest(dest interface{}) bool {
+	switch dest.(type) {
+	case *map[string]interface{}, *Row:
+		return true
+	}
+	return false
+}
+
+// matchColumn finds name among columns, preferring an exact match over one
+// that differs in case
+func matchColumn(columns []string, name string) (string, bool) {
+	found := ""
+	for _, col := range columns {
+		if col == name {
+			return col, true
+		}
+		if found == "" && strings.EqualFold(col, name) {
+			found = col
+		}
+	}
+	return found, found != ""
+}
+
+// Err returns the error that ended iteration, if any
+func (it *memoryIterator) Err() error {
+	return it.err
+}
+
 // Close closes the iterator
 func (it *memoryIterator) Close() error {
 	if !it.closed {
@@ -258,13 +587,33 @@ func (it *memoryIterator) Close() error {
 	return nil
 }
 
-// Columns returns the column names
+// Columns returns the names of the selected columns, or nil when all columns
+// of a table without a schema are selected
 func (it *memoryIterator) Columns() []string {
 	return it.columns
 }
 
+// ColumnTypes describes the selected columns using the table schema. Columns
+// the schema doesn't declare are untyped and nullable.
+func (it *memoryIterator) ColumnTypes() []ColumnDefinition {
+	types := make([]ColumnDefinition, len(it.columns))
+	for i, col := range it.columns {
+		def, ok := it.table.schema.column(col)
+		if !ok {
+			def = ColumnDefinition{Name: col, Nullable: true}
+		}
+		types[i] = def
+	}
+	return types
+}
+
 // Helper functions
 
-func generateRowID() string {
-	return fmt.Sprintf("row_%d", len("placeholder")) // Simple ID generation
+// copyRow returns a shallow copy of row
+func copyRow(row Row) Row {
+	copied := make(Row, len(row))
+	for k, v := range row {
+		copied[k] = v
+	}
+	return copied
 }
@@ -0,0 +1,428 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/telumdb/telumdb/internal/config"
+)
+
+// newTestTables creates an empty table on each engine type
+func newTestTables(t *testing.T) map[string]Table {
+	t.Helper()
+	return newTestTablesWithSchema(t, TableSchema{})
+}
+
+// newTestTablesWithSchema creates an empty table with the given schema on
+// each engine type
+func newTestTablesWithSchema(t *testing.T, schema TableSchema) map[string]Table {
+	t.Helper()
+	ctx := context.Background()
+
+	tables := make(map[string]Table)
+	for _, kind := range []string{"hybrid", "memory"} {
+		engine, err := CreateEngine(config.StorageConfig{Engine: kind, DataDir: t.TempDir()})
+		if err != nil {
+			t.Fatalf("CreateEngine(%s) error = %v", kind, err)
+		}
+		if err := engine.Start(ctx); err != nil {
+			t.Fatalf("Start(%s) error = %v", kind, err)
+		}
+		t.Cleanup(func() { engine.Shutdown(ctx) })
+
+		if err := engine.CreateTable("people", schema); err != nil {
+			t.Fatalf("CreateTable(%s) error = %v", kind, err)
+		}
+		table, err := engine.GetTable("people")
+		if err != nil {
+			t.Fatalf("GetTable(%s) error = %v", kind, err)
+		}
+		tables[kind] = table
+	}
+	return tables
+}
+
+// insertPeople fills a table with a fixed set of rows
+func insertPeople(t *testing.T, table Table) {
+	t.Helper()
+
+	people := []Row{
+		{"id": 1, "name": "alice", "age": 25, "city": "Paris"},
+		{"id": 2, "name": "bob", "age": 31, "city": "Berlin"},
+		{"id": 3, "name": "carol", "age": 42, "city": nil},
+		{"id": 4, "name": "dave", "age": 30},
+	}
+	for _, row := range people {
+		if _, err := table.Insert(context.Background(), row); err != nil {
+			t.Fatalf("Insert() error = %v", err)
+		}
+	}
+}
+
+func TestTableConditions(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition Condition
+		want      int64
+	}{
+		{"all", nil, 4},
+		{"greater than", NewSimpleCondition("age", ">", 30), 2},
+		{"and", NewAndCondition(NewSimpleCondition("age", ">=", 30), NewSimpleCondition("city", "=", "Berlin")), 1},
+		{"or", NewOrCondition(NewSimpleCondition("name", "=", "alice"), NewSimpleCondition("age", "=", 42)), 2},
+		{"not skips NULL", NewNotCondition(NewSimpleCondition("city", "=", "Paris")), 1},
+		{"in", NewInCondition("name", "alice", "dave", "zoe"), 2},
+		{"between", NewBetweenCondition("age", 30, 40), 2},
+		{"like", NewLikeCondition("name", "%o%"), 2},
+		{"is null", NewIsNullCondition("city"), 2},
+		{"is not null", NewIsNotNullCondition("city"), 2},
+	}
+
+	ctx := context.Background()
+	for kind, table := range newTestTables(t) {
+		insertPeople(t, table)
+
+		for _, tt := range tests {
+			t.Run(kind+"/"+tt.name, func(t *testing.T) {
+				count, err := table.Count(ctx, tt.condition)
+				if err != nil {
+					t.Fatalf("Count() error = %v", err)
+				}
+				if count != tt.want {
+					t.Errorf("Count() = %d, want %d", count, tt.want)
+				}
+
+				it, err := table.Select(ctx, []string{"id"}, tt.condition)
+				if err != nil {
+					t.Fatalf("Select() error = %v", err)
+				}
+				var rows int64
+				for it.Next() {
+					rows++
+				}
+				it.Close()
+				if rows != tt.want {
+					t.Errorf("Select() returned %d rows, want %d", rows, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestTableDeleteAndUpdateConditions(t *testing.T) {
+	ctx := context.Background()
+	for kind, table := range newTestTables(t) {
+		t.Run(kind, func(t *testing.T) {
+			insertPeople(t, table)
+
+			deleted, err := table.Delete(ctx, NewSimpleCondition("age", ">", 30))
+			if err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if deleted != 2 {
+				t.Errorf("Delete(age > 30) = %d, want 2", deleted)
+			}
+			if count, _ := table.Count(ctx, nil); count != 2 {
+				t.Errorf("Count() after Delete(age > 30) = %d, want 2", count)
+			}
+
+			updated, err := table.Update(ctx, Row{"name": "someone", "age": 99}, NewLikeCondition("name", "a%"))
+			if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			if updated != 1 {
+				t.Errorf("Update(name LIKE a%%) = %d, want 1", updated)
+			}
+			if count, _ := table.Count(ctx, NewSimpleCondition("age", "=", 99)); count != 1 {
+				t.Errorf("Count() after Update() = %d, want 1", count)
+			}
+
+			// Invalid conditions are rejected even if no row would be examined
+			bad := NewOrCondition(NewSimpleCondition("age", "~", 1))
+			if _, err := table.Delete(ctx, bad); err == nil {
+				t.Error("Delete() with an invalid condition should fail")
+			}
+			if _, err := table.Count(ctx, bad); err == nil {
+				t.Error("Count() with an invalid condition should fail")
+			}
+			if _, err := table.Select(ctx, nil, bad); err == nil {
+				t.Error("Select() with an invalid condition should fail")
+			}
+			if _, err := table.Update(ctx, Row{"age": 1}, bad); err == nil {
+				t.Error("Update() with an invalid condition should fail")
+			}
+		})
+	}
+}
+
+func TestTablePartialUpdate(t *testing.T) {
+	ctx := context.Background()
+	schema := TableSchema{Columns: []ColumnDefinition{
+		{Name: "id", Type: "INTEGER", PrimaryKey: true},
+		{Name: "name", Type: "TEXT"},
+		{Name: "age", Type: "INTEGER"},
+		{Name: "city", Type: "TEXT", Nullable: true},
+	}}
+
+	for kind, table := range newTestTablesWithSchema(t, schema) {
+		t.Run(kind, func(t *testing.T) {
+			insertPeople(t, table)
+
+			// Only the given columns change
+			updated, err := table.Update(ctx, Row{"city": "Lyon"}, NewSimpleCondition("age", "<", 31))
+			if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			if updated != 2 {
+				t.Errorf("Update(age < 31) = %d, want 2", updated)
+			}
+			want := map[string]interface{}{"id": 1, "name": "alice", "age": 25, "city": "Lyon"}
+			if count, _ := table.Count(ctx, MapCondition(want)); count != 1 {
+				t.Errorf("Count(%v) after Update() = %d, want 1", want, count)
+			}
+
+			// A nil condition updates every row, and NULL is a value
+			updated, err = table.Update(ctx, Row{"city": nil}, nil)
+			if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			if updated != 4 {
+				t.Errorf("Update(nil) = %d, want 4", updated)
+			}
+			if count, _ := table.Count(ctx, NewIsNullCondition("city")); count != 4 {
+				t.Errorf("Count(city IS NULL) = %d, want 4", count)
+			}
+
+			if updated, err := table.Update(ctx, Row{"age": 50}, NewSimpleCondition("name", "=", "nobody")); err != nil || updated != 0 {
+				t.Errorf("Update() matching no rows = %d, %v, want 0", updated, err)
+			}
+			if _, err := table.Update(ctx, Row{"nmae": "x"}, nil); !errors.Is(err, ErrUnknownColumn) {
+				t.Errorf("Update() of an unknown column error = %v, want ErrUnknownColumn", err)
+			}
+			if _, err := table.Update(ctx, Row{"name": nil}, nil); !errors.Is(err, ErrNotNull) {
+				t.Errorf("Update() to NULL error = %v, want ErrNotNull", err)
+			}
+			if _, err := table.Update(ctx, Row{}, nil); err == nil {
+				t.Error("Update() without columns should fail")
+			}
+
+			// Setting the key to its current value is allowed
+			if updated, err := table.Update(ctx, Row{"id": 2, "age": 32}, NewSimpleCondition("id", "=", 2)); err != nil || updated != 1 {
+				t.Errorf("Update() keeping the key = %d, %v, want 1", updated, err)
+			}
+
+			deleted, err := table.Delete(ctx, nil)
+			if err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if deleted != 4 {
+				t.Errorf("Delete(nil) = %d, want 4", deleted)
+			}
+		})
+	}
+}
+
+func TestTableRowIDs(t *testing.T) {
+	ctx := context.Background()
+	for kind, table := range newTestTables(t) {
+		t.Run(kind, func(t *testing.T) {
+			for want := int64(1); want <= 3; want++ {
+				id, err := table.Insert(ctx, Row{"n": want})
+				if err != nil {
+					t.Fatalf("Insert() error = %v", err)
+				}
+				if id != want {
+					t.Errorf("Insert() = %d, want %d", id, want)
+				}
+			}
+
+			// IDs are not reused after a delete
+			if _, err := table.Delete(ctx, NewSimpleCondition("n", "=", 3)); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if id, err := table.Insert(ctx, Row{"n": 4}); err != nil || id != 4 {
+				t.Errorf("Insert() after Delete() = %d, %v, want 4", id, err)
+			}
+
+			it, err := table.Select(ctx, nil, nil)
+			if err != nil {
+				t.Fatalf("Select() error = %v", err)
+			}
+			defer it.Close()
+			var ids []int64
+			for it.Next() {
+				var row map[string]interface{}
+				if err := it.Scan(&row); err != nil {
+					t.Fatalf("Scan() error = %v", err)
+				}
+				if row["n"] != it.RowID() {
+					t.Errorf("RowID() = %d for row %v", it.RowID(), row)
+				}
+				ids = append(ids, it.RowID())
+			}
+			if want := []int64{1, 2, 4}; len(ids) != len(want) || ids[0] != 1 || ids[1] != 2 || ids[2] != 4 {
+				t.Errorf("row IDs = %v, want %v", ids, want)
+			}
+		})
+	}
+}
+
+func TestTableIntegerPrimaryKey(t *testing.T) {
+	ctx := context.Background()
+	schema := TableSchema{Columns: []ColumnDefinition{
+		{Name: "id", Type: "INTEGER", PrimaryKey: true},
+		{Name: "name", Type: "TEXT", Nullable: true},
+	}}
+
+	for kind, table := range newTestTablesWithSchema(t, schema) {
+		t.Run(kind, func(t *testing.T) {
+			if id, err := table.Insert(ctx, Row{"name": "alice"}); err != nil || id != 1 {
+				t.Errorf("Insert() without key = %d, %v, want 1", id, err)
+			}
+			if id, err := table.Insert(ctx, Row{"id": 10, "name": "bob"}); err != nil || id != 10 {
+				t.Errorf("Insert() with key = %d, %v, want 10", id, err)
+			}
+			if id, err := table.Insert(ctx, Row{"name": "carol"}); err != nil || id != 11 {
+				t.Errorf("Insert() after explicit key = %d, %v, want 11", id, err)
+			}
+			if _, err := table.Insert(ctx, Row{"id": 10, "name": "dave"}); !errors.Is(err, ErrAlreadyExists) {
+				t.Errorf("Insert() with duplicate key error = %v, want ErrAlreadyExists", err)
+			}
+			if _, err := table.Insert(ctx, Row{"id": "x"}); err == nil {
+				t.Error("Insert() with a non-integer key should fail")
+			}
+
+			// The generated key is stored in the row
+			if count, _ := table.Count(ctx, NewSimpleCondition("id", "=", 11)); count != 1 {
+				t.Errorf("Count(id = 11) = %d, want 1", count)
+			}
+
+			// Updates keep the key and can't change it
+			if _, err := table.Update(ctx, Row{"name": "robert"}, NewSimpleCondition("name", "=", "bob")); err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			if count, _ := table.Count(ctx, MapCondition(map[string]interface{}{"id": 10, "name": "robert"})); count != 1 {
+				t.Errorf("Count() after Update() = %d, want 1", count)
+			}
+			if _, err := table.Update(ctx, Row{"id": 12, "name": "robert"}, NewSimpleCondition("id", "=", 10)); err == nil {
+				t.Error("Update() changing the primary key should fail")
+			}
+
+			// Without a condition the key in row must match every row
+			if _, err := table.Update(ctx, Row{"id": 10, "name": "zed"}, nil); err == nil {
+				t.Error("Update() of every row to one key should fail")
+			}
+			if _, err := table.Delete(ctx, NewNotCondition(NewSimpleCondition("id", "=", 10))); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if n, err := table.Update(ctx, Row{"id": 10, "name": "zed"}, nil); err != nil || n != 1 {
+				t.Errorf("Update() with the only row's key = %d, %v, want 1", n, err)
+			}
+		})
+	}
+}
+
+func TestTableTextPrimaryKey(t *testing.T) {
+	ctx := context.Background()
+	schema := TableSchema{Columns: []ColumnDefinition{
+		{Name: "code", Type: "TEXT", PrimaryKey: true},
+	}}
+
+	for kind, table := range newTestTablesWithSchema(t, schema) {
+		t.Run(kind, func(t *testing.T) {
+			if id, err := table.Insert(ctx, Row{"code": "a"}); err != nil || id != 1 {
+				t.Errorf("Insert() = %d, %v, want 1", id, err)
+			}
+			if _, err := table.Insert(ctx, Row{"code": "a"}); !errors.Is(err, ErrAlreadyExists) {
+				t.Errorf("Insert() with duplicate key error = %v, want ErrAlreadyExists", err)
+			}
+			if _, err := table.Insert(ctx, Row{}); err == nil {
+				t.Error("Insert() without a key should fail")
+			}
+			if id, err := table.Insert(ctx, Row{"code": "b"}); err != nil || id != 2 {
+				t.Errorf("Insert() = %d, %v, want 2", id, err)
+			}
+		})
+	}
+}
+
+func TestCreateTableSchemaValidation(t *testing.T) {
+	engine := newMemoryEngine(t)
+
+	tests := []struct {
+		name    string
+		columns []ColumnDefinition
+	}{
+		{"two primary keys", []ColumnDefinition{{Name: "a", PrimaryKey: true}, {Name: "b", PrimaryKey: true}}},
+		{"duplicate column", []ColumnDefinition{{Name: "a"}, {Name: "a"}}},
+		{"empty column name", []ColumnDefinition{{Name: ""}}},
+	}
+
+	for _, tt := range tests {
+		if err := engine.CreateTable("invalid", TableSchema{Columns: tt.columns}); err == nil {
+			t.Errorf("CreateTable() with %s should fail", tt.name)
+		}
+	}
+}
+
+func TestTableSchemaEnforcement(t *testing.T) {
+	ctx := context.Background()
+	schema := TableSchema{Columns: []ColumnDefinition{
+		{Name: "id", Type: "INTEGER", PrimaryKey: true},
+		{Name: "name", Type: "TEXT"},
+		{Name: "joined", Type: "TIMESTAMP", Nullable: true},
+		{Name: "active", Type: "BOOLEAN", Default: false},
+	}}
+
+	for kind, table := range newTestTablesWithSchema(t, schema) {
+		t.Run(kind, func(t *testing.T) {
+			// A typo in a key is rejected instead of stored
+			_, err := table.Insert(ctx, Row{"name": "alice", "activ": true})
+			var colErr *ColumnError
+			if !errors.As(err, &colErr) || colErr.Column != "activ" || !errors.Is(err, ErrUnknownColumn) {
+				t.Errorf("Insert() with unknown column error = %v", err)
+			}
+			if _, err := table.Insert(ctx, Row{"joined": "2024-01-01"}); !errors.Is(err, ErrNotNull) {
+				t.Errorf("Insert() without name error = %v, want ErrNotNull", err)
+			}
+			if _, err := table.Insert(ctx, Row{"name": "alice", "joined": "soon"}); !errors.Is(err, ErrTypeMismatch) {
+				t.Errorf("Insert() with bad timestamp error = %v, want ErrTypeMismatch", err)
+			}
+			if count, _ := table.Count(ctx, nil); count != 0 {
+				t.Fatalf("Count() after rejected inserts = %d, want 0", count)
+			}
+
+			// Timestamps in any accepted form compare correctly once stored
+			cet := time.FixedZone("CET", 3600)
+			rows := []Row{
+				{"name": "alice", "joined": time.Date(2024, 3, 1, 9, 0, 0, 500, cet)},
+				{"name": "bob", "joined": "2024-03-01T08:30:00Z"},
+				{"name": "carol", "joined": "2024-02-29"},
+			}
+			for _, row := range rows {
+				if _, err := table.Insert(ctx, row); err != nil {
+					t.Fatalf("Insert(%v) error = %v", row, err)
+				}
+			}
+			after := NewSimpleCondition("joined", ">", time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC))
+			if count, _ := table.Count(ctx, after); count != 2 {
+				t.Errorf("Count(joined > 08:00Z) = %d, want 2", count)
+			}
+			if count, _ := table.Count(ctx, NewSimpleCondition("active", "=", false)); count != 3 {
+				t.Errorf("Count(active = false) = %d, want 3", count)
+			}
+
+			// Updates are checked too
+			if _, err := table.Update(ctx, Row{"name": 5}, NewSimpleCondition("id", "=", 1)); !errors.Is(err, ErrTypeMismatch) {
+				t.Errorf("Update() with bad type error = %v, want ErrTypeMismatch", err)
+			}
+			if _, err := table.Update(ctx, Row{"name": "alicia", "active": "true"}, NewSimpleCondition("id", "=", 1)); err != nil {
+				t.Errorf("Update() error = %v", err)
+			}
+			if count, _ := table.Count(ctx, MapCondition(map[string]interface{}{"id": 1, "active": true})); count != 1 {
+				t.Errorf("Count() after Update() = %d, want 1", count)
+			}
+		})
+	}
+}
@@ -2,11 +2,13 @@ package storage
 
 import (
 	"context"
+	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"math"
 	"os"
 	"path/filepath"
-	"unsafe"
+	"sync"
 
 	"github.com/google/uuid"
 )
@@ -17,6 +19,18 @@ type tensorImpl struct {
 	schema TensorSchema
 	engine Engine
 	data   []float32
+
+	// chunked tensors keep their data in chunk files instead of data, read
+	// through the engine's chunk cache
+	chunked bool
+
+	// logged counts committed transactions whose chunks of the tensor are
+	// still in the tensor log without the tensor log being enabled, which
+	// Start would replay
+	logged int
+
+	// mu guards schema, data and logged
+	mu sync.RWMutex
 }
 
 // Name returns the tensor name
@@ -26,12 +40,20 @@ func (t *tensorImpl) Name() string {
 
 // Schema returns the tensor schema
 func (t *tensorImpl) Schema() TensorSchema {
-	return t.schema
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	schema := t.schema
+	schema.Shape = append([]int(nil), t.schema.Shape...)
+	schema.Metadata = copyMetadata(t.schema.Metadata)
+	return schema
 }
 
 // Shape returns the tensor shape
 func (t *tensorImpl) Shape() []int {
-	return t.schema.Shape
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return append([]int(nil), t.schema.Shape...)
 }
 
 // DType returns the tensor data type
@@ -41,99 +63,87 @@ func (t *tensorImpl) DType() string {
 
 // StoreChunk stores a chunk of data at the specified indices
 func (t *tensorImpl) StoreChunk(ctx context.Context, indices []int, data []byte) error {
-	// Validate indices
-	if len(indices) != len(t.schema.Shape) {
-		return fmt.Errorf("indices length %d doesn't match tensor dimensions %d", len(indices), len(t.schema.Shape))
+	engine := t.registeredEngine()
+	if engine != nil && engine.walEnabled() {
+		return t.storeLogged(ctx, engine, indices, data)
 	}
 
-	for i, idx := range indices {
-		if idx < 0 {
-			return fmt.Errorf("negative index %d at dimension %d", idx, i)
-		}
-		if i < len(t.schema.ChunkSize) && idx >= (t.schema.Shape[i]+t.schema.ChunkSize[i]-1)/t.schema.ChunkSize[i] {
-			return fmt.Errorf("chunk index %d exceeds dimension %d size", idx, i)
-		}
+	t.mu.Lock()
+	if engine != nil && t.logged > 0 {
+		t.mu.Unlock()
+		return t.storeSuperseding(ctx, engine, indices, data)
 	}
+	defer t.mu.Unlock()
 
-	// Validate and convert data
-	if len(data) == 0 {
-		return fmt.Errorf("empty data provided")
+	region, values, err := t.prepareChunk(indices, data)
+	if err != nil {
+		return err
 	}
+	t.writeChunk(region, values)
 
-	floatData := bytesToFloat32Slice(data)
-	if floatData == nil {
-		return fmt.Errorf("invalid data format: byte length must be multiple of 4")
+	// Save to disk
+	if err := t.save(); err != nil {
+		return fmt.Errorf("failed to save tensor: %w", err)
 	}
+	chunkWrites.Inc()
 
-	// Calculate chunk size from schema
-	chunkSize := t.calculateChunkSize()
-	if len(floatData) != chunkSize {
-		return fmt.Errorf("data size %d doesn't match expected chunk size %d", len(floatData), chunkSize)
+	return nil
+}
+
+// prepareChunk validates a chunk write and returns the region of the chunk
+// and its values. The caller holds t.mu.
+func (t *tensorImpl) prepareChunk(indices []int, data []byte) (chunkRegion, []float32, error) {
+	region, err := t.chunkRegion(indices)
+	if err != nil {
+		return chunkRegion{}, nil, err
 	}
 
-	// Calculate starting flat index for the chunk
-	startFlatIndex := t.calculateChunkStartIndex(indices)
+	// Validate and convert data
+	if len(data) == 0 {
+		return chunkRegion{}, nil, fmt.Errorf("empty data provided")
+	}
 
-	// Validate bounds
-	if startFlatIndex < 0 || startFlatIndex+chunkSize > len(t.data) {
-		return fmt.Errorf("chunk indices out of bounds: start=%d, size=%d, tensor_size=%d",
-			startFlatIndex, chunkSize, len(t.data))
+	floatData := bytesToFloat32Slice(data)
+	if floatData == nil {
+		return chunkRegion{}, nil, fmt.Errorf("invalid data format: byte length must be multiple of 4")
+	}
+	if len(floatData) != region.size {
+		return chunkRegion{}, nil, fmt.Errorf("data size %d doesn't match expected chunk size %d", len(floatData), region.size)
 	}
 
-	// Store chunk data
 	for i, value := range floatData {
 		if math.IsNaN(float64(value)) || math.IsInf(float64(value), 0) {
-			return fmt.Errorf("invalid value at position %d: NaN or Inf", i)
+			return chunkRegion{}, nil, fmt.Errorf("invalid value at position %d: NaN or Inf", i)
 		}
-		t.data[startFlatIndex+i] = value
 	}
 
-	// Save to disk
-	if err := t.save(); err != nil {
-		return fmt.Errorf("failed to save tensor: %w", err)
-	}
-
-	return nil
+	return region, floatData, nil
 }
 
 // GetChunk retrieves a chunk of data at the specified indices
 func (t *tensorImpl) GetChunk(ctx context.Context, indices []int) ([]byte, error) {
-	// Validate indices
-	if len(indices) != len(t.schema.Shape) {
-		return nil, fmt.Errorf("indices length %d doesn't match tensor dimensions %d", len(indices), len(t.schema.Shape))
-	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
 
-	for i, idx := range indices {
-		if idx < 0 {
-			return nil, fmt.Errorf("negative index %d at dimension %d", idx, i)
-		}
-		if i < len(t.schema.ChunkSize) && idx >= (t.schema.Shape[i]+t.schema.ChunkSize[i]-1)/t.schema.ChunkSize[i] {
-			return nil, fmt.Errorf("chunk index %d exceeds dimension %d size", idx, i)
-		}
-	}
-
-	// Calculate chunk size
-	chunkSize := t.calculateChunkSize()
-	if chunkSize <= 0 {
-		return nil, fmt.Errorf("invalid chunk size: %d", chunkSize)
-	}
-
-	// Calculate starting flat index for the chunk
-	startFlatIndex := t.calculateChunkStartIndex(indices)
-
-	// Check bounds
-	if startFlatIndex < 0 || startFlatIndex+chunkSize > len(t.data) {
-		return nil, fmt.Errorf("chunk indices out of bounds: start=%d, size=%d, tensor_size=%d",
-			startFlatIndex, chunkSize, len(t.data))
+	region, err := t.chunkRegion(indices)
+	if err != nil {
+		return nil, err
 	}
 
 	// Extract chunk data
-	chunk := t.data[startFlatIndex : startFlatIndex+chunkSize]
+	chunk, err := t.readChunk(region)
+	if err != nil {
+		return nil, err
+	}
+	chunkReads.Inc()
 	return float32SliceToBytes(chunk), nil
 }
 
 // Slice returns a slice of the tensor
 func (t *tensorImpl) Slice(ctx context.Context, ranges []Range) (Tensor, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
 	// Validate ranges length
 	if len(ranges) != len(t.schema.Shape) {
 		return nil, fmt.Errorf("ranges length %d doesn't match tensor dimensions %d", len(ranges), len(t.schema.Shape))
@@ -152,9 +162,11 @@ func (t *tensorImpl) Slice(ctx context.Context, ranges []Range) (Tensor, error)
 
 	// Calculate new shape
 	newShape := make([]int, len(t.schema.Shape))
+	origin := make([]int, len(t.schema.Shape))
 	totalSize := 1
 	for i, r := range ranges {
 		newShape[i] = r.End - r.Start
+		origin[i] = r.Start
 		totalSize *= newShape[i]
 	}
 
@@ -163,72 +175,78 @@ func (t *tensorImpl) Slice(ctx context.Context, ranges []Range) (Tensor, error)
 		return nil, fmt.Errorf("slice too large: %d elements exceeds limit", totalSize)
 	}
 
+	// Only the chunks overlapping the slice are read
+	data, err := t.readRegion(origin, newShape)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create new tensor
 	newSchema := TensorSchema{
 		Shape:       newShape,
 		DType:       t.schema.DType,
 		ChunkSize:   t.schema.ChunkSize,
 		Compression: t.schema.Compression,
-		Metadata:    t.schema.Metadata,
+		Metadata:    copyMetadata(t.schema.Metadata),
 	}
 
-	newTensor := &tensorImpl{
+	return &tensorImpl{
 		name:   fmt.Sprintf("%s_slice_%s", t.name, uuid.New().String()[:8]),
 		schema: newSchema,
 		engine: t.engine,
-		data:   make([]float32, totalSize),
-	}
+		data:   data,
+	}, nil
+}
 
-	// Copy slice data using proper multi-dimensional indexing
-	for destIdx := range newTensor.data {
-		// Convert flat destination index to multi-dimensional indices in new tensor
-		destIndices := t.flatToMultiDimIndex(destIdx, newShape)
+// Reshape changes the tensor shape
+func (t *tensorImpl) Reshape(ctx context.Context, newShape []int) error {
+	if err := validateShape(newShape); err != nil {
+		return fmt.Errorf("cannot reshape: %w", err)
+	}
 
-		// Convert to source indices by adding range offsets
-		srcIndices := make([]int, len(destIndices))
-		for i := range destIndices {
-			srcIndices[i] = destIndices[i] + ranges[i].Start
+	return t.updateSchema(ctx, func(schema *TensorSchema) error {
+		// Calculate total size
+		oldSize := t.calculateSize(schema.Shape)
+		newSize := t.calculateSize(newShape)
+		if oldSize != newSize {
+			return fmt.Errorf("cannot reshape: size mismatch (old=%d, new=%d)", oldSize, newSize)
 		}
 
-		// Convert source indices to flat index in original tensor
-		srcFlatIdx := t.calculateFlatIndex(srcIndices)
-
-		// Validate source index
-		if srcFlatIdx < 0 || srcFlatIdx >= len(t.data) {
-			return nil, fmt.Errorf("source index out of bounds: %d", srcFlatIdx)
+		// Update shape, keeping the chunk sizes of the dimensions that remain
+		schema.Shape = append([]int(nil), newShape...)
+		if len(schema.ChunkSize) > len(newShape) {
+			schema.ChunkSize = schema.ChunkSize[:len(newShape)]
 		}
-
-		// Copy data
-		newTensor.data[destIdx] = t.data[srcFlatIdx]
-	}
-
-	return newTensor, nil
+		return nil
+	})
 }
 
-// Reshape changes the tensor shape
-func (t *tensorImpl) Reshape(ctx context.Context, newShape []int) error {
-	// Calculate total size
-	oldSize := 1
-	for _, dim := range t.schema.Shape {
-		oldSize *= dim
+// ApplyOperation applies a mathematical operation to the tensor
+func (t *tensorImpl) ApplyOperation(ctx context.Context, op Operation) (Tensor, error) {
+	// Operations work on all of the data, read from the chunk files into a copy
+	if t.chunked {
+		view, err := t.snapshot()
+		if err != nil {
+			return nil, err
+		}
+		if op.Operand == t {
+			op.Operand = view
+		}
+		return view.ApplyOperation(ctx, op)
 	}
 
-	newSize := 1
-	for _, dim := range newShape {
-		newSize *= dim
-	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
 
-	if oldSize != newSize {
-		return fmt.Errorf("cannot reshape: size mismatch (old=%d, new=%d)", oldSize, newSize)
+	// Work on a copy of a tensor operand so only one lock is held at a time
+	if operand, ok := op.Operand.(*tensorImpl); ok && operand != t {
+		snapshot, err := operand.snapshot()
+		if err != nil {
+			return nil, err
+		}
+		op.Operand = snapshot
 	}
 
-	// Update shape
-	t.schema.Shape = newShape
-	return t.save()
-}
-
-// ApplyOperation applies a mathematical operation to the tensor
-func (t *tensorImpl) ApplyOperation(ctx context.Context, op Operation) (Tensor, error) {
 	switch op.Type {
 	case "add":
 		return t.applyAddOperation(op)
@@ -267,22 +285,176 @@ func (t *tensorImpl) ApplyOperation(ctx context.Context, op Operation) (Tensor,
 	}
 }
 
-// Metadata returns the tensor metadata
+// Metadata returns a copy of the tensor metadata
 func (t *tensorImpl) Metadata() map[string]interface{} {
-	return t.schema.Metadata
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return copyMetadata(t.schema.Metadata)
 }
 
 // SetMetadata sets a metadata value
 func (t *tensorImpl) SetMetadata(key string, value interface{}) error {
-	if t.schema.Metadata == nil {
-		t.schema.Metadata = make(map[string]interface{})
+	return t.UpdateMetadata(map[string]interface{}{key: value})
+}
+
+// UpdateMetadata sets several metadata values at once; either all of them
+// are recorded or none is
+func (t *tensorImpl) UpdateMetadata(values map[string]interface{}) error {
+	return t.updateSchema(context.Background(), func(schema *TensorSchema) error {
+		if schema.Metadata == nil {
+			schema.Metadata = make(map[string]interface{}, len(values))
+		}
+		for key, value := range values {
+			schema.Metadata[key] = value
+		}
+		return nil
+	})
+}
+
+// updateSchema applies change to a copy of the tensor schema and, for a
+// tensor of an engine, records the result in the catalog. Chunk files are
+// only rewritten when the change moves chunk boundaries.
+func (t *tensorImpl) updateSchema(ctx context.Context, change func(*TensorSchema) error) error {
+	e := t.registeredEngine()
+	if e == nil {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		schema := t.schemaCopy()
+		if err := change(&schema); err != nil {
+			return err
+		}
+		t.schema = schema
+		return nil
+	}
+
+	// SQLite's write lock is taken before the tensor's, the order used by
+	// committing transactions and logged chunk writes
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", sqliteError("transaction", err))
 	}
-	t.schema.Metadata[key] = value
-	return t.save()
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE tensors SET updated_at = CURRENT_TIMESTAMP WHERE name = ?`, t.name)
+	if err != nil {
+		return fmt.Errorf("failed to update tensor: %w", sqliteError("catalog", err))
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("tensor %s %w", t.name, ErrNotFound)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	schema := t.schemaCopy()
+	if err := change(&schema); err != nil {
+		return err
+	}
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return fmt.Errorf("failed to serialize tensor schema: %w", err)
+	}
+	metadataJSON, err := json.Marshal(schema.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to serialize tensor metadata: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE tensors SET schema = ?, metadata = ? WHERE name = ?`,
+		string(schemaJSON), string(metadataJSON), t.name)
+	if err != nil {
+		return fmt.Errorf("failed to update tensor: %w", sqliteError("catalog", err))
+	}
+
+	// A new layout gets all chunks, including those only logged so far
+	relayout := t.chunked && layoutName(schema) != layoutName(t.schema)
+	if relayout {
+		data, err := t.readRegion(make([]int, len(t.schema.Shape)), t.schema.Shape)
+		if err != nil {
+			return err
+		}
+		if err := t.writeLayout(schema, data); err != nil {
+			return fmt.Errorf("failed to write tensor chunks: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM tensor_journal WHERE tensor_name = ?`, t.name); err != nil {
+			return fmt.Errorf("failed to clear tensor journal: %w", sqliteError("journal", err))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", sqliteError("transaction", err))
+	}
+
+	old := t.schema
+	t.schema = schema
+	if relayout {
+		e.cache.drop(t)
+		os.RemoveAll(filepath.Join(t.tensorDir(), layoutName(old)))
+	}
+	return nil
 }
 
 // Helper methods
 
+// registeredEngine returns the engine the tensor belongs to, or nil for
+// slices, operation results and tensors not yet or no longer in the engine
+func (t *tensorImpl) registeredEngine() *engineImpl {
+	e, ok := t.engine.(*engineImpl)
+	if !ok {
+		return nil
+	}
+
+	e.tensorLock.RLock()
+	defer e.tensorLock.RUnlock()
+	if e.tensors[t.name] != t {
+		return nil
+	}
+	return e
+}
+
+// schemaCopy returns a copy of the schema that can be modified. The caller
+// holds t.mu.
+func (t *tensorImpl) schemaCopy() TensorSchema {
+	schema := t.schema
+	schema.Shape = append([]int(nil), t.schema.Shape...)
+	schema.ChunkSize = append([]int(nil), t.schema.ChunkSize...)
+	schema.Metadata = copyMetadata(t.schema.Metadata)
+	return schema
+}
+
+// snapshot returns a detached copy of the tensor's schema and data, with
+// the data of a chunked tensor read from its chunks
+func (t *tensorImpl) snapshot() (*tensorImpl, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	data := append([]float32(nil), t.data...)
+	if t.chunked {
+		var err error
+		if data, err = t.readRegion(make([]int, len(t.schema.Shape)), t.schema.Shape); err != nil {
+			return nil, err
+		}
+	}
+
+	return &tensorImpl{
+		name:   t.name,
+		schema: t.schemaCopy(),
+		engine: t.engine,
+		data:   data,
+	}, nil
+}
+
+// copyMetadata returns a shallow copy of a metadata map
+func copyMetadata(metadata map[string]interface{}) map[string]interface{} {
+	if metadata == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(metadata))
+	for key, value := range metadata {
+		copied[key] = value
+	}
+	return copied
+}
+
 func (t *tensorImpl) calculateFlatIndex(indices []int) int {
 	if len(indices) != len(t.schema.Shape) {
 		return 0
@@ -309,32 +481,6 @@ func (t *tensorImpl) flatToMultiDimIndex(flatIndex int, shape []int) []int {
 	return indices
 }
 
-func (t *tensorImpl) calculateChunkSize() int {
-	if len(t.schema.ChunkSize) == 0 {
-		return 1 // Default chunk size
-	}
-
-	size := 1
-	for _, dim := range t.schema.ChunkSize {
-		size *= dim
-	}
-	return size
-}
-
-func (t *tensorImpl) calculateChunkStartIndex(indices []int) int {
-	// Convert chunk indices to flat index considering chunk size
-	chunkIndices := make([]int, len(indices))
-	for i := range indices {
-		chunkStride := t.schema.ChunkSize[i]
-		if chunkStride == 0 {
-			chunkStride = 1
-		}
-		chunkIndices[i] = indices[i] * chunkStride
-	}
-
-	return t.calculateFlatIndex(chunkIndices)
-}
-
 // broadcastShapes determines the broadcast shape for two tensors
 func broadcastShapes(shape1, shape2 []int) ([]int, error) {
 	// Pad the shorter shape with leading 1s
@@ -384,7 +530,7 @@ func (t *tensorImpl) broadcastTensor(targetShape []int) (*tensorImpl, error) {
 		DType:       t.schema.DType,
 		ChunkSize:   t.schema.ChunkSize,
 		Compression: t.schema.Compression,
-		Metadata:    t.schema.Metadata,
+		Metadata:    copyMetadata(t.schema.Metadata),
 	}
 
 	broadcasted := &tensorImpl{
@@ -428,33 +574,110 @@ func (t *tensorImpl) calculateSize(shape []int) int {
 	return size
 }
 
-func (t *tensorImpl) getFilePath() string {
-	return filepath.Join(t.engine.(*engineImpl).dataDir, "tensor_"+t.name+".bin")
+// persistent reports whether the tensor is backed by chunk files in the data
+// directory
+func (t *tensorImpl) persistent() bool {
+	return t.chunked
 }
 
+// save writes the chunks stored since the last save to their files. Each file
+// is replaced atomically, so a crash leaves either the previous or the new
+// contents of a chunk. The caller holds t.mu for writing.
 func (t *tensorImpl) save() error {
-	filePath := t.getFilePath()
+	if !t.persistent() {
+		return nil
+	}
+	return t.engine.(*engineImpl).cache.flush(t)
+}
 
-	// Convert float32 slice to bytes
-	data := float32SliceToBytes(t.data)
+// writeFileAtomic replaces the file at path with data. The data is written
+// to a temporary file in the same directory first, which is then renamed
+// over path. When durable, the data and the rename are flushed to disk.
+func writeFileAtomic(path string, data []byte, perm os.FileMode, durable bool) error {
+	dir := filepath.Dir(path)
+	f, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := f.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return err
+	}
+	if durable {
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	if !durable {
+		return nil
+	}
 
-	return os.WriteFile(filePath, data, 0644)
+	// Persist the rename itself; not every platform can sync a directory
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
 }
 
+// load prepares the tensor's files when the engine starts. Data files of
+// earlier versions, which held the whole tensor, are split into chunks, and
+// layouts left by an interrupted reshape are removed. No chunk is read.
 func (t *tensorImpl) load() error {
-	filePath := t.getFilePath()
+	if !t.persistent() {
+		return nil
+	}
 
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// File doesn't exist, initialize with zeros
-			return nil
+	legacy := t.legacyFilePath()
+	data, err := os.ReadFile(legacy)
+	if err == nil {
+		values := bytesToFloat32Slice(data)
+		if len(values) != t.calculateSize(t.schema.Shape) {
+			return fmt.Errorf("data file %s has %d bytes, want %d", legacy, len(data), 4*t.calculateSize(t.schema.Shape))
+		}
+		if err := t.writeLayout(t.schema, values); err != nil {
+			return err
 		}
+		if err := os.Remove(legacy); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
 		return err
 	}
 
-	// Convert bytes to float32 slice
-	t.data = bytesToFloat32Slice(data)
+	layouts, err := os.ReadDir(t.tensorDir())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	current := layoutName(t.schema)
+	for _, layout := range layouts {
+		path := filepath.Join(t.tensorDir(), layout.Name())
+		if layout.Name() != current {
+			os.RemoveAll(path)
+			continue
+		}
+		leftovers, _ := filepath.Glob(filepath.Join(path, "*.tmp"))
+		for _, leftover := range leftovers {
+			os.Remove(leftover)
+		}
+	}
 	return nil
 }
 
@@ -1135,17 +1358,27 @@ func (t *tensorImpl) applyCosineSimilarity(op Operation) (Tensor, error) {
 
 // Utility functions
 
+// bytesToFloat32Slice decodes little-endian float32 values, returning nil
+// when the length isn't a multiple of 4
 func bytesToFloat32Slice(data []byte) []float32 {
 	if len(data)%4 != 0 {
 		return nil
 	}
 
-	slice := (*[1 << 28]float32)(unsafe.Pointer(&data[0]))[:len(data)/4]
+	slice := make([]float32, len(data)/4)
+	for i := range slice {
+		slice[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
 	return slice
 }
 
+// float32SliceToBytes encodes float32 values as little-endian bytes
 func float32SliceToBytes(slice []float32) []byte {
-	return (*[1 << 28]byte)(unsafe.Pointer(&slice[0]))[:len(slice)*4]
+	data := make([]byte, len(slice)*4)
+	for i, value := range slice {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(value))
+	}
+	return data
 }
 
 func cosineSimilarity(a, b []float32) float32 {
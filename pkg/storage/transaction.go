@@ -3,49 +3,419 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"sort"
 )
 
-// memoryTransaction implements the Transaction interface
+// memoryTransaction implements the Transaction interface. Catalog changes go
+// through the SQL transaction; tensors created or dropped in it are only
+// added to or removed from the engine once it commits.
+//
+// Chunks stored through the transaction are recorded in the tensor log as
+// part of the SQL transaction and applied to the tensors after it commits.
+// If the process stops before they reach the tensor files, Start replays
+// them, so the chunks take effect if and only if the transaction committed.
 type memoryTransaction struct {
 	tx     *sql.Tx
 	engine *engineImpl
+
+	created map[string]*tensorImpl
+	dropped map[string]bool
+	writes  []tensorWrite
+}
+
+// tensorWrite is a chunk stored in a transaction, and its journal entry
+type tensorWrite struct {
+	id      int64
+	tensor  *tensorImpl
+	indices []int
+	data    []byte
 }
 
 // Commit commits the transaction
 func (mt *memoryTransaction) Commit(ctx context.Context) error {
-	return mt.tx.Commit()
+	e := mt.engine
+
+	// Chunks of tensors created in the transaction are applied once the
+	// tensors are added to the engine
+	var existing, created []tensorWrite
+	for _, w := range mt.writes {
+		if mt.created[w.tensor.name] == w.tensor {
+			created = append(created, w)
+		} else {
+			existing = append(existing, w)
+		}
+	}
+
+	// Tensors written in the transaction stay locked from the commit until
+	// their chunks are applied, so no other write lands in between. Until the
+	// chunks' log entries are cleared, direct writes to the tensors remove
+	// the entries of the chunks they store, or a replay would undo them.
+	written := mt.writtenTensors()
+	for _, tensor := range written {
+		tensor.mu.Lock()
+	}
+	err := mt.tx.Commit()
+	var applyErr error
+	if err == nil {
+		for _, tensor := range written {
+			tensor.logged++
+		}
+		applyErr = e.applyTensorWrites(existing)
+	}
+	for _, tensor := range written {
+		tensor.mu.Unlock()
+	}
+	if err != nil {
+		return err
+	}
+
+	e.tensorLock.Lock()
+	defer e.tensorLock.Unlock()
+
+	for name := range mt.dropped {
+		tensor, exists := e.tensors[name]
+		if !exists {
+			continue
+		}
+		e.cache.drop(tensor)
+		tensor.removeFiles()
+		delete(e.tensors, name)
+		tensorMemoryBytes.Add(-tensorBytes(tensor))
+	}
+
+	for name, tensor := range mt.created {
+		if err := tensor.removeFiles(); err != nil {
+			return fmt.Errorf("failed to remove old files of tensor %s: %w", name, err)
+		}
+		e.tensors[name] = tensor
+		tensorMemoryBytes.Add(tensorBytes(tensor))
+	}
+
+	createdWritten := mt.createdTensorsWritten()
+	for _, tensor := range createdWritten {
+		tensor.mu.Lock()
+		tensor.logged++
+		tensor.mu.Unlock()
+	}
+	if applyErr == nil {
+		applyErr = e.applyTensorWrites(created)
+	}
+	if applyErr == nil {
+		applyErr = e.clearTensorWrites(mt.writes)
+	}
+	if applyErr != nil {
+		return fmt.Errorf("transaction committed but its tensor chunks were not saved, they will be applied on restart: %w", applyErr)
+	}
+
+	for _, tensor := range append(written, createdWritten...) {
+		tensor.mu.Lock()
+		tensor.logged--
+		tensor.mu.Unlock()
+	}
+	return nil
 }
 
 // Rollback rolls back the transaction
 func (mt *memoryTransaction) Rollback(ctx context.Context) error {
+	mt.created = nil
+	mt.dropped = nil
+	mt.writes = nil
 	return mt.tx.Rollback()
 }
 
+// writtenTensors returns the engine's tensors that have chunks stored in the
+// transaction, in name order
+func (mt *memoryTransaction) writtenTensors() []*tensorImpl {
+	var tensors []*tensorImpl
+	seen := make(map[*tensorImpl]bool)
+	for _, w := range mt.writes {
+		if seen[w.tensor] || mt.created[w.tensor.name] == w.tensor {
+			continue
+		}
+		seen[w.tensor] = true
+		tensors = append(tensors, w.tensor)
+	}
+	sort.Slice(tensors, func(i, j int) bool { return tensors[i].name < tensors[j].name })
+	return tensors
+}
+
+// createdTensorsWritten returns the tensors created in the transaction that
+// have chunks stored in it
+func (mt *memoryTransaction) createdTensorsWritten() []*tensorImpl {
+	var tensors []*tensorImpl
+	seen := make(map[*tensorImpl]bool)
+	for _, w := range mt.writes {
+		if seen[w.tensor] || mt.created[w.tensor.name] != w.tensor {
+			continue
+		}
+		seen[w.tensor] = true
+		tensors = append(tensors, w.tensor)
+	}
+	return tensors
+}
+
 // CreateTable creates a new table within the transaction
 func (mt *memoryTransaction) CreateTable(name string, schema TableSchema) error {
-	// For now, just defer to the engine
-	// TODO: Implement proper transactional table creation
-	return fmt.Errorf("transactional table creation not implemented")
+	return createTable(mt.tx, name, schema)
 }
 
 // DropTable drops a table within the transaction
 func (mt *memoryTransaction) DropTable(name string) error {
-	// For now, just defer to the engine
-	// TODO: Implement proper transactional table dropping
-	return fmt.Errorf("transactional table dropping not implemented")
+	return dropTable(mt.tx, name)
+}
+
+// GetTable retrieves a table as seen from the transaction. The table is only
+// usable until the transaction ends.
+func (mt *memoryTransaction) GetTable(name string) (Table, error) {
+	schema, err := loadTableSchema(mt.tx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &memoryTable{
+		name:   name,
+		schema: schema,
+		engine: mt.engine,
+		tx:     mt.tx,
+	}, nil
 }
 
 // CreateTensor creates a new tensor within the transaction
 func (mt *memoryTransaction) CreateTensor(name string, schema TensorSchema) error {
-	// For now, just defer to the engine
-	// TODO: Implement proper transactional tensor creation
-	return fmt.Errorf("transactional tensor creation not implemented")
+	if err := validateObjectName(name); err != nil {
+		return err
+	}
+	if err := validateTensorSchema(schema); err != nil {
+		return fmt.Errorf("tensor %s: %w", name, err)
+	}
+	if mt.tensorExists(name) {
+		return fmt.Errorf("tensor %s %w", name, ErrAlreadyExists)
+	}
+
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return fmt.Errorf("failed to serialize tensor schema: %w", err)
+	}
+
+	_, err = mt.tx.Exec(
+		`INSERT INTO tensors (name, schema, metadata) VALUES (?, ?, ?)`,
+		name, string(schemaJSON), "{}",
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create tensor: %w", sqliteError("catalog", err))
+	}
+
+	if mt.created == nil {
+		mt.created = make(map[string]*tensorImpl)
+	}
+	mt.created[name] = mt.engine.newTensor(name, schema)
+
+	return nil
 }
 
 // DropTensor drops a tensor within the transaction
 func (mt *memoryTransaction) DropTensor(name string) error {
-	// For now, just defer to the engine
-	// TODO: Implement proper transactional tensor dropping
-	return fmt.Errorf("transactional tensor dropping not implemented")
+	if !mt.tensorExists(name) {
+		return fmt.Errorf("tensor %s %w", name, ErrNotFound)
+	}
+
+	// Chunks stored in the tensor go with it, including logged ones not yet
+	// in its file
+	if err := dropTensor(mt.tx, name); err != nil {
+		return err
+	}
+	kept := mt.writes[:0]
+	for _, w := range mt.writes {
+		if w.tensor.name != name {
+			kept = append(kept, w)
+		}
+	}
+	mt.writes = kept
+
+	// A tensor created in this transaction never reaches the engine
+	if _, ok := mt.created[name]; ok {
+		delete(mt.created, name)
+		return nil
+	}
+
+	if mt.dropped == nil {
+		mt.dropped = make(map[string]bool)
+	}
+	mt.dropped[name] = true
+	return nil
+}
+
+// GetTensor retrieves a tensor as seen from the transaction. Chunks stored
+// through it are visible to reads through it, and to everyone else once the
+// transaction commits. The tensor is only usable until the transaction ends.
+func (mt *memoryTransaction) GetTensor(name string) (Tensor, error) {
+	tensor := mt.tensor(name)
+	if tensor == nil {
+		return nil, fmt.Errorf("tensor %s %w", name, ErrNotFound)
+	}
+	return &txTensor{mt: mt, tensor: tensor}, nil
+}
+
+// tensorExists reports whether a tensor exists as seen from the transaction
+func (mt *memoryTransaction) tensorExists(name string) bool {
+	return mt.tensor(name) != nil
+}
+
+// tensor returns a tensor as seen from the transaction, or nil
+func (mt *memoryTransaction) tensor(name string) *tensorImpl {
+	if tensor, ok := mt.created[name]; ok {
+		return tensor
+	}
+	if mt.dropped[name] {
+		return nil
+	}
+
+	mt.engine.tensorLock.RLock()
+	defer mt.engine.tensorLock.RUnlock()
+	return mt.engine.tensors[name]
+}
+
+// txTensor is a tensor retrieved through a transaction. Stored chunks are
+// journaled in the transaction and read back from it until it commits.
+type txTensor struct {
+	mt     *memoryTransaction
+	tensor *tensorImpl
+}
+
+// Name returns the tensor name
+func (tt *txTensor) Name() string {
+	return tt.tensor.Name()
+}
+
+// Schema returns the tensor schema
+func (tt *txTensor) Schema() TensorSchema {
+	return tt.tensor.Schema()
+}
+
+// Shape returns the tensor shape
+func (tt *txTensor) Shape() []int {
+	return tt.tensor.Shape()
+}
+
+// DType returns the tensor data type
+func (tt *txTensor) DType() string {
+	return tt.tensor.DType()
+}
+
+// Metadata returns a copy of the tensor metadata
+func (tt *txTensor) Metadata() map[string]interface{} {
+	return tt.tensor.Metadata()
+}
+
+// StoreChunk validates a chunk and records it in the transaction
+func (tt *txTensor) StoreChunk(ctx context.Context, indices []int, data []byte) error {
+	tt.tensor.mu.RLock()
+	_, _, err := tt.tensor.prepareChunk(indices, data)
+	tt.tensor.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	id, err := journalChunk(ctx, tt.mt.tx, tt.tensor.name, indices, data)
+	if err != nil {
+		return err
+	}
+
+	tt.mt.writes = append(tt.mt.writes, tensorWrite{
+		id:      id,
+		tensor:  tt.tensor,
+		indices: append([]int(nil), indices...),
+		data:    append([]byte(nil), data...),
+	})
+	return nil
+}
+
+// GetChunk returns a chunk as last stored in the transaction, or else as
+// stored in the tensor
+func (tt *txTensor) GetChunk(ctx context.Context, indices []int) ([]byte, error) {
+	for i := len(tt.mt.writes) - 1; i >= 0; i-- {
+		w := tt.mt.writes[i]
+		if w.tensor == tt.tensor && equalInts(w.indices, indices) {
+			chunkReads.Inc()
+			return append([]byte(nil), w.data...), nil
+		}
+	}
+	return tt.tensor.GetChunk(ctx, indices)
+}
+
+// Slice returns a slice of the tensor including chunks stored in the
+// transaction
+func (tt *txTensor) Slice(ctx context.Context, ranges []Range) (Tensor, error) {
+	view, err := tt.view()
+	if err != nil {
+		return nil, err
+	}
+	return view.Slice(ctx, ranges)
+}
+
+// ApplyOperation applies an operation to the tensor including chunks stored
+// in the transaction
+func (tt *txTensor) ApplyOperation(ctx context.Context, op Operation) (Tensor, error) {
+	view, err := tt.view()
+	if err != nil {
+		return nil, err
+	}
+	return view.ApplyOperation(ctx, op)
+}
+
+// Reshape is not supported in a transaction
+func (tt *txTensor) Reshape(ctx context.Context, newShape []int) error {
+	return fmt.Errorf("tensor %s cannot be reshaped in a transaction", tt.tensor.name)
+}
+
+// SetMetadata is not supported in a transaction
+func (tt *txTensor) SetMetadata(key string, value interface{}) error {
+	return fmt.Errorf("tensor %s metadata cannot be set in a transaction", tt.tensor.name)
+}
+
+// UpdateMetadata is not supported in a transaction
+func (tt *txTensor) UpdateMetadata(values map[string]interface{}) error {
+	return fmt.Errorf("tensor %s metadata cannot be set in a transaction", tt.tensor.name)
+}
+
+// view returns the tensor with the chunks stored in the transaction applied,
+// as a detached copy when there are any
+func (tt *txTensor) view() (*tensorImpl, error) {
+	var view *tensorImpl
+	for _, w := range tt.mt.writes {
+		if w.tensor != tt.tensor {
+			continue
+		}
+		if view == nil {
+			snapshot, err := tt.tensor.snapshot()
+			if err != nil {
+				return nil, err
+			}
+			view = snapshot
+		}
+		region, values, err := view.prepareChunk(w.indices, w.data)
+		if err != nil {
+			return nil, err
+		}
+		view.writeChunk(region, values)
+	}
+	if view == nil {
+		return tt.tensor, nil
+	}
+	return view, nil
+}
+
+// equalInts reports whether two int slices are equal
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
 }
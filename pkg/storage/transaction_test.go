@@ -0,0 +1,318 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/telumdb/telumdb/internal/config"
+)
+
+// newHybridEngine starts a hybrid engine in dataDir
+func newHybridEngine(t *testing.T, dataDir string) Engine {
+	t.Helper()
+
+	engine, err := CreateEngine(config.StorageConfig{Engine: "hybrid", DataDir: dataDir})
+	if err != nil {
+		t.Fatalf("CreateEngine() error = %v", err)
+	}
+	if err := engine.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	return engine
+}
+
+// readChunk returns a chunk of a tensor as float32 values
+func readChunk(t *testing.T, tensor Tensor, indices []int) []float32 {
+	t.Helper()
+
+	data, err := tensor.GetChunk(context.Background(), indices)
+	if err != nil {
+		t.Fatalf("GetChunk(%v) error = %v", indices, err)
+	}
+	return bytesToFloat32Slice(data)
+}
+
+func TestTransactionTensorWrites(t *testing.T) {
+	ctx := context.Background()
+	dataDir := t.TempDir()
+	engine := newHybridEngine(t, dataDir)
+	defer func() { engine.Shutdown(ctx) }()
+
+	if err := engine.CreateTable("docs", TableSchema{}); err != nil {
+		t.Fatalf("CreateTable() error = %v", err)
+	}
+	if err := engine.CreateTensor("embeddings", TensorSchema{Shape: []int{4}, DType: "float32", ChunkSize: []int{2}}); err != nil {
+		t.Fatalf("CreateTensor() error = %v", err)
+	}
+	tensor, _ := engine.GetTensor("embeddings")
+	chunk := float32SliceToBytes([]float32{1, 2})
+
+	// A rolled back transaction leaves rows and chunks untouched
+	tx, err := engine.BeginTransaction(ctx)
+	if err != nil {
+		t.Fatalf("BeginTransaction() error = %v", err)
+	}
+	docs, _ := tx.GetTable("docs")
+	if _, err := docs.Insert(ctx, Row{"title": "a"}); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+	txTensor, err := tx.GetTensor("embeddings")
+	if err != nil {
+		t.Fatalf("GetTensor() in transaction error = %v", err)
+	}
+	if err := txTensor.StoreChunk(ctx, []int{1}, chunk); err != nil {
+		t.Fatalf("StoreChunk() in transaction error = %v", err)
+	}
+	if err := txTensor.StoreChunk(ctx, []int{1}, []byte{1, 2, 3}); err == nil {
+		t.Error("StoreChunk() of a malformed chunk should fail")
+	}
+	if got := readChunk(t, txTensor, []int{1}); got[0] != 1 || got[1] != 2 {
+		t.Errorf("GetChunk() in transaction = %v, want [1 2]", got)
+	}
+	if got := readChunk(t, tensor, []int{1}); got[0] != 0 {
+		t.Errorf("GetChunk() outside an open transaction = %v, want zeros", got)
+	}
+	sum, err := txTensor.ApplyOperation(ctx, Operation{Type: "sum"})
+	if err != nil {
+		t.Fatalf("ApplyOperation() in transaction error = %v", err)
+	}
+	if got := sum.(*tensorImpl).data[0]; got != 3 {
+		t.Errorf("sum in transaction = %v, want 3", got)
+	}
+	if err := tx.Rollback(ctx); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+
+	if got := readChunk(t, tensor, []int{1}); got[0] != 0 || got[1] != 0 {
+		t.Errorf("GetChunk() after rollback = %v, want zeros", got)
+	}
+	table, _ := engine.GetTable("docs")
+	if count, _ := table.Count(ctx, nil); count != 0 {
+		t.Errorf("Count() after rollback = %d, want 0", count)
+	}
+
+	// A committed transaction applies both
+	tx, err = engine.BeginTransaction(ctx)
+	if err != nil {
+		t.Fatalf("BeginTransaction() error = %v", err)
+	}
+	docs, _ = tx.GetTable("docs")
+	if _, err := docs.Insert(ctx, Row{"title": "b"}); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+	txTensor, _ = tx.GetTensor("embeddings")
+	if err := txTensor.StoreChunk(ctx, []int{1}, chunk); err != nil {
+		t.Fatalf("StoreChunk() in transaction error = %v", err)
+	}
+	if err := txTensor.Reshape(ctx, []int{2, 2}); err == nil {
+		t.Error("Reshape() in a transaction should fail")
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	if got := readChunk(t, tensor, []int{1}); got[0] != 1 || got[1] != 2 {
+		t.Errorf("GetChunk() after commit = %v, want [1 2]", got)
+	}
+	if count, _ := table.Count(ctx, nil); count != 1 {
+		t.Errorf("Count() after commit = %d, want 1", count)
+	}
+
+	// Chunks of a tensor dropped in the transaction are discarded with it
+	tx, err = engine.BeginTransaction(ctx)
+	if err != nil {
+		t.Fatalf("BeginTransaction() error = %v", err)
+	}
+	txTensor, _ = tx.GetTensor("embeddings")
+	if err := txTensor.StoreChunk(ctx, []int{0}, chunk); err != nil {
+		t.Fatalf("StoreChunk() in transaction error = %v", err)
+	}
+	if err := tx.DropTensor("embeddings"); err != nil {
+		t.Fatalf("DropTensor() in transaction error = %v", err)
+	}
+	if _, err := tx.GetTensor("embeddings"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetTensor() after DropTensor() error = %v, want ErrNotFound", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	var pending int
+	db := engine.(*HybridEngine).db
+	if err := db.QueryRow(`SELECT COUNT(*) FROM tensor_journal`).Scan(&pending); err != nil {
+		t.Fatalf("QueryRow() error = %v", err)
+	}
+	if pending != 0 {
+		t.Errorf("%d journal entries left after commit", pending)
+	}
+}
+
+func TestTensorJournalReplay(t *testing.T) {
+	ctx := context.Background()
+	dataDir := t.TempDir()
+	engine := newHybridEngine(t, dataDir)
+
+	if err := engine.CreateTensor("embeddings", TensorSchema{Shape: []int{4}, DType: "float32", ChunkSize: []int{2}}); err != nil {
+		t.Fatalf("CreateTensor() error = %v", err)
+	}
+
+	// Commit the SQL transaction but stop before its chunks are applied, as
+	// a crash would
+	tx, err := engine.BeginTransaction(ctx)
+	if err != nil {
+		t.Fatalf("BeginTransaction() error = %v", err)
+	}
+	txTensor, _ := tx.GetTensor("embeddings")
+	if err := txTensor.StoreChunk(ctx, []int{1}, float32SliceToBytes([]float32{5, 6})); err != nil {
+		t.Fatalf("StoreChunk() in transaction error = %v", err)
+	}
+	if err := tx.(*memoryTransaction).tx.Commit(); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	// A save interrupted halfway leaves a temporary file behind
+	leftover := filepath.Join(dataDir, "tensor_embeddings.bin.123.tmp")
+	if err := os.WriteFile(leftover, []byte{1}, 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := engine.(*HybridEngine).closeDB(); err != nil {
+		t.Fatalf("closeDB() error = %v", err)
+	}
+
+	engine = newHybridEngine(t, dataDir)
+	defer engine.Shutdown(ctx)
+
+	tensor, err := engine.GetTensor("embeddings")
+	if err != nil {
+		t.Fatalf("GetTensor() error = %v", err)
+	}
+	if got := readChunk(t, tensor, []int{1}); got[0] != 5 || got[1] != 6 {
+		t.Errorf("GetChunk() after replay = %v, want [5 6]", got)
+	}
+	if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+		t.Errorf("temporary file left after restart: %v", err)
+	}
+
+	var pending int
+	if err := engine.(*HybridEngine).db.QueryRow(`SELECT COUNT(*) FROM tensor_journal`).Scan(&pending); err != nil {
+		t.Fatalf("QueryRow() error = %v", err)
+	}
+	if pending != 0 {
+		t.Errorf("%d journal entries left after replay", pending)
+	}
+}
+
+func TestDirectWriteSupersedesJournal(t *testing.T) {
+	ctx := context.Background()
+	dataDir := t.TempDir()
+	engine := newHybridEngine(t, dataDir)
+
+	if err := engine.CreateTensor("embeddings", TensorSchema{Shape: []int{4}, DType: "float32", ChunkSize: []int{2}}); err != nil {
+		t.Fatalf("CreateTensor() error = %v", err)
+	}
+
+	// Keep the committed chunk in the journal, as a failed apply would
+	db := engine.(*HybridEngine).db
+	if _, err := db.Exec(`CREATE TRIGGER keep_journal BEFORE DELETE ON tensor_journal BEGIN SELECT RAISE(ABORT, 'journal is read-only'); END`); err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	tx, err := engine.BeginTransaction(ctx)
+	if err != nil {
+		t.Fatalf("BeginTransaction() error = %v", err)
+	}
+	txTensor, _ := tx.GetTensor("embeddings")
+	if err := txTensor.StoreChunk(ctx, []int{1}, float32SliceToBytes([]float32{5, 6})); err != nil {
+		t.Fatalf("StoreChunk() in transaction error = %v", err)
+	}
+	if err := tx.Commit(ctx); err == nil {
+		t.Fatal("Commit() should report the chunks left in the journal")
+	}
+	if _, err := db.Exec(`DROP TRIGGER keep_journal`); err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+
+	tensor, _ := engine.GetTensor("embeddings")
+	if err := tensor.StoreChunk(ctx, []int{1}, float32SliceToBytes([]float32{7, 8})); err != nil {
+		t.Fatalf("StoreChunk() error = %v", err)
+	}
+	// Stop without the shutdown checkpoint, as a crash would
+	if err := engine.(*HybridEngine).closeDB(); err != nil {
+		t.Fatalf("closeDB() error = %v", err)
+	}
+
+	engine = newHybridEngine(t, dataDir)
+	defer engine.Shutdown(ctx)
+
+	tensor, err = engine.GetTensor("embeddings")
+	if err != nil {
+		t.Fatalf("GetTensor() error = %v", err)
+	}
+	if got := readChunk(t, tensor, []int{1}); got[0] != 7 || got[1] != 8 {
+		t.Errorf("GetChunk() after restart = %v, want [7 8]", got)
+	}
+
+	var pending int
+	if err := engine.(*HybridEngine).db.QueryRow(`SELECT COUNT(*) FROM tensor_journal`).Scan(&pending); err != nil {
+		t.Fatalf("QueryRow() error = %v", err)
+	}
+	if pending != 0 {
+		t.Errorf("%d journal entries left after restart", pending)
+	}
+}
+
+func TestDropTensorAtomic(t *testing.T) {
+	ctx := context.Background()
+	dataDir := t.TempDir()
+	engine := newHybridEngine(t, dataDir)
+	defer engine.Shutdown(ctx)
+
+	if err := engine.CreateTensor("embeddings", TensorSchema{Shape: []int{4}, DType: "float32", ChunkSize: []int{2}}); err != nil {
+		t.Fatalf("CreateTensor() error = %v", err)
+	}
+	tensor, _ := engine.GetTensor("embeddings")
+	if err := tensor.StoreChunk(ctx, []int{0}, float32SliceToBytes([]float32{1, 2})); err != nil {
+		t.Fatalf("StoreChunk() error = %v", err)
+	}
+
+	// Make the last of the catalog deletes fail
+	db := engine.(*HybridEngine).db
+	statements := []string{
+		`INSERT INTO tensor_journal (tensor_name, chunk, data) VALUES ('embeddings', '[1]', x'0000404000008040')`,
+		`CREATE TRIGGER keep_journal BEFORE DELETE ON tensor_journal BEGIN SELECT RAISE(ABORT, 'journal is read-only'); END`,
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("Exec(%q) error = %v", statement, err)
+		}
+	}
+
+	if err := engine.DropTensor("embeddings"); err == nil {
+		t.Fatal("DropTensor() should fail")
+	}
+	var rows int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM tensors WHERE name = 'embeddings'`).Scan(&rows); err != nil {
+		t.Fatalf("QueryRow() error = %v", err)
+	}
+	if rows != 1 {
+		t.Errorf("%d catalog rows after a failed DropTensor(), want 1", rows)
+	}
+	if got := readChunk(t, tensor, []int{0}); got[0] != 1 || got[1] != 2 {
+		t.Errorf("GetChunk() after a failed DropTensor() = %v, want [1 2]", got)
+	}
+	if _, err := engine.GetTensor("embeddings"); err != nil {
+		t.Errorf("GetTensor() after a failed DropTensor() error = %v", err)
+	}
+
+	if _, err := db.Exec(`DROP TRIGGER keep_journal`); err != nil {
+		t.Fatalf("Exec() error = %v", err)
+	}
+	if err := engine.DropTensor("embeddings"); err != nil {
+		t.Fatalf("DropTensor() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "tensor_embeddings")); !os.IsNotExist(err) {
+		t.Errorf("tensor files kept after DropTensor(): %v", err)
+	}
+}
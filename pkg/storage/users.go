@@ -0,0 +1,205 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// ErrInvalidCredentials is returned when a username or password doesn't match
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// userNamePattern restricts user names to plain identifiers
+var userNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,62}$`)
+
+// User represents a database user account
+type User struct {
+	Name      string
+	Superuser bool
+	CreatedAt time.Time
+}
+
+// UserStore is implemented by engines that persist user accounts
+type UserStore interface {
+	CreateUser(name, password string, superuser bool) error
+	DropUser(name string) error
+	SetPassword(name, password string) error
+	Authenticate(name, password string) (*User, error)
+	ListUsers() ([]User, error)
+}
+
+// validateUserName checks that a user name is a valid identifier
+func validateUserName(name string) error {
+	if !userNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid user name: %q", name)
+	}
+	return nil
+}
+
+// CreateUser creates a new user account with a hashed password
+func (e *engineImpl) CreateUser(name, password string, superuser bool) error {
+	if !e.started {
+		return fmt.Errorf("engine not started")
+	}
+	if err := validateUserName(name); err != nil {
+		return err
+	}
+	if password == "" {
+		return fmt.Errorf("password cannot be empty")
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	var exists int
+	if err := e.db.QueryRow(`SELECT COUNT(*) FROM users WHERE name = ?`, name).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check user: %w", err)
+	}
+	if exists > 0 {
+		return fmt.Errorf("user already exists: %s", name)
+	}
+
+	_, err = e.db.Exec(
+		`INSERT INTO users (name, password_hash, superuser) VALUES (?, ?, ?)`,
+		name, hash, superuser,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return nil
+}
+
+// DropUser removes a user account
+func (e *engineImpl) DropUser(name string) error {
+	if !e.started {
+		return fmt.Errorf("engine not started")
+	}
+
+	tx, err := e.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`DELETE FROM users WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("failed to drop user: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("user not found: %s", name)
+	}
+
+	// Foreign keys aren't enforced by default in SQLite, so clean up grants explicitly
+	if _, err := tx.Exec(`DELETE FROM privileges WHERE grantee = ?`, name); err != nil {
+		return fmt.Errorf("failed to delete user privileges: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// SetPassword replaces a user's password
+func (e *engineImpl) SetPassword(name, password string) error {
+	if !e.started {
+		return fmt.Errorf("engine not started")
+	}
+	if password == "" {
+		return fmt.Errorf("password cannot be empty")
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	res, err := e.db.Exec(
+		`UPDATE users SET password_hash = ?, updated_at = CURRENT_TIMESTAMP WHERE name = ?`,
+		hash, name,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set password: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("user not found: %s", name)
+	}
+
+	return nil
+}
+
+// Authenticate verifies a user's password and returns the account
+func (e *engineImpl) Authenticate(name, password string) (*User, error) {
+	if !e.started {
+		return nil, fmt.Errorf("engine not started")
+	}
+
+	var (
+		hash      string
+		superuser bool
+		createdAt time.Time
+	)
+	err := e.db.QueryRow(
+		`SELECT password_hash, superuser, created_at FROM users WHERE name = ?`,
+		name,
+	).Scan(&hash, &superuser, &createdAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// Burn the same amount of time as a real check so unknown
+			// users can't be told apart from wrong passwords
+			verifyPassword(password, dummyPasswordHash())
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+
+	if !verifyPassword(password, hash) {
+		return nil, ErrInvalidCredentials
+	}
+
+	return &User{
+		Name:      name,
+		Superuser: superuser,
+		CreatedAt: createdAt,
+	}, nil
+}
+
+// ListUsers returns all user accounts
+func (e *engineImpl) ListUsers() ([]User, error) {
+	if !e.started {
+		return nil, fmt.Errorf("engine not started")
+	}
+
+	rows, err := e.db.Query(`SELECT name, superuser, created_at FROM users ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		var user User
+		if err := rows.Scan(&user.Name, &user.Superuser, &user.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// dummyPasswordHash returns a hash that is verified against when a user doesn't exist
+func dummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = hashPassword("telumdb")
+	})
+	return dummyHash
+}
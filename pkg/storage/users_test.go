@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/telumdb/telumdb/internal/config"
+)
+
+// newTestEngine starts an engineImpl backed by a temporary data directory
+func newTestEngine(t *testing.T) *engineImpl {
+	t.Helper()
+
+	cfg := &config.Config{
+		Storage: config.StorageConfig{
+			DataDir: t.TempDir(),
+		},
+	}
+
+	engine, err := NewEngine(cfg)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+
+	ctx := context.Background()
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { engine.Shutdown(ctx) })
+
+	return engine.(*engineImpl)
+}
+
+func TestPasswordHashing(t *testing.T) {
+	hash, err := hashPassword("s3cret")
+	if err != nil {
+		t.Fatalf("hashPassword() error = %v", err)
+	}
+
+	if !verifyPassword("s3cret", hash) {
+		t.Error("verifyPassword() rejected the correct password")
+	}
+	if verifyPassword("wrong", hash) {
+		t.Error("verifyPassword() accepted a wrong password")
+	}
+	if verifyPassword("s3cret", "plaintext") {
+		t.Error("verifyPassword() accepted a malformed hash")
+	}
+
+	other, _ := hashPassword("s3cret")
+	if other == hash {
+		t.Error("hashPassword() should use a random salt")
+	}
+}
+
+func TestUserStore(t *testing.T) {
+	engine := newTestEngine(t)
+
+	if err := engine.CreateUser("alice", "pw1", false); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	if err := engine.CreateUser("alice", "pw2", false); err == nil {
+		t.Error("CreateUser() should reject duplicate users")
+	}
+	if err := engine.CreateUser("bad name", "pw", false); err == nil {
+		t.Error("CreateUser() should reject invalid names")
+	}
+
+	user, err := engine.Authenticate("alice", "pw1")
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if user.Name != "alice" || user.Superuser {
+		t.Errorf("Authenticate() = %+v, want non-superuser alice", user)
+	}
+
+	if _, err := engine.Authenticate("alice", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Authenticate() with wrong password error = %v, want ErrInvalidCredentials", err)
+	}
+	if _, err := engine.Authenticate("nobody", "pw1"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Authenticate() with unknown user error = %v, want ErrInvalidCredentials", err)
+	}
+
+	if err := engine.SetPassword("alice", "pw3"); err != nil {
+		t.Fatalf("SetPassword() error = %v", err)
+	}
+	if _, err := engine.Authenticate("alice", "pw3"); err != nil {
+		t.Errorf("Authenticate() after SetPassword() error = %v", err)
+	}
+
+	users, err := engine.ListUsers()
+	if err != nil {
+		t.Fatalf("ListUsers() error = %v", err)
+	}
+	if len(users) != 1 || users[0].Name != "alice" {
+		t.Errorf("ListUsers() = %+v, want [alice]", users)
+	}
+
+	if err := engine.DropUser("alice"); err != nil {
+		t.Fatalf("DropUser() error = %v", err)
+	}
+	if err := engine.DropUser("alice"); err == nil {
+		t.Error("DropUser() should fail for missing users")
+	}
+}
@@ -0,0 +1,369 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// The tensor log
+//
+// With StorageConfig.WALEnabled, SQLite runs in WAL mode and chunks stored in
+// a tensor are appended to the tensor_journal table instead of being written
+// to their chunk files. The chunk lands in memory as the log entry commits, so
+// the log holds every chunk not yet in its file, in the order they were
+// stored. A background checkpointer writes those chunks every
+// CheckpointInterval and drops the entries they cover, and Start replays whatever is left after
+// the process was killed.
+//
+// StorageConfig.SyncMode selects what is flushed to disk:
+//
+//   - off: nothing is synced; a crash of the machine can lose recent writes
+//   - normal: chunk files are synced when saved and SQLite syncs at WAL
+//     checkpoints; writes survive the process being killed
+//   - full: SQLite also syncs the log on every commit; writes survive power
+//     loss as soon as they return
+
+// syncModes maps sync modes to SQLite's synchronous setting
+var syncModes = map[string]string{
+	"off":    "OFF",
+	"normal": "NORMAL",
+	"full":   "FULL",
+}
+
+// parseSyncMode validates a sync mode, which defaults to normal
+func parseSyncMode(mode string) (string, error) {
+	if mode == "" {
+		return "normal", nil
+	}
+	mode = strings.ToLower(mode)
+	if _, ok := syncModes[mode]; !ok {
+		return "", fmt.Errorf("invalid sync mode %q: must be off, normal or full", mode)
+	}
+	return mode, nil
+}
+
+// walEnabled reports whether chunks go through the tensor log. In-memory
+// engines have nothing to recover and never log.
+func (e *engineImpl) walEnabled() bool {
+	return !e.inMemory && e.config.Storage.WALEnabled
+}
+
+// syncFiles reports whether chunk files are synced to disk when saved
+func (e *engineImpl) syncFiles() bool {
+	return e.syncMode != "off"
+}
+
+// journalChunk appends a chunk to the tensor log and returns its entry ID
+func journalChunk(ctx context.Context, tx *sql.Tx, name string, indices []int, data []byte) (int64, error) {
+	chunkJSON, err := json.Marshal(indices)
+	if err != nil {
+		return 0, fmt.Errorf("failed to serialize chunk indices: %w", err)
+	}
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO tensor_journal (tensor_name, chunk, data) VALUES (?, ?, ?)`,
+		name, string(chunkJSON), data,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to journal chunk: %w", sqliteError("journal", err))
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to journal chunk: %w", sqliteError("journal", err))
+	}
+	return id, nil
+}
+
+// storeLogged stores a chunk through the tensor log. The chunk file catches
+// up at the next checkpoint.
+func (t *tensorImpl) storeLogged(ctx context.Context, e *engineImpl, indices []int, data []byte) error {
+	t.mu.RLock()
+	_, _, err := t.prepareChunk(indices, data)
+	t.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", sqliteError("transaction", err))
+	}
+	defer tx.Rollback()
+
+	if _, err := journalChunk(ctx, tx, t.name, indices, data); err != nil {
+		return err
+	}
+
+	// The entry is written before the tensor is locked and committed while it
+	// is, like a transaction storing chunks, so entries are logged in the
+	// order the chunks land in memory
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	region, values, err := t.prepareChunk(indices, data)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit chunk: %w", sqliteError("journal", err))
+	}
+	t.writeChunk(region, values)
+	chunkWrites.Inc()
+
+	return nil
+}
+
+// storeSuperseding stores a chunk directly in a tensor that has chunks of
+// committed transactions left in the tensor log. Replaying the log would
+// overwrite the chunk with an older value, so the entries of the chunk are
+// removed in the same SQL transaction that confirms the write.
+func (t *tensorImpl) storeSuperseding(ctx context.Context, e *engineImpl, indices []int, data []byte) error {
+	chunkJSON, err := json.Marshal(indices)
+	if err != nil {
+		return fmt.Errorf("failed to serialize chunk indices: %w", err)
+	}
+
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", sqliteError("transaction", err))
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM tensor_journal WHERE tensor_name = ? AND chunk = ?`,
+		t.name, string(chunkJSON),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to clear tensor journal: %w", sqliteError("journal", err))
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	region, values, err := t.prepareChunk(indices, data)
+	if err != nil {
+		return err
+	}
+	t.writeChunk(region, values)
+	if err := t.save(); err != nil {
+		return fmt.Errorf("failed to save tensor: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to clear tensor journal: %w", sqliteError("journal", err))
+	}
+	chunkWrites.Inc()
+
+	return nil
+}
+
+// applyChunks stores logged chunks in their tensors and returns the tensors
+// changed. The caller holds the tensors' locks.
+func applyChunks(writes []tensorWrite) ([]*tensorImpl, error) {
+	var changed []*tensorImpl
+	seen := make(map[*tensorImpl]bool)
+	for _, w := range writes {
+		region, values, err := w.tensor.prepareChunk(w.indices, w.data)
+		if err != nil {
+			return changed, fmt.Errorf("tensor %s chunk %v: %w", w.tensor.name, w.indices, err)
+		}
+		w.tensor.writeChunk(region, values)
+		chunkWrites.Inc()
+
+		if !seen[w.tensor] {
+			seen[w.tensor] = true
+			changed = append(changed, w.tensor)
+		}
+	}
+	return changed, nil
+}
+
+// applyTensorWrites stores the chunks of a committed transaction in their
+// tensors. With the tensor log they stay logged until the next checkpoint;
+// otherwise the tensors are saved, and clearTensorWrites removes the entries
+// once all of them are. The caller holds the tensors' locks.
+func (e *engineImpl) applyTensorWrites(writes []tensorWrite) error {
+	if len(writes) == 0 {
+		return nil
+	}
+
+	changed, err := applyChunks(writes)
+	if err != nil {
+		return err
+	}
+	if e.walEnabled() {
+		return nil
+	}
+
+	for _, tensor := range changed {
+		if err := tensor.save(); err != nil {
+			return fmt.Errorf("failed to save tensor %s: %w", tensor.name, err)
+		}
+	}
+	return nil
+}
+
+// clearTensorWrites removes the log entries of a committed transaction whose
+// chunks were saved by applyTensorWrites
+func (e *engineImpl) clearTensorWrites(writes []tensorWrite) error {
+	if len(writes) == 0 || e.walEnabled() {
+		return nil
+	}
+
+	// Entries of one transaction have consecutive IDs, as SQLite serializes
+	// write transactions
+	_, err := e.db.Exec(`DELETE FROM tensor_journal WHERE id BETWEEN ? AND ?`, writes[0].id, writes[len(writes)-1].id)
+	if err != nil {
+		return fmt.Errorf("failed to clear tensor journal: %w", sqliteError("journal", err))
+	}
+	return nil
+}
+
+// replayTensorJournal applies the logged chunks that were not yet in their
+// files when the engine last stopped, saves the tensors and clears
+// the log
+func (e *engineImpl) replayTensorJournal() error {
+	rows, err := e.db.Query(`SELECT id, tensor_name, chunk, data FROM tensor_journal ORDER BY id`)
+	if err != nil {
+		return fmt.Errorf("failed to read tensor journal: %w", sqliteError("journal", err))
+	}
+	defer rows.Close()
+
+	var writes []tensorWrite
+	for rows.Next() {
+		var w tensorWrite
+		var name, chunkJSON string
+		if err := rows.Scan(&w.id, &name, &chunkJSON, &w.data); err != nil {
+			return fmt.Errorf("failed to scan tensor journal: %w", err)
+		}
+		if err := json.Unmarshal([]byte(chunkJSON), &w.indices); err != nil {
+			return fmt.Errorf("failed to parse tensor journal: %w", err)
+		}
+
+		w.tensor = e.tensors[name]
+		if w.tensor == nil {
+			e.logger.Warn("Skipping journaled chunk of missing tensor", zap.String("name", name))
+			continue
+		}
+		writes = append(writes, w)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read tensor journal: %w", sqliteError("journal", err))
+	}
+	rows.Close()
+
+	changed, err := applyChunks(writes)
+	if err != nil {
+		return err
+	}
+	for _, tensor := range changed {
+		if err := tensor.save(); err != nil {
+			return fmt.Errorf("failed to save tensor %s: %w", tensor.name, err)
+		}
+	}
+	if _, err := e.db.Exec(`DELETE FROM tensor_journal`); err != nil {
+		return fmt.Errorf("failed to clear tensor journal: %w", sqliteError("journal", err))
+	}
+	if len(writes) > 0 {
+		e.logger.Info("Replayed tensor journal", zap.Int("chunks", len(writes)))
+	}
+	return nil
+}
+
+// checkpoint saves the chunks stored since tensors were last saved, drops the
+// log entries their files now cover and copies the SQLite WAL back into the
+// database file
+func (e *engineImpl) checkpoint() error {
+	e.checkpointMu.Lock()
+	defer e.checkpointMu.Unlock()
+
+	// Every entry up to last has reached memory by the time its tensor can
+	// be locked, so saving the tensor covers it
+	var last int64
+	if err := e.db.QueryRow(`SELECT COALESCE(MAX(id), 0) FROM tensor_journal`).Scan(&last); err != nil {
+		return fmt.Errorf("failed to read tensor journal: %w", sqliteError("journal", err))
+	}
+
+	var names []string
+	var saveErr error
+	e.tensorLock.RLock()
+	for name, tensor := range e.tensors {
+		tensor.mu.Lock()
+		err := tensor.save()
+		tensor.mu.Unlock()
+		if err != nil {
+			saveErr = fmt.Errorf("failed to save tensor %s: %w", name, err)
+			break
+		}
+		names = append(names, name)
+	}
+	e.tensorLock.RUnlock()
+
+	// Entries of tensors that were not saved are kept for replay
+	namesJSON, err := json.Marshal(names)
+	if err != nil {
+		return fmt.Errorf("failed to serialize tensor names: %w", err)
+	}
+	_, err = e.db.Exec(
+		`DELETE FROM tensor_journal WHERE id <= ? AND tensor_name IN (SELECT value FROM json_each(?))`,
+		last, string(namesJSON),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to clear tensor journal: %w", sqliteError("journal", err))
+	}
+	if saveErr != nil {
+		return saveErr
+	}
+
+	if e.walEnabled() {
+		if _, err := e.db.Exec(`PRAGMA wal_checkpoint(PASSIVE)`); err != nil {
+			return fmt.Errorf("failed to checkpoint database: %w", sqliteError("checkpoint", err))
+		}
+	}
+	return nil
+}
+
+// startCheckpointer runs checkpoint every CheckpointInterval until
+// stopCheckpointer is called
+func (e *engineImpl) startCheckpointer() {
+	interval := e.config.Storage.CheckpointInterval
+	if !e.walEnabled() || interval <= 0 {
+		return
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	e.stopCheckpoint, e.checkpointDone = stop, done
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := e.checkpoint(); err != nil {
+					e.logger.Error("Checkpoint failed", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// stopCheckpointer stops the background checkpointer and waits for a
+// running checkpoint to finish
+func (e *engineImpl) stopCheckpointer() {
+	if e.stopCheckpoint == nil {
+		return
+	}
+	close(e.stopCheckpoint)
+	<-e.checkpointDone
+	e.stopCheckpoint, e.checkpointDone = nil, nil
+}
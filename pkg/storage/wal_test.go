@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/telumdb/telumdb/internal/config"
+)
+
+// newLoggedEngine starts a hybrid engine with the tensor log enabled
+func newLoggedEngine(t *testing.T, dataDir string, interval time.Duration) *HybridEngine {
+	t.Helper()
+
+	engine, err := NewHybridEngine(config.StorageConfig{
+		Engine:             "hybrid",
+		DataDir:            dataDir,
+		SyncMode:           "full",
+		WALEnabled:         true,
+		CheckpointInterval: interval,
+	})
+	if err != nil {
+		t.Fatalf("NewHybridEngine() error = %v", err)
+	}
+	if err := engine.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	return engine
+}
+
+// pendingChunks returns the number of entries in the tensor log
+func pendingChunks(t *testing.T, engine *HybridEngine) int {
+	t.Helper()
+
+	var pending int
+	if err := engine.db.QueryRow(`SELECT COUNT(*) FROM tensor_journal`).Scan(&pending); err != nil {
+		t.Fatalf("QueryRow() error = %v", err)
+	}
+	return pending
+}
+
+func TestTensorLog(t *testing.T) {
+	ctx := context.Background()
+	dataDir := t.TempDir()
+	engine := newLoggedEngine(t, dataDir, 0)
+
+	var journalMode string
+	var synchronous int
+	if err := engine.db.QueryRow(`PRAGMA journal_mode`).Scan(&journalMode); err != nil {
+		t.Fatalf("QueryRow() error = %v", err)
+	}
+	if err := engine.db.QueryRow(`PRAGMA synchronous`).Scan(&synchronous); err != nil {
+		t.Fatalf("QueryRow() error = %v", err)
+	}
+	if journalMode != "wal" || synchronous != 2 {
+		t.Errorf("journal_mode = %s, synchronous = %d, want wal and 2 (FULL)", journalMode, synchronous)
+	}
+
+	if err := engine.CreateTensor("embeddings", TensorSchema{Shape: []int{4}, DType: "float32", ChunkSize: []int{2}}); err != nil {
+		t.Fatalf("CreateTensor() error = %v", err)
+	}
+	tensor, _ := engine.GetTensor("embeddings")
+	path := filepath.Join(dataDir, "tensor_embeddings", "layout_4_2", "c0.bin")
+
+	// Stored chunks are logged and the chunk file is left alone
+	if err := tensor.StoreChunk(ctx, []int{0}, float32SliceToBytes([]float32{1, 2})); err != nil {
+		t.Fatalf("StoreChunk() error = %v", err)
+	}
+	if err := tensor.StoreChunk(ctx, []int{1}, []byte{1}); err == nil {
+		t.Error("StoreChunk() of a malformed chunk should fail")
+	}
+	if got := readChunk(t, tensor, []int{0}); got[0] != 1 || got[1] != 2 {
+		t.Errorf("GetChunk() = %v, want [1 2]", got)
+	}
+	if n := pendingChunks(t, engine); n != 1 {
+		t.Errorf("%d log entries after StoreChunk(), want 1", n)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("chunk file written before checkpoint: %v", err)
+	}
+
+	// A checkpoint moves them to their files
+	if err := engine.checkpoint(); err != nil {
+		t.Fatalf("checkpoint() error = %v", err)
+	}
+	if n := pendingChunks(t, engine); n != 0 {
+		t.Errorf("%d log entries after checkpoint, want 0", n)
+	}
+	if data, _ := os.ReadFile(path); len(data) != 8 || bytesToFloat32Slice(data)[1] != 2 {
+		t.Errorf("chunk file after checkpoint = %v", bytesToFloat32Slice(data))
+	}
+
+	// Chunks logged after the checkpoint survive the process being killed
+	if err := tensor.StoreChunk(ctx, []int{1}, float32SliceToBytes([]float32{3, 4})); err != nil {
+		t.Fatalf("StoreChunk() error = %v", err)
+	}
+	if err := engine.closeDB(); err != nil {
+		t.Fatalf("closeDB() error = %v", err)
+	}
+
+	engine = newLoggedEngine(t, dataDir, 0)
+	defer engine.Shutdown(ctx)
+
+	tensor, err := engine.GetTensor("embeddings")
+	if err != nil {
+		t.Fatalf("GetTensor() error = %v", err)
+	}
+	if got := readChunk(t, tensor, []int{0}); got[0] != 1 || got[1] != 2 {
+		t.Errorf("GetChunk(0) after restart = %v, want [1 2]", got)
+	}
+	if got := readChunk(t, tensor, []int{1}); got[0] != 3 || got[1] != 4 {
+		t.Errorf("GetChunk(1) after restart = %v, want [3 4]", got)
+	}
+	if n := pendingChunks(t, engine); n != 0 {
+		t.Errorf("%d log entries after replay, want 0", n)
+	}
+
+	// Logged chunks of a dropped tensor don't reach one created in its place
+	if err := tensor.StoreChunk(ctx, []int{0}, float32SliceToBytes([]float32{5, 6})); err != nil {
+		t.Fatalf("StoreChunk() error = %v", err)
+	}
+	if err := engine.DropTensor("embeddings"); err != nil {
+		t.Fatalf("DropTensor() error = %v", err)
+	}
+	if n := pendingChunks(t, engine); n != 0 {
+		t.Errorf("%d log entries after DropTensor(), want 0", n)
+	}
+}
+
+func TestCheckpointer(t *testing.T) {
+	ctx := context.Background()
+	dataDir := t.TempDir()
+	engine := newLoggedEngine(t, dataDir, 10*time.Millisecond)
+	defer engine.Shutdown(ctx)
+
+	if err := engine.CreateTensor("embeddings", TensorSchema{Shape: []int{2}, DType: "float32", ChunkSize: []int{2}}); err != nil {
+		t.Fatalf("CreateTensor() error = %v", err)
+	}
+	tensor, _ := engine.GetTensor("embeddings")
+	if err := tensor.StoreChunk(ctx, []int{0}, float32SliceToBytes([]float32{1, 2})); err != nil {
+		t.Fatalf("StoreChunk() error = %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for pendingChunks(t, engine) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("chunk not checkpointed within 5s")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	data, err := os.ReadFile(filepath.Join(dataDir, "tensor_embeddings", "layout_2_2", "c0.bin"))
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if got := bytesToFloat32Slice(data); got[0] != 1 || got[1] != 2 {
+		t.Errorf("chunk file after checkpoint = %v, want [1 2]", got)
+	}
+}
+
+func TestSyncMode(t *testing.T) {
+	tests := []struct {
+		mode    string
+		want    string
+		wantErr bool
+	}{
+		{"", "normal", false},
+		{"off", "off", false},
+		{"FULL", "full", false},
+		{"always", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSyncMode(tt.mode)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseSyncMode(%q) = %q, %v, want %q", tt.mode, got, err, tt.want)
+		}
+	}
+
+	engine, _ := NewHybridEngine(config.StorageConfig{Engine: "hybrid", DataDir: t.TempDir(), SyncMode: "always"})
+	if err := engine.Start(context.Background()); err == nil {
+		engine.Shutdown(context.Background())
+		t.Error("Start() with an invalid sync mode should fail")
+	}
+}
+
+func TestConcurrentInsertsWithWAL(t *testing.T) {
+	ctx := context.Background()
+	engine := newLoggedEngine(t, t.TempDir(), 0)
+	defer engine.Shutdown(ctx)
+
+	schema := TableSchema{Columns: []ColumnDefinition{
+		{Name: "id", Type: "TEXT", PrimaryKey: true},
+		{Name: "n", Type: "INTEGER"},
+	}}
+	if err := engine.CreateTable("events", schema); err != nil {
+		t.Fatalf("CreateTable() error = %v", err)
+	}
+	table, err := engine.GetTable("events")
+	if err != nil {
+		t.Fatalf("GetTable() error = %v", err)
+	}
+
+	const writers, inserts = 8, 50
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*inserts)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < inserts; i++ {
+				if _, err := table.Insert(ctx, Row{"id": fmt.Sprintf("%d-%d", w, i), "n": i}); err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("Insert() error = %v", err)
+	}
+	if count, err := table.Count(ctx, nil); err != nil || count != writers*inserts {
+		t.Errorf("Count() = %d, %v, want %d", count, err, writers*inserts)
+	}
+}